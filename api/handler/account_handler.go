@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/models"
+	"auth-service/service"
+	"errors"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler interface {
+	DeleteAccount(ctx *gin.Context)
+}
+
+type accountHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewAccountHandler(authService service.AuthService, logger *slog.Logger) AccountHandler {
+	return &accountHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary Delete the current account
+// @Description Schedules the deletion of the current user's account after a grace period. The password, and a TOTP or recovery code when two-factor authentication is enabled, must be confirmed.
+// @Description All sessions and personal access tokens are revoked right away. Signing in again before delete_at cancels the deletion; after it the account is anonymized and other services are told to purge the user's data.
+// @Accept json
+// @Produce json
+// @Param Accept-Language header string false "Locale of the confirmation email when the user has none saved"
+// @Param account body models.DeleteAccountReq true "Confirmation"
+// @Success 202 {object} models.AccountDeletionResp
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 429 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /users/me [delete]
+func (h *accountHandlerImpl) DeleteAccount(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	var req models.DeleteAccountReq
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Password == "" {
+		response.Error(ctx, 400, "Password is required")
+		return
+	}
+	if throttled(ctx, h.authService, h.logger, service.ThrottleDeleteAccount, claims.Email) {
+		return
+	}
+
+	resp, err := h.authService.RequestAccountDeletion(ctx.Request.Context(), claims, req, requestClient(ctx), ctx.GetHeader("Accept-Language"))
+	if errors.Is(err, service.ErrIncorrectPassword) || errors.Is(err, service.ErrInvalidMFACode) {
+		recordFailure(ctx, h.authService, h.logger, service.ThrottleDeleteAccount, claims.Email)
+	}
+	if err != nil {
+		fail(ctx, h.logger, "RequestAccountDeletion", err)
+		return
+	}
+
+	clearAuthCookies(ctx)
+	ctx.JSON(202, resp)
+}
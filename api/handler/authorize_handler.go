@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"auth-service/models"
+	"auth-service/service"
+	"embed"
+	"errors"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/authorize.html
+var templatesFS embed.FS
+
+var authorizeTemplate = template.Must(template.ParseFS(templatesFS, "templates/authorize.html"))
+
+type authorizePage struct {
+	Req        models.AuthorizeReq
+	ClientName string
+	Scopes     []string
+	Error      string
+	Fatal      bool
+}
+
+// @Summary OAuth2 authorization endpoint
+// @Description Renders the login and consent page for the authorization code flow.
+// @Description PKCE with the S256 method is required.
+// @Produce html
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string false "Space separated scopes"
+// @Param state query string false "Opaque value returned to the client"
+// @Param nonce query string false "Copied into the ID token"
+// @Param code_challenge query string true "base64url(sha256(code_verifier))"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 200 {string} string "Login page"
+// @Failure 302 {string} string "Redirect to the client with an error"
+// @Failure 400 {string} string "Invalid client or redirect_uri"
+// @Router /authorize [get]
+func (h *oauthHandlerImpl) Authorize(ctx *gin.Context) {
+	var req models.AuthorizeReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		h.logger.Error("Bind error", "error", err)
+		h.renderAuthorize(ctx, 400, authorizePage{Error: "Invalid request", Fatal: true})
+		return
+	}
+
+	client, ok := h.validateAuthorize(ctx, req)
+	if !ok {
+		return
+	}
+
+	h.renderAuthorize(ctx, 200, authorizePage{
+		Req:        req,
+		ClientName: client.Name,
+		Scopes:     strings.Fields(req.Scope),
+	})
+}
+
+// @Summary Submit the authorization page
+// @Description Authenticates the user and redirects back to the client with an authorization code,
+// @Description or with error=access_denied when the user denies the request.
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param email formData string true "User email"
+// @Param password formData string false "User password"
+// @Param mfa_token formData string false "Token from the two-factor step of this page"
+// @Param mfa_code formData string false "TOTP or recovery code"
+// @Param action formData string true "allow or deny"
+// @Success 302 {string} string "Redirect to the client"
+// @Failure 400 {string} string "Invalid client or redirect_uri"
+// @Failure 401 {string} string "Login page with an error"
+// @Router /authorize [post]
+func (h *oauthHandlerImpl) AuthorizeSubmit(ctx *gin.Context) {
+	var req models.AuthorizeReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		h.logger.Error("Bind error", "error", err)
+		h.renderAuthorize(ctx, 400, authorizePage{Error: "Invalid request", Fatal: true})
+		return
+	}
+
+	client, ok := h.validateAuthorize(ctx, req)
+	if !ok {
+		return
+	}
+
+	if req.Action != "allow" {
+		redirectWithError(ctx, req, "access_denied", "The user denied the request")
+		return
+	}
+
+	page := authorizePage{
+		Req:        req,
+		ClientName: client.Name,
+		Scopes:     strings.Fields(req.Scope),
+	}
+	page.Req.Password = ""
+	page.Req.MFACode = ""
+
+	var user *models.User
+	if req.MFAToken != "" {
+		var err error
+		user, err = h.authService.CompleteMFA(ctx.Request.Context(), req.MFAToken, req.MFACode)
+		if errors.Is(err, service.ErrInvalidMFACode) {
+			page.Error = "Invalid verification code"
+			h.renderAuthorize(ctx, 401, page)
+			return
+		}
+		if err != nil {
+			h.logger.Error("CompleteMFA error", "error", err)
+			page.Req.MFAToken = ""
+			page.Error = "Please sign in again"
+			h.renderAuthorize(ctx, 401, page)
+			return
+		}
+	} else {
+		if throttleWait(ctx, h.authService, h.logger, service.ThrottleLogin, req.Email) > 0 {
+			page.Error = "Too many attempts, try again later"
+			h.renderAuthorize(ctx, 429, page)
+			return
+		}
+
+		var err error
+		user, err = h.authService.LoginUser(ctx.Request.Context(), models.LoginUserReq{
+			Email:    req.Email,
+			Password: req.Password,
+		}, requestClient(ctx))
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			page.Error = "Please verify your email address first"
+			h.renderAuthorize(ctx, 403, page)
+			return
+		}
+		if err != nil {
+			h.logger.Error("LoginUser error", "error", err)
+			recordFailure(ctx, h.authService, h.logger, service.ThrottleLogin, req.Email)
+			page.Error = "Invalid email or password"
+			h.renderAuthorize(ctx, 401, page)
+			return
+		}
+		h.authService.ClearFailures(ctx.Request.Context(), service.ThrottleLogin, req.Email, ctx.ClientIP())
+
+		mfaEnabled, err := h.authService.IsMFAEnabled(ctx.Request.Context(), user.ID)
+		if err != nil {
+			h.logger.Error("IsMFAEnabled error", "error", err)
+			redirectWithError(ctx, req, "server_error", "Error logging in")
+			return
+		}
+		if mfaEnabled {
+			pending, err := h.authService.StartMFAChallenge(user.ID)
+			if err != nil {
+				h.logger.Error("StartMFAChallenge error", "error", err)
+				redirectWithError(ctx, req, "server_error", "Error logging in")
+				return
+			}
+			page.Req.MFAToken = pending.MFAToken
+			h.renderAuthorize(ctx, 200, page)
+			return
+		}
+	}
+
+	code, err := h.authService.CreateAuthorizationCode(ctx.Request.Context(), client, req, user.ID)
+	if errors.Is(err, service.ErrInvalidScope) {
+		redirectWithError(ctx, req, "invalid_scope", err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("CreateAuthorizationCode error", "error", err)
+		redirectWithError(ctx, req, "server_error", "Error creating authorization code")
+		return
+	}
+
+	redirectToClient(ctx, req, url.Values{"code": {code}})
+}
+
+// validateAuthorize checks the client and redirect URI first, so that an
+// untrusted redirect_uri never receives a response, and then the parameters
+// whose errors are reported to the client through the redirect.
+func (h *oauthHandlerImpl) validateAuthorize(ctx *gin.Context, req models.AuthorizeReq) (*models.OAuthClient, bool) {
+	client, err := h.authService.ValidateAuthorizeRequest(ctx.Request.Context(), req)
+	if err != nil {
+		h.logger.Error("ValidateAuthorizeRequest error", "error", err)
+		h.renderAuthorize(ctx, 400, authorizePage{Error: err.Error(), Fatal: true})
+		return nil, false
+	}
+
+	if req.ResponseType != "code" {
+		redirectWithError(ctx, req, "unsupported_response_type", "Only the code response type is supported")
+		return nil, false
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		redirectWithError(ctx, req, "invalid_request", "PKCE with code_challenge_method=S256 is required")
+		return nil, false
+	}
+
+	return client, true
+}
+
+func (h *oauthHandlerImpl) renderAuthorize(ctx *gin.Context, status int, page authorizePage) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("X-Frame-Options", "DENY")
+	ctx.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action "+formAction(page)+"; frame-ancestors 'none'")
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Status(status)
+
+	if err := authorizeTemplate.Execute(ctx.Writer, page); err != nil {
+		h.logger.Error("Render authorize page error", "error", err)
+	}
+}
+
+// formAction lists where the page's form may be submitted. Browsers apply
+// form-action to the redirects that follow the submission too, so the
+// validated redirect_uri of the client has to be allowed as well: an origin for
+// web clients and the scheme alone for custom schemes of native apps.
+func formAction(page authorizePage) string {
+	if page.Fatal || page.Req.RedirectURI == "" {
+		return "'self'"
+	}
+
+	u, err := url.Parse(page.Req.RedirectURI)
+	if err != nil || u.Scheme == "" {
+		return "'self'"
+	}
+
+	source := u.Scheme + ":"
+	if u.Host != "" {
+		source = u.Scheme + "://" + u.Host
+	}
+	if strings.ContainsAny(source, " ;,'\"") {
+		return "'self'"
+	}
+	return "'self' " + source
+}
+
+func redirectWithError(ctx *gin.Context, req models.AuthorizeReq, code string, description string) {
+	redirectToClient(ctx, req, url.Values{
+		"error":             {code},
+		"error_description": {description},
+	})
+}
+
+func redirectToClient(ctx *gin.Context, req models.AuthorizeReq, params url.Values) {
+	u, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		ctx.String(400, "Invalid redirect_uri")
+		return
+	}
+
+	query := u.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	u.RawQuery = query.Encode()
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Redirect(302, u.String())
+}
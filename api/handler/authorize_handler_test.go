@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"auth-service/models"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderAuthorizeFormAction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &oauthHandlerImpl{logger: slog.Default()}
+
+	tests := []struct {
+		name        string
+		page        authorizePage
+		formActions []string
+	}{
+		{"web client", authorizePage{Req: models.AuthorizeReq{RedirectURI: "https://app.example.com/callback?x=1"}}, []string{"'self'", "https://app.example.com"}},
+		{"port", authorizePage{Req: models.AuthorizeReq{RedirectURI: "http://localhost:3000/callback"}}, []string{"'self'", "http://localhost:3000"}},
+		{"native app", authorizePage{Req: models.AuthorizeReq{RedirectURI: "com.example.app:/oauth2redirect"}}, []string{"'self'", "com.example.app:"}},
+		{"fatal error", authorizePage{Req: models.AuthorizeReq{RedirectURI: "https://evil.example.com/"}, Fatal: true}, []string{"'self'"}},
+		{"no redirect_uri", authorizePage{}, []string{"'self'"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			h.renderAuthorize(ctx, 200, tt.page)
+
+			assert.Equal(t, tt.formActions, cspDirective(w.Header().Get("Content-Security-Policy"), "form-action"))
+		})
+	}
+}
+
+func cspDirective(policy string, name string) []string {
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) > 0 && fields[0] == name {
+			return fields[1:]
+		}
+	}
+	return nil
+}
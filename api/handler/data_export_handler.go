@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"auth-service/service"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataExportHandler interface {
+	RequestExport(ctx *gin.Context)
+	GetExport(ctx *gin.Context)
+	Download(ctx *gin.Context)
+}
+
+type dataExportHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewDataExportHandler(authService service.AuthService, logger *slog.Logger) DataExportHandler {
+	return &dataExportHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary Export my data
+// @Description Starts building an archive of the current user's personal data: profile, sessions, audit events and linked identities, plus accounts, transactions, budgets and goals from the services that are configured. Every section is included as JSON and as CSV.
+// @Description The archive is built in the background; a signed download link is emailed when it is ready and can also be fetched with GET /users/me/exports/{id}. Only one export can be in progress at a time.
+// @Produce json
+// @Success 202 {object} models.DataExport
+// @Failure 401 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /users/me/export [post]
+func (h *dataExportHandlerImpl) RequestExport(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	export, err := h.authService.RequestDataExport(ctx.Request.Context(), claims.ID, requestClient(ctx))
+	if err != nil {
+		fail(ctx, h.logger, "RequestDataExport", err)
+		return
+	}
+
+	ctx.Header("Location", "/api/v1/users/me/exports/"+export.ID)
+	ctx.JSON(202, export)
+}
+
+// @Summary Get one of my data exports
+// @Description Returns the status of an export; download_url is set once it is ready and until it expires.
+// @Produce json
+// @Param id path string true "Export ID"
+// @Success 200 {object} models.DataExport
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /users/me/exports/{id} [get]
+func (h *dataExportHandlerImpl) GetExport(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	export, err := h.authService.GetDataExport(ctx.Request.Context(), claims.ID, ctx.Param("id"))
+	if err != nil {
+		fail(ctx, h.logger, "GetDataExport", err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(200, export)
+}
+
+// @Summary Download a data export
+// @Description Downloads the zip archive of an export. The link is signed and expires, so it works without signing in; an invalid or expired link is reported as not found.
+// @Produce application/zip
+// @Param id path string true "Export ID"
+// @Param expires query string true "Expiry of the link, Unix seconds"
+// @Param signature query string true "Signature of the link"
+// @Success 200 {file} file
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /exports/{id}/download [get]
+func (h *dataExportHandlerImpl) Download(ctx *gin.Context) {
+	export, err := h.authService.OpenDataExport(ctx.Request.Context(), ctx.Param("id"), ctx.Query("expires"), ctx.Query("signature"), requestClient(ctx))
+	if err != nil {
+		fail(ctx, h.logger, "OpenDataExport", err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	filename := "data-export-" + time.Now().UTC().Format("20060102") + ".zip"
+	ctx.FileAttachment(export.FilePath, filename)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/service"
+	"auth-service/storage/postgres"
+	"log/slog"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultEmailsLimit = 50
+	maxEmailsLimit     = 500
+)
+
+type EmailHandler interface {
+	ListEmails(ctx *gin.Context)
+	GetEmail(ctx *gin.Context)
+	RedriveEmail(ctx *gin.Context)
+}
+
+type emailHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewEmailHandler(authService service.AuthService, logger *slog.Logger) EmailHandler {
+	return &emailHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary List outgoing emails
+// @Description Lists the messages in the email outbox, newest first. Requires emails:read.
+// @Produce json
+// @Param status query string false "pending, sending, sent or dead"
+// @Param limit query int false "Page size, 50 by default"
+// @Param offset query int false "Number of messages to skip"
+// @Success 200 {object} models.EmailMessagesList
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/emails [get]
+func (h *emailHandlerImpl) ListEmails(ctx *gin.Context) {
+	status := ctx.Query("status")
+	switch status {
+	case "", postgres.EmailPending, postgres.EmailSending, postgres.EmailSent, postgres.EmailDead:
+	default:
+		response.Error(ctx, 400, "Invalid status")
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultEmailsLimit)))
+	if err != nil || limit <= 0 || limit > maxEmailsLimit {
+		response.Error(ctx, 400, "Invalid limit")
+		return
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		response.Error(ctx, 400, "Invalid offset")
+		return
+	}
+
+	resp, err := h.authService.ListEmailMessages(ctx.Request.Context(), status, limit, offset)
+	if err != nil {
+		fail(ctx, h.logger, "ListEmailMessages", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Outgoing email status
+// @Description Reports the delivery status of one message in the email outbox. Requires emails:read.
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} models.EmailMessage
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/emails/{id} [get]
+func (h *emailHandlerImpl) GetEmail(ctx *gin.Context) {
+	resp, err := h.authService.GetEmailMessage(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		fail(ctx, h.logger, "GetEmailMessage", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Re-drive a dead email
+// @Description Queues a dead-lettered message for delivery again with a fresh retry budget. Requires emails:write.
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/emails/{id}/redrive [post]
+func (h *emailHandlerImpl) RedriveEmail(ctx *gin.Context) {
+	resp, err := h.authService.RedriveEmailMessage(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		fail(ctx, h.logger, "RedriveEmailMessage", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/pkg/apperr"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fail responds with the status of the domain error in err's chain and logs
+// anything unexpected. Input the handler rejects itself is answered with
+// response.Error instead.
+func fail(ctx *gin.Context, logger *slog.Logger, op string, err error) {
+	if apperr.CodeOf(err) == apperr.Internal {
+		logger.Error(op+" error", "error", err)
+	}
+	response.Fail(ctx, err)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"auth-service/models"
+	"auth-service/service"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"invalid code", service.ErrInvalidMFACode, 400, "invalid_argument", "invalid verification code"},
+		{"expired mfa token", service.ErrInvalidMFAToken, 401, "unauthenticated", "invalid or expired mfa token"},
+		{"mfa not enabled", service.ErrMFANotEnabled, 409, "conflict", "two-factor authentication is not enabled"},
+		{"too many attempts", service.ErrTooManyMFAAttempts, 429, "rate_limited", "too many verification attempts, sign in again"},
+		{"unexpected", errors.New("connection refused"), 500, "internal", "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			fail(ctx, slog.Default(), "CompleteMFA", tt.err)
+
+			var body models.Error
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.code, body.Code)
+			assert.Equal(t, tt.message, body.Message)
+		})
+	}
+}
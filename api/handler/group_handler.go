@@ -0,0 +1,332 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/models"
+	"auth-service/service"
+	"log/slog"
+	"net/mail"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler interface {
+	CreateGroup(ctx *gin.Context)
+	ListGroups(ctx *gin.Context)
+	GetGroup(ctx *gin.Context)
+	DeleteGroup(ctx *gin.Context)
+	InviteMember(ctx *gin.Context)
+	ListInvitations(ctx *gin.Context)
+	AcceptInvitation(ctx *gin.Context)
+	DeclineInvitation(ctx *gin.Context)
+	UpdateMember(ctx *gin.Context)
+	RemoveMember(ctx *gin.Context)
+	LeaveGroup(ctx *gin.Context)
+}
+
+type groupHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewGroupHandler(authService service.AuthService, logger *slog.Logger) GroupHandler {
+	return &groupHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary Create a group
+// @Description Creates a household group with the caller as its owner. Tokens list the group once they are refreshed.
+// @Accept json
+// @Produce json
+// @Param group body models.CreateGroupReq true "Group name"
+// @Success 201 {object} models.Group
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups [post]
+func (h *groupHandlerImpl) CreateGroup(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	var req models.CreateGroupReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Bind error", "error", err)
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+
+	resp, err := h.authService.CreateGroup(ctx.Request.Context(), claims.ID, req.Name)
+	if err != nil {
+		fail(ctx, h.logger, "CreateGroup", err)
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+// @Summary List my groups
+// @Description Lists the groups the caller belongs to with the caller's role in each
+// @Produce json
+// @Success 200 {object} models.GroupsList
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups [get]
+func (h *groupHandlerImpl) ListGroups(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.ListGroups(ctx.Request.Context(), claims.ID)
+	if err != nil {
+		fail(ctx, h.logger, "ListGroups", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Get a group
+// @Description Returns a group the caller belongs to with its members
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} models.Group
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/{id} [get]
+func (h *groupHandlerImpl) GetGroup(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.GetGroup(ctx.Request.Context(), claims.ID, ctx.Param("id"))
+	if err != nil {
+		fail(ctx, h.logger, "GetGroup", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Delete a group
+// @Description Deletes a group with its memberships and invitations. Only owners can delete a group.
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/{id} [delete]
+func (h *groupHandlerImpl) DeleteGroup(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.DeleteGroup(ctx.Request.Context(), claims.ID, ctx.Param("id"))
+	if err != nil {
+		fail(ctx, h.logger, "DeleteGroup", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Invite to a group
+// @Description Invites an email address to the group and emails the invitee. Only owners can invite.
+// @Description The role is owner, member or viewer, member by default. Invitations expire after 7 days.
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param invitation body models.InviteGroupMemberReq true "Invitee email and role"
+// @Param Accept-Language header string false "Locale of the email when the invitee has none saved"
+// @Success 201 {object} models.GroupInvitation
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/{id}/invitations [post]
+func (h *groupHandlerImpl) InviteMember(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	var req models.InviteGroupMemberReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Bind error", "error", err)
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+	addr, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		response.Error(ctx, 400, "Invalid email")
+		return
+	}
+	req.Email = addr.Address
+
+	resp, err := h.authService.InviteGroupMember(ctx.Request.Context(), claims.ID, claims.Email, ctx.Param("id"), req, ctx.GetHeader("Accept-Language"))
+	if err != nil {
+		fail(ctx, h.logger, "InviteGroupMember", err)
+		return
+	}
+
+	ctx.JSON(201, resp)
+}
+
+// @Summary List my invitations
+// @Description Lists the pending group invitations sent to the caller's email
+// @Produce json
+// @Success 200 {object} models.GroupInvitationsList
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/invitations [get]
+func (h *groupHandlerImpl) ListInvitations(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.ListGroupInvitations(ctx.Request.Context(), claims.Email)
+	if err != nil {
+		fail(ctx, h.logger, "ListGroupInvitations", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Accept an invitation
+// @Description Joins the group with the invited role. Tokens list the group once they are refreshed.
+// @Produce json
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} models.GroupInvitation
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/invitations/{id}/accept [post]
+func (h *groupHandlerImpl) AcceptInvitation(ctx *gin.Context) {
+	h.respondToInvitation(ctx, true)
+}
+
+// @Summary Decline an invitation
+// @Produce json
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} models.GroupInvitation
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/invitations/{id}/decline [post]
+func (h *groupHandlerImpl) DeclineInvitation(ctx *gin.Context) {
+	h.respondToInvitation(ctx, false)
+}
+
+func (h *groupHandlerImpl) respondToInvitation(ctx *gin.Context, accept bool) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.RespondToGroupInvitation(ctx.Request.Context(), claims.ID, claims.Email, ctx.Param("id"), accept)
+	if err != nil {
+		fail(ctx, h.logger, "RespondToGroupInvitation", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Change a member's role
+// @Description Sets the role of a group member to owner, member or viewer. Only owners can change roles and the last owner cannot be demoted.
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param user_id path string true "Member user ID"
+// @Param member body models.UpdateGroupMemberReq true "New role"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/{id}/members/{user_id} [put]
+func (h *groupHandlerImpl) UpdateMember(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateGroupMemberReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Bind error", "error", err)
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+
+	resp, err := h.authService.UpdateGroupMember(ctx.Request.Context(), claims.ID, ctx.Param("id"), ctx.Param("user_id"), req.Role)
+	if err != nil {
+		fail(ctx, h.logger, "UpdateGroupMember", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Remove a member
+// @Description Removes a member from the group. Only owners can remove other members and the last owner cannot be removed.
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param user_id path string true "Member user ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/{id}/members/{user_id} [delete]
+func (h *groupHandlerImpl) RemoveMember(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.RemoveGroupMember(ctx.Request.Context(), claims.ID, ctx.Param("id"), ctx.Param("user_id"))
+	if err != nil {
+		fail(ctx, h.logger, "RemoveGroupMember", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Leave a group
+// @Description Removes the caller from the group. The last owner has to hand over ownership or delete the group instead.
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /groups/{id}/leave [post]
+func (h *groupHandlerImpl) LeaveGroup(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.RemoveGroupMember(ctx.Request.Context(), claims.ID, ctx.Param("id"), claims.ID)
+	if err != nil {
+		fail(ctx, h.logger, "LeaveGroup", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
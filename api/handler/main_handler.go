@@ -7,6 +7,17 @@ import (
 
 type MainHandler interface {
 	AuthHandler() UserHandler
+	SessionHandler() SessionHandler
+	WellKnownHandler() WellKnownHandler
+	OAuthHandler() OAuthHandler
+	MFAHandler() MFAHandler
+	WebAuthnHandler() WebAuthnHandler
+	EmailHandler() EmailHandler
+	GroupHandler() GroupHandler
+	PersonalTokenHandler() PersonalTokenHandler
+	AuditHandler() AuditHandler
+	AccountHandler() AccountHandler
+	DataExportHandler() DataExportHandler
 }
 
 type mainHandlerImpl struct {
@@ -21,3 +32,47 @@ func NewMainHandler(authService service.AuthService, logger *slog.Logger) MainHa
 func (h *mainHandlerImpl) AuthHandler() UserHandler {
 	return NewUserHandler(h.authService, h.logger)
 }
+
+func (h *mainHandlerImpl) SessionHandler() SessionHandler {
+	return NewSessionHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) WellKnownHandler() WellKnownHandler {
+	return NewWellKnownHandler(h.logger)
+}
+
+func (h *mainHandlerImpl) OAuthHandler() OAuthHandler {
+	return NewOAuthHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) MFAHandler() MFAHandler {
+	return NewMFAHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) WebAuthnHandler() WebAuthnHandler {
+	return NewWebAuthnHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) EmailHandler() EmailHandler {
+	return NewEmailHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) GroupHandler() GroupHandler {
+	return NewGroupHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) PersonalTokenHandler() PersonalTokenHandler {
+	return NewPersonalTokenHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) AuditHandler() AuditHandler {
+	return NewAuditHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) AccountHandler() AccountHandler {
+	return NewAccountHandler(h.authService, h.logger)
+}
+
+func (h *mainHandlerImpl) DataExportHandler() DataExportHandler {
+	return NewDataExportHandler(h.authService, h.logger)
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/models"
+	"auth-service/service"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler interface {
+	GetStatus(ctx *gin.Context)
+	EnrollTOTP(ctx *gin.Context)
+	ConfirmTOTP(ctx *gin.Context)
+	RegenerateRecoveryCodes(ctx *gin.Context)
+	ResetUserMFA(ctx *gin.Context)
+}
+
+type mfaHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewMFAHandler(authService service.AuthService, logger *slog.Logger) MFAHandler {
+	return &mfaHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary Two-factor status
+// @Description Reports whether the current user has two-factor authentication enabled
+// @Produce json
+// @Success 200 {object} models.MFAStatus
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/mfa [get]
+func (h *mfaHandlerImpl) GetStatus(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.GetMFAStatus(ctx.Request.Context(), claims.ID)
+	if err != nil {
+		fail(ctx, h.logger, "GetMFAStatus", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Start TOTP enrollment
+// @Description Generates a TOTP secret and returns it as an otpauth:// URI and a base64 PNG QR code.
+// @Description The secret becomes active after it is confirmed with a first code.
+// @Produce json
+// @Success 200 {object} models.TOTPEnrollResp
+// @Failure 401 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/mfa/totp/enroll [post]
+func (h *mfaHandlerImpl) EnrollTOTP(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.EnrollTOTP(ctx.Request.Context(), claims.ID, claims.Email)
+	if err != nil {
+		fail(ctx, h.logger, "EnrollTOTP", err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(200, resp)
+}
+
+// @Summary Confirm TOTP enrollment
+// @Description Enables two-factor authentication with the first code from the authenticator app
+// @Description and returns the one-time recovery codes. They are shown only once.
+// @Accept json
+// @Produce json
+// @Param code body models.MFACodeReq true "TOTP code"
+// @Success 200 {object} models.RecoveryCodesResp
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/mfa/totp/confirm [post]
+func (h *mfaHandlerImpl) ConfirmTOTP(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("BindJSON error", "error", err)
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+
+	resp, err := h.authService.ConfirmTOTP(ctx.Request.Context(), claims.ID, req.Code)
+	if err != nil {
+		fail(ctx, h.logger, "ConfirmTOTP", err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(200, resp)
+}
+
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes of the current user. Requires a current TOTP code.
+// @Accept json
+// @Produce json
+// @Param code body models.MFACodeReq true "TOTP code"
+// @Success 200 {object} models.RecoveryCodesResp
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/mfa/recovery-codes [post]
+func (h *mfaHandlerImpl) RegenerateRecoveryCodes(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("BindJSON error", "error", err)
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+
+	resp, err := h.authService.RegenerateRecoveryCodes(ctx.Request.Context(), claims.ID, req.Code)
+	if err != nil {
+		fail(ctx, h.logger, "RegenerateRecoveryCodes", err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(200, resp)
+}
+
+// @Summary Reset a user's two-factor authentication
+// @Description Removes the TOTP secret and recovery codes of a user who lost their device. Requires mfa:reset.
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/mfa/users/{id} [delete]
+func (h *mfaHandlerImpl) ResetUserMFA(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+	resp, err := h.authService.ResetMFA(ctx.Request.Context(), ctx.Param("id"), claims.ID)
+	if err != nil {
+		fail(ctx, h.logger, "ResetMFA", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/api/token"
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/service"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthHandler interface {
+	Authorize(ctx *gin.Context)
+	AuthorizeSubmit(ctx *gin.Context)
+	Token(ctx *gin.Context)
+	UserInfo(ctx *gin.Context)
+}
+
+type oauthHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewOAuthHandler(authService service.AuthService, logger *slog.Logger) OAuthHandler {
+	return &oauthHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary OAuth2 token endpoint
+// @Description Issues tokens for the authorization_code, password, refresh_token and client_credentials grants.
+// @Description Clients authenticate with HTTP Basic or client_id/client_secret form fields.
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, password, refresh_token or client_credentials"
+// @Param code formData string false "Authorization code (authorization_code grant)"
+// @Param redirect_uri formData string false "Redirect URI used in the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier (authorization_code grant)"
+// @Param username formData string false "User email (password grant)"
+// @Param password formData string false "User password (password grant)"
+// @Param refresh_token formData string false "Refresh token (refresh_token grant), only accepted from the client it was issued to"
+// @Param scope formData string false "Space separated scopes, openid adds an ID token"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} models.TokenResp
+// @Failure 400 {object} models.OAuthError
+// @Failure 401 {object} models.OAuthError
+// @Router /token [post]
+func (h *oauthHandlerImpl) Token(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req models.TokenReq
+	if err := ctx.ShouldBind(&req); err != nil {
+		h.logger.Error("Bind error", "error", err)
+		oauthError(ctx, 400, "invalid_request", "Invalid request body")
+		return
+	}
+	if id, secret, ok := ctx.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+
+	var client *models.OAuthClient
+	if req.ClientID != "" {
+		var err error
+		client, err = h.authService.AuthenticateClient(ctx.Request.Context(), req.ClientID, req.ClientSecret)
+		if err != nil {
+			oauthError(ctx, 401, "invalid_client", "Client authentication failed")
+			return
+		}
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		h.authorizationCodeGrant(ctx, req, client)
+	case "password":
+		h.passwordGrant(ctx, req, client)
+	case "refresh_token":
+		h.refreshTokenGrant(ctx, req, client)
+	case "client_credentials":
+		h.clientCredentialsGrant(ctx, req, client)
+	default:
+		oauthError(ctx, 400, "unsupported_grant_type", "Unsupported grant type")
+	}
+}
+
+func (h *oauthHandlerImpl) authorizationCodeGrant(ctx *gin.Context, req models.TokenReq, client *models.OAuthClient) {
+	if client == nil {
+		oauthError(ctx, 401, "invalid_client", "Client authentication is required")
+		return
+	}
+	if !slices.Contains(client.GrantTypes, "authorization_code") {
+		oauthError(ctx, 400, "unauthorized_client", "Client is not allowed to use the authorization_code grant")
+		return
+	}
+
+	tokens, code, err := h.authService.ExchangeAuthorizationCode(ctx.Request.Context(), client, req, h.clientInfo(ctx, client))
+	if errors.Is(err, service.ErrInvalidGrant) {
+		oauthError(ctx, 400, "invalid_grant", err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("ExchangeAuthorizationCode error", "error", err)
+		oauthError(ctx, 500, "server_error", "Error exchanging authorization code")
+		return
+	}
+
+	// The scope and nonce come from the authorization request, not from the
+	// token request.
+	req.Scope = code.Scope
+	h.respondWithTokens(ctx, req, client, code.UserID, code.Nonce, tokens)
+}
+
+func (h *oauthHandlerImpl) passwordGrant(ctx *gin.Context, req models.TokenReq, client *models.OAuthClient) {
+	if client != nil && !slices.Contains(client.GrantTypes, "password") {
+		oauthError(ctx, 400, "unauthorized_client", "Client is not allowed to use the password grant")
+		return
+	}
+	if throttleWait(ctx, h.authService, h.logger, service.ThrottleLogin, req.Username) > 0 {
+		oauthError(ctx, 429, "invalid_grant", service.ErrTooManyAttempts.Error())
+		return
+	}
+
+	user, err := h.authService.LoginUser(ctx.Request.Context(), models.LoginUserReq{
+		Email:    req.Username,
+		Password: req.Password,
+	}, h.clientInfo(ctx, client))
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		oauthError(ctx, 400, "invalid_grant", "Email address is not verified")
+		return
+	}
+	if err != nil {
+		h.logger.Error("LoginUser error", "error", err)
+		recordFailure(ctx, h.authService, h.logger, service.ThrottleLogin, req.Username)
+		oauthError(ctx, 400, "invalid_grant", "Invalid username or password")
+		return
+	}
+	h.authService.ClearFailures(ctx.Request.Context(), service.ThrottleLogin, req.Username, ctx.ClientIP())
+
+	mfaEnabled, err := h.authService.IsMFAEnabled(ctx.Request.Context(), user.ID)
+	if err != nil {
+		h.logger.Error("IsMFAEnabled error", "error", err)
+		oauthError(ctx, 500, "server_error", "Error logging in")
+		return
+	}
+	if mfaEnabled {
+		oauthError(ctx, 400, "invalid_grant", "Two-factor authentication is required, use the authorization code flow")
+		return
+	}
+
+	tokens, err := h.authService.StartSession(ctx.Request.Context(), user, h.clientInfo(ctx, client))
+	if err != nil {
+		h.logger.Error("StartSession error", "error", err)
+		oauthError(ctx, 500, "server_error", "Error creating session")
+		return
+	}
+
+	h.respondWithTokens(ctx, req, client, user.ID, "", tokens)
+}
+
+func (h *oauthHandlerImpl) refreshTokenGrant(ctx *gin.Context, req models.TokenReq, client *models.OAuthClient) {
+	if client != nil && !slices.Contains(client.GrantTypes, "refresh_token") {
+		oauthError(ctx, 400, "unauthorized_client", "Client is not allowed to use the refresh_token grant")
+		return
+	}
+
+	tokens, err := h.authService.RefreshSession(ctx.Request.Context(), req.RefreshToken, h.clientInfo(ctx, client))
+	if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+		oauthError(ctx, 400, "invalid_grant", err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("RefreshSession error", "error", err)
+		oauthError(ctx, 500, "server_error", "Error refreshing token")
+		return
+	}
+
+	claims, err := token.ExtractAndValidateToken(tokens.AccessToken)
+	if err != nil {
+		h.logger.Error("ExtractAndValidateToken error", "error", err)
+		oauthError(ctx, 500, "server_error", "Error refreshing token")
+		return
+	}
+
+	h.respondWithTokens(ctx, req, client, claims.ID, "", tokens)
+}
+
+func (h *oauthHandlerImpl) clientCredentialsGrant(ctx *gin.Context, req models.TokenReq, client *models.OAuthClient) {
+	if client == nil {
+		oauthError(ctx, 401, "invalid_client", "Client authentication is required")
+		return
+	}
+
+	resp, err := h.authService.IssueClientToken(client, req.Scope)
+	if errors.Is(err, service.ErrUnauthorizedClient) {
+		oauthError(ctx, 400, "unauthorized_client", err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrInvalidScope) {
+		oauthError(ctx, 400, "invalid_scope", err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("IssueClientToken error", "error", err)
+		oauthError(ctx, 500, "server_error", "Error issuing token")
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (h *oauthHandlerImpl) respondWithTokens(ctx *gin.Context, req models.TokenReq, client *models.OAuthClient, userID string, nonce string, tokens *models.LoginUserResp) {
+	resp := models.TokenResp{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(token.AccessTokenTTL.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		Scope:        req.Scope,
+	}
+
+	if slices.Contains(strings.Fields(req.Scope), "openid") {
+		audience := config.Load().ISSUER_URL
+		if client != nil {
+			audience = client.ClientID
+		}
+
+		idToken, err := h.authService.IssueIDToken(ctx.Request.Context(), userID, audience, nonce)
+		if err != nil {
+			h.logger.Error("IssueIDToken error", "error", err)
+			oauthError(ctx, 500, "server_error", "Error issuing ID token")
+			return
+		}
+		resp.IDToken = idToken
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (h *oauthHandlerImpl) clientInfo(ctx *gin.Context, client *models.OAuthClient) models.ClientInfo {
+	info := models.ClientInfo{
+		IPAddress: ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+	if client != nil {
+		info.DeviceName = client.Name
+		info.ClientID = client.ClientID
+	}
+	return info
+}
+
+// @Summary OpenID Connect userinfo
+// @Description Returns the standard claims of the user the access token belongs to
+// @Produce json
+// @Success 200 {object} models.UserInfo
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Router /userinfo [get]
+func (h *oauthHandlerImpl) UserInfo(ctx *gin.Context) {
+	val, ok := ctx.Get("claims")
+	if !ok {
+		h.logger.Error("Token not found in context")
+		response.Error(ctx, 401, "Unauthorized")
+		return
+	}
+	claims, ok := val.(*token.Claims)
+	if !ok {
+		h.logger.Error("Token claims not found in context")
+		response.Error(ctx, 401, "Unauthorized")
+		return
+	}
+
+	resp, err := h.authService.GetUserInfo(ctx.Request.Context(), claims.ID)
+	if err != nil {
+		fail(ctx, h.logger, "GetUserInfo", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func oauthError(ctx *gin.Context, status int, code string, description string) {
+	if status == 401 {
+		ctx.Header("WWW-Authenticate", `Basic realm="token"`)
+	}
+	ctx.JSON(status, models.OAuthError{Error: code, ErrorDescription: description})
+}
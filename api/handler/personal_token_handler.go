@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/models"
+	"auth-service/service"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonalTokenHandler interface {
+	CreateToken(ctx *gin.Context)
+	ListTokens(ctx *gin.Context)
+	RevokeToken(ctx *gin.Context)
+}
+
+type personalTokenHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewPersonalTokenHandler(authService service.AuthService, logger *slog.Logger) PersonalTokenHandler {
+	return &personalTokenHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary Create a personal access token
+// @Description Creates an API token for scripts and integrations. Scopes are permissions the user holds, such as transactions:read.
+// @Description The token is only shown in this response; send it as "Authorization: Bearer <token>".
+// @Accept json
+// @Produce json
+// @Param token body models.CreatePersonalTokenReq true "Token name, scopes and lifetime in days"
+// @Success 201 {object} models.PersonalTokenCreated
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/tokens [post]
+func (h *personalTokenHandlerImpl) CreateToken(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	var req models.CreatePersonalTokenReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Bind error", "error", err)
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+
+	resp, err := h.authService.CreatePersonalToken(ctx.Request.Context(), claims.ID, req)
+	if err != nil {
+		fail(ctx, h.logger, "CreatePersonalToken", err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(201, resp)
+}
+
+// @Summary List personal access tokens
+// @Description Lists the caller's tokens that have not been revoked, without the token values
+// @Produce json
+// @Success 200 {object} models.PersonalTokensList
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/tokens [get]
+func (h *personalTokenHandlerImpl) ListTokens(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.ListPersonalTokens(ctx.Request.Context(), claims.ID)
+	if err != nil {
+		fail(ctx, h.logger, "ListPersonalTokens", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Revoke a personal access token
+// @Description Revokes one of the caller's tokens; it stops working immediately
+// @Produce json
+// @Param id path string true "Token ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/tokens/{id} [delete]
+func (h *personalTokenHandlerImpl) RevokeToken(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.RevokePersonalToken(ctx.Request.Context(), claims.ID, ctx.Param("id"))
+	if err != nil {
+		fail(ctx, h.logger, "RevokePersonalToken", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/api/token"
+	"auth-service/models"
+	"auth-service/service"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler interface {
+	GetSessions(ctx *gin.Context)
+	RevokeSession(ctx *gin.Context)
+	RevokeOtherSessions(ctx *gin.Context)
+}
+
+type sessionHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewSessionHandler(authService service.AuthService, logger *slog.Logger) SessionHandler {
+	return &sessionHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary List sessions
+// @Description Lists the active sessions (devices) of the current user
+// @Produce json
+// @Success 200 {object} models.SessionsList
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/sessions [get]
+func (h *sessionHandlerImpl) GetSessions(ctx *gin.Context) {
+	claims, ok := h.claims(ctx)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.GetUserSessions(ctx.Request.Context(), claims.ID, claims.SessionID)
+	if err != nil {
+		fail(ctx, h.logger, "GetUserSessions", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Revoke session
+// @Description Signs the current user out of one of their sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Router /auth/sessions/{id} [delete]
+func (h *sessionHandlerImpl) RevokeSession(ctx *gin.Context) {
+	claims, ok := h.claims(ctx)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.RevokeSession(ctx.Request.Context(), claims.ID, ctx.Param("id"))
+	if err != nil {
+		fail(ctx, h.logger, "RevokeSession", err)
+		return
+	}
+
+	if ctx.Param("id") == claims.SessionID {
+		ctx.SetCookie("access_token", "", -1, "/", "", false, true)
+	}
+	ctx.JSON(200, resp)
+}
+
+// @Summary Sign out everywhere else
+// @Description Revokes every session of the current user except the one making the request
+// @Produce json
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/sessions/revoke-others [post]
+func (h *sessionHandlerImpl) RevokeOtherSessions(ctx *gin.Context) {
+	claims, ok := h.claims(ctx)
+	if !ok {
+		return
+	}
+	if claims.SessionID == "" {
+		response.Error(ctx, 400, "Current session is unknown, please log in again")
+		return
+	}
+
+	resp, err := h.authService.RevokeOtherSessions(ctx.Request.Context(), claims.ID, claims.SessionID)
+	if err != nil {
+		fail(ctx, h.logger, "RevokeOtherSessions", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+func (h *sessionHandlerImpl) claims(ctx *gin.Context) (*token.Claims, bool) {
+	return requireClaims(ctx, h.logger)
+}
+
+// requireClaims returns the claims IsAuthenticated stored in the context and
+// responds with 401 when there are none.
+func requireClaims(ctx *gin.Context, logger *slog.Logger) (*token.Claims, bool) {
+	val, ok := ctx.Get("claims")
+	if !ok {
+		logger.Error("Token not found in context")
+		response.Error(ctx, 401, "Unauthorized")
+		return nil, false
+	}
+	claims, ok := val.(*token.Claims)
+	if !ok {
+		logger.Error("Token claims not found in context")
+		response.Error(ctx, 401, "Unauthorized")
+		return nil, false
+	}
+	return claims, true
+}
+
+// requestClient describes the client that sent the request, for sessions and
+// the audit log.
+func requestClient(ctx *gin.Context) models.ClientInfo {
+	return models.ClientInfo{
+		IPAddress: ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/service"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// throttled responds with 429 when action is currently locked for email or
+// the client's IP.
+func throttled(ctx *gin.Context, authService service.AuthService, logger *slog.Logger, action string, email string) bool {
+	wait := throttleWait(ctx, authService, logger, action, email)
+	if wait <= 0 {
+		return false
+	}
+
+	response.Fail(ctx, service.ErrTooManyAttempts)
+	return true
+}
+
+// throttleWait returns how long the client has to wait before trying action
+// again and sets Retry-After when it has to. Limiter errors are logged and
+// let the request through so that a Redis outage does not lock everybody out.
+func throttleWait(ctx *gin.Context, authService service.AuthService, logger *slog.Logger, action string, email string) time.Duration {
+	wait, err := authService.CheckThrottle(ctx.Request.Context(), action, email, ctx.ClientIP())
+	if err != nil {
+		logger.Error("CheckThrottle error", "error", err)
+		return 0
+	}
+	if wait > 0 {
+		setRetryAfter(ctx, wait)
+	}
+	return wait
+}
+
+func recordFailure(ctx *gin.Context, authService service.AuthService, logger *slog.Logger, action string, email string) {
+	wait, err := authService.RecordFailure(ctx.Request.Context(), action, email, ctx.ClientIP())
+	if err != nil {
+		logger.Error("RecordFailure error", "error", err)
+		return
+	}
+	if wait > 0 {
+		setRetryAfter(ctx, wait)
+	}
+}
+
+func setRetryAfter(ctx *gin.Context, wait time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
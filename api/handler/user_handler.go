@@ -1,13 +1,13 @@
 package handler
 
 import (
+	"auth-service/api/response"
 	"auth-service/api/token"
 	"auth-service/models"
-	"auth-service/pkg/helper"
+	"auth-service/pkg/mail"
 	"auth-service/service"
+	"errors"
 	"log/slog"
-	"math/rand"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +21,12 @@ type UserHandler interface {
 	ResetPassword(ctx *gin.Context)
 	LogOutUser(ctx *gin.Context)
 	RefreshToken(ctx *gin.Context)
+	VerifyMFA(ctx *gin.Context)
+	VerifyEmail(ctx *gin.Context)
+	ConfirmEmailChange(ctx *gin.Context)
+	ResendVerification(ctx *gin.Context)
+	UnlockAccount(ctx *gin.Context)
+	ListRoles(ctx *gin.Context)
 }
 
 type userHandlerImpl struct {
@@ -33,10 +39,11 @@ func NewUserHandler(authService service.AuthService, logger *slog.Logger) UserHa
 }
 
 // @Summary Register user
-// @Description Registers a new user
+// @Description Registers a new user in the pending state and emails a verification link
 // @Accept json
 // @Produce json
 // @Param user body models.RegisterUser true "User details"
+// @Param Accept-Language header string false "Picks the locale when the body has none"
 // @Success 200 {object} models.Response
 // @Failure 400 {object} models.Error
 // @Failure 500 {object} models.Error
@@ -47,38 +54,55 @@ func (h *userHandlerImpl) RegisterUser(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&userReq); err != nil {
 		h.logger.Error("BindJSON error", "error", err)
-		ctx.JSON(400, models.Error{Message: "Invalid request body"})
+		response.Error(ctx, 400, "Invalid request body")
 		return
 	}
+	if userReq.Locale != "" && !mail.IsSupportedLocale(userReq.Locale) {
+		response.Error(ctx, 400, "Unsupported locale")
+		return
+	}
+	userReq.Locale = mail.ResolveLocale(userReq.Locale, ctx.GetHeader("Accept-Language"))
 
-	exists, err := h.authService.EmailExists(userReq.Email)
+	exists, err := h.authService.EmailExists(ctx.Request.Context(), userReq.Email)
 	if err != nil {
-		h.logger.Error("EmailExists error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error checking email"})
+		fail(ctx, h.logger, "EmailExists", err)
 		return
 	}
 	if exists {
-		ctx.JSON(400, models.Error{Message: "Email already exists"})
+		response.Error(ctx, 400, "Email already exists")
 		return
 	}
 
-	resp, err := h.authService.RegisterUser(userReq)
+	resp, err := h.authService.RegisterUser(ctx.Request.Context(), userReq)
 	if err != nil {
-		h.logger.Error("RegisterUser error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error registering user"})
+		fail(ctx, h.logger, "RegisterUser", err)
 		return
 	}
 
+	// The account exists at this point; if the email cannot be sent the user
+	// can ask for a new one through /auth/resend-verification.
+	link, err := h.authService.CreateEmailVerification(ctx.Request.Context(), userReq.Email)
+	if err != nil {
+		h.logger.Error("CreateEmailVerification error", "error", err)
+	} else if err = h.authService.SendVerificationEmail(ctx.Request.Context(), userReq.Email, link, ctx.GetHeader("Accept-Language")); err != nil {
+		h.logger.Error("SendVerificationEmail error", "error", err)
+	}
+
 	ctx.JSON(200, resp)
 }
 
 // @Summary Login user
-// @Description Login a user
+// @Description Login a user. When the user has two-factor authentication enabled the response
+// @Description is a models.MFAPendingResp and the login is completed with /auth/mfa/verify.
 // @Accept json
 // @Produce json
 // @Param user body models.LoginUserReq true "User credentials"
 // @Success 200 {object} models.LoginUserResp
+// @Success 202 {object} models.MFAPendingResp
 // @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 429 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Failure 404 {object} models.Error
 // @router /auth/login [post]
@@ -87,160 +111,207 @@ func (h *userHandlerImpl) LoginUser(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&userReq); err != nil {
 		h.logger.Error("BindJSON error", "error", err)
-		ctx.JSON(400, models.Error{Message: "Invalid request body"})
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+	if throttled(ctx, h.authService, h.logger, service.ThrottleLogin, userReq.Email) {
 		return
 	}
 
-	exists, err := h.authService.EmailExists(userReq.Email)
+	exists, err := h.authService.EmailExists(ctx.Request.Context(), userReq.Email)
 	if err != nil {
-		h.logger.Error("EmailExists error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error checking email"})
+		fail(ctx, h.logger, "EmailExists", err)
 		return
 	}
 	if !exists {
-		ctx.JSON(404, models.Error{Message: "Email not found"})
+		recordFailure(ctx, h.authService, h.logger, service.ThrottleLogin, userReq.Email)
+		response.Error(ctx, 404, "Email not found")
 		return
 	}
 
-	user, err := h.authService.LoginUser(userReq)
+	user, err := h.authService.LoginUser(ctx.Request.Context(), userReq, requestClient(ctx))
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		recordFailure(ctx, h.authService, h.logger, service.ThrottleLogin, userReq.Email)
+	}
 	if err != nil {
-		h.logger.Error("LoginUser error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error logging in"})
+		fail(ctx, h.logger, "LoginUser", err)
 		return
 	}
+	h.authService.ClearFailures(ctx.Request.Context(), service.ThrottleLogin, userReq.Email, ctx.ClientIP())
 
-	accessToken, err := token.GeneratedJWTTokenAccess(models.User{
-		ID:    user.ID,
-		Email: user.Email,
-		Role:  user.Role,
-	})
+	mfaEnabled, err := h.authService.IsMFAEnabled(ctx.Request.Context(), user.ID)
 	if err != nil {
-		h.logger.Error("GeneratedJwtTokenAccess error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error generating access token"})
+		fail(ctx, h.logger, "IsMFAEnabled", err)
+		return
+	}
+	if mfaEnabled {
+		pending, err := h.authService.StartMFAChallenge(user.ID)
+		if err != nil {
+			h.logger.Error("StartMFAChallenge error", "error", err)
+			response.Error(ctx, 500, "Error logging in")
+			return
+		}
+		ctx.JSON(202, pending)
 		return
 	}
 
-	refreshToken, err := token.GeneratedJwtTokenRefresh(models.User{
-		ID:    user.ID,
-		Email: user.Email,
-		Role:  user.Role,
+	resp, err := h.authService.StartSession(ctx.Request.Context(), user, models.ClientInfo{
+		IPAddress:  ctx.ClientIP(),
+		UserAgent:  ctx.Request.UserAgent(),
+		DeviceName: userReq.DeviceName,
 	})
 	if err != nil {
-		h.logger.Error("GeneratedJwtTokenRefresh error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error generating refresh token"})
+		fail(ctx, h.logger, "StartSession", err)
 		return
 	}
 
-	_, err = h.authService.SaveRefreshToken(models.RefreshToken{
-		Email: user.Email,
-		RefreshToken: refreshToken,
-        ExpiresAt: time.Now().Add(7 * 24 * time.Hour).Format("2006-01-02 15:04:05"),
-	})
-	
-	if err!= nil {
-        h.logger.Error("SaveRefreshToken error", "error", err)
-        ctx.JSON(500, models.Error{Message: "Error saving refresh token"})
-        return
-    }
-
-	ctx.SetCookie("access_token", accessToken, 3600, "/", "", false, true)
-	ctx.JSON(200, models.LoginUserResp{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	})
+	setAuthCookies(ctx, resp)
+	ctx.JSON(200, resp)
 }
 
-// @Summary Logout user
-// @Description Logout a user
+// @Summary Complete a two-factor login
+// @Description Exchanges the mfa_token from /auth/login and a TOTP or recovery code for the access/refresh pair
 // @Accept json
 // @Produce json
-// @Success 200 {object} models.Response
+// @Param verify body models.MFAVerifyReq true "MFA token and code"
+// @Success 200 {object} models.LoginUserResp
+// @Failure 400 {object} models.Error
 // @Failure 401 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 429 {object} models.Error
 // @Failure 500 {object} models.Error
-// @Failure 404 {object} models.Error
-// @router /auth/logout [post]
-func (h *userHandlerImpl) LogOutUser(ctx *gin.Context) {
-	val, ok := ctx.Get("claims")
-	if !ok {
-		h.logger.Error("Token not found in context")
-		ctx.JSON(401, models.Error{Message: "Unauthorized"})
+// @router /auth/mfa/verify [post]
+func (h *userHandlerImpl) VerifyMFA(ctx *gin.Context) {
+	var req models.MFAVerifyReq
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("BindJSON error", "error", err)
+		response.Error(ctx, 400, "Invalid request body")
 		return
 	}
-	claims, ok := val.(*token.Claims)
-	if !ok {
-		h.logger.Error("Token claims not found in context")
-		ctx.JSON(401, models.Error{Message: "Unauthorized"})
+
+	user, err := h.authService.CompleteMFA(ctx.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		fail(ctx, h.logger, "CompleteMFA", err)
 		return
 	}
 
-	_, err := h.authService.DeleteUser(claims.ID)
+	resp, err := h.authService.StartSession(ctx.Request.Context(), user, models.ClientInfo{
+		IPAddress:  ctx.ClientIP(),
+		UserAgent:  ctx.Request.UserAgent(),
+		DeviceName: req.DeviceName,
+	})
 	if err != nil {
-		h.logger.Error("DeleteUser error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error logging out"})
+		fail(ctx, h.logger, "StartSession", err)
 		return
 	}
 
-	tokenstr := ctx.Request.Header.Get("Authorization")
-	_, err = h.authService.AddTokenBlacklist(tokenstr, time.Duration(claims.ExpiresAt))
-	if err != nil {
-		h.logger.Error("AddTokenBlacklist error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error blacklisting token"})
+	setAuthCookies(ctx, resp)
+	ctx.JSON(200, resp)
+}
+
+// @Summary Logout user
+// @Description Ends the current session: the access token is revoked and the refresh token can no longer be used. Other sessions and the account itself are left untouched.
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @router /auth/logout [post]
+func (h *userHandlerImpl) LogOutUser(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
 		return
 	}
 
-	_, err = h.authService.InvalidateRefreshToken(claims.Email)
+	resp, err := h.authService.LogOut(ctx.Request.Context(), claims, requestClient(ctx))
 	if err != nil {
-		h.logger.Error("InvalidateRefreshToken error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error invalidating refresh token"})
+		fail(ctx, h.logger, "LogOut", err)
 		return
 	}
 
-	ctx.SetCookie("access_token", "", -1, "/", "", false, true)
-	ctx.JSON(200, models.Response{
-		Status:  "success",
-		Message: "User logged out",
-	})
+	clearAuthCookies(ctx)
+	ctx.JSON(200, resp)
 }
 
 // @summary Update user role
-// @Description Update user role
+// @Description Replaces the roles of a user. Requires roles:write.
 // @Accept json
 // @Produce json
 // @Param user body models.ManageUserRoles true "User details"
 // @Success 200 {object} models.Response
 // @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Router /auth/roles [post]
 func (h *userHandlerImpl) ManageUserRoles(ctx *gin.Context) {
-	val, ok := ctx.Get("claims")
-	if!ok {
-        h.logger.Error("Token not found in context")
-        ctx.JSON(401, models.Error{Message: "Unauthorized"})
-        return
-    }
-	claims, ok := val.(*token.Claims)
-	if!ok {
-        h.logger.Error("Token claims not found in context")
-        ctx.JSON(401, models.Error{Message: "Unauthorized"})
-        return
-    }
-	if claims.Role!= "admin" {
-		ctx.JSON(403, models.Error{Message: "Only admin can manage user roles"})
-        return
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
 	}
+
 	var userReq models.ManageUserRoles
 
 	if err := ctx.ShouldBindJSON(&userReq); err != nil {
 		h.logger.Error("BindJSON error", "error", err)
-		ctx.JSON(400, models.Error{Message: "Invalid request body"})
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+
+	resp, err := h.authService.UpdateUserRoles(ctx.Request.Context(), userReq, claims.ID, requestClient(ctx))
+	if err != nil {
+		fail(ctx, h.logger, "UpdateUserRoles", err)
 		return
 	}
 
-	resp, err := h.authService.UpdateUserRoles(userReq)
+	ctx.JSON(200, resp)
+}
+
+// @Summary List roles
+// @Description Lists the roles and the permissions each one grants. Requires roles:read.
+// @Produce json
+// @Success 200 {object} models.RolesList
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/roles [get]
+func (h *userHandlerImpl) ListRoles(ctx *gin.Context) {
+	resp, err := h.authService.ListRoles(ctx.Request.Context())
 	if err != nil {
-		h.logger.Error("UpdateUserRoles error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error updating user roles"})
+		fail(ctx, h.logger, "ListRoles", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @summary Unlock account
+// @Description Lifts the lockout and backoff of an account after repeated failed attempts. Requires accounts:unlock.
+// @Accept json
+// @Produce json
+// @Param unlock body models.UnlockAccountReq true "Account email"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/unlock [post]
+func (h *userHandlerImpl) UnlockAccount(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+	var req models.UnlockAccountReq
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Email == "" {
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+
+	resp, err := h.authService.UnlockAccount(ctx.Request.Context(), req.Email, claims.ID)
+	if err != nil {
+		fail(ctx, h.logger, "UnlockAccount", err)
 		return
 	}
 
@@ -252,8 +323,10 @@ func (h *userHandlerImpl) ManageUserRoles(ctx *gin.Context) {
 // @accept json
 // @Produce json
 // @param user body models.ForgotPassword true "User details"
+// @Param Accept-Language header string false "Locale of the email when the account has none saved"
 // @Success 200 {object} models.Response
 // @Failure 400 {object} models.Error
+// @Failure 429 {object} models.Error
 // @Failure 500 {object} models.Error
 // @router /auth/forgot-password [post]
 func (h *userHandlerImpl) ForgotPassword(ctx *gin.Context) {
@@ -261,23 +334,30 @@ func (h *userHandlerImpl) ForgotPassword(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&userReq); err != nil {
 		h.logger.Error("BindJSON error", "error", err)
-		ctx.JSON(400, models.Error{Message: "Invalid request body"})
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+	if throttled(ctx, h.authService, h.logger, service.ThrottleForgotPassword, userReq.Email) {
 		return
 	}
+	// Every request counts, so codes cannot be requested in a tight loop.
+	recordFailure(ctx, h.authService, h.logger, service.ThrottleForgotPassword, userReq.Email)
 
-	code := strconv.Itoa(rand.Intn(999999) + 100000)
+	code, err := token.GenerateResetCode()
+	if err != nil {
+		fail(ctx, h.logger, "GenerateResetCode", err)
+		return
+	}
 
-	_, err := h.authService.StoreCode(userReq.Email, code, time.Duration(time.Minute*5))
+	_, err = h.authService.StoreCode(ctx.Request.Context(), userReq.Email, code, time.Duration(time.Minute*5))
 	if err != nil {
-		h.logger.Error("StoreCode error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error storing code"})
+		fail(ctx, h.logger, "StoreCode", err)
 		return
 	}
 
-	err = helper.SendPasswordResetEmail(userReq.Email, code)
+	err = h.authService.SendPasswordResetEmail(ctx.Request.Context(), userReq.Email, code, ctx.GetHeader("Accept-Language"))
 	if err != nil {
-		h.logger.Error("SendResetPasswordEmail error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error sending email"})
+		fail(ctx, h.logger, "SendPasswordResetEmail", err)
 		return
 	}
 
@@ -288,12 +368,13 @@ func (h *userHandlerImpl) ForgotPassword(ctx *gin.Context) {
 }
 
 // @summary Reset password
-// @Description Reset user password
+// @Description Reset user password and sign out all of its sessions
 // @accept json
 // @produce json
 // @param resetPassword body models.ResetPassword true "Reset password details"
 // @Success 200 {object} models.Response
 // @Failure 400 {object} models.Error
+// @Failure 429 {object} models.Error
 // @Failure 500 {object} models.Error
 // @router /auth/reset-password [post]
 func (h *userHandlerImpl) ResetPassword(ctx *gin.Context) {
@@ -301,25 +382,28 @@ func (h *userHandlerImpl) ResetPassword(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&resetPasswordReq); err != nil {
 		h.logger.Error("BindJSON error", "error", err)
-		ctx.JSON(400, models.Error{Message: "Invalid request body"})
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+	if throttled(ctx, h.authService, h.logger, service.ThrottleResetPassword, resetPasswordReq.Email) {
 		return
 	}
 
-	isvalid, err := h.authService.IsCodeValid(resetPasswordReq.Email, resetPasswordReq.Code)
+	isvalid, err := h.authService.IsCodeValid(ctx.Request.Context(), resetPasswordReq.Email, resetPasswordReq.Code)
 	if err != nil {
-		h.logger.Error("IsCodeValid error", "error", err)
-		ctx.JSON(400, models.Error{Message: "Invalid code"})
+		fail(ctx, h.logger, "IsCodeValid", err)
 		return
 	}
 	if !isvalid {
-		ctx.JSON(400, models.Error{Message: "Invalid email or code"})
+		recordFailure(ctx, h.authService, h.logger, service.ThrottleResetPassword, resetPasswordReq.Email)
+		response.Error(ctx, 400, "Invalid email or code")
 		return
 	}
+	h.authService.ClearFailures(ctx.Request.Context(), service.ThrottleResetPassword, resetPasswordReq.Email, ctx.ClientIP())
 
-	resp, err := h.authService.ResetPassword(resetPasswordReq)
+	resp, err := h.authService.ResetPassword(ctx.Request.Context(), resetPasswordReq, requestClient(ctx))
 	if err != nil {
-		h.logger.Error("ResetPassword error", "error", err)
-		ctx.JSON(500, models.Error{Message: "Error resetting password"})
+		fail(ctx, h.logger, "ResetPassword", err)
 		return
 	}
 
@@ -327,53 +411,169 @@ func (h *userHandlerImpl) ResetPassword(ctx *gin.Context) {
 }
 
 // @summary Refresh token
-// @description Refresh user token
+// @description Exchanges a refresh token for a new access/refresh pair. The refresh token
+// @description is read from the request body or, if absent, from the refresh_token cookie.
+// @description Each refresh token can be used once; presenting a used one revokes the session.
 // @accept json
 // @produce json
-// @Success 200 {object} map[string]interface{}
+// @param refreshToken body models.RefreshTokenReq false "Refresh token"
+// @Success 200 {object} models.LoginUserResp
 // @Failure 401 {object} models.Error
 // @Failure 500 {object} models.Error
 // @router /auth/refresh-token [post]
 func (h *userHandlerImpl) RefreshToken(ctx *gin.Context) {
-	val, ok := ctx.Get("claims")
-	if !ok {
-		h.logger.Error("Token not found in context")
-		ctx.JSON(401, models.Error{Message: "Unauthorized"})
+	var req models.RefreshTokenReq
+
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			h.logger.Error("BindJSON error", "error", err)
+			response.Error(ctx, 400, "Invalid request body")
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = ctx.Cookie("refresh_token")
+	}
+	if req.RefreshToken == "" {
+		response.Error(ctx, 401, "Refresh token is required")
 		return
 	}
-	claims, ok := val.(*token.Claims)
-	if !ok {
-		h.logger.Error("Token claims not found in context")
-		ctx.JSON(401, models.Error{Message: "Unauthorized"})
+
+	resp, err := h.authService.RefreshSession(ctx.Request.Context(), req.RefreshToken, models.ClientInfo{
+		IPAddress: ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	})
+	if errors.Is(err, service.ErrRefreshTokenReused) {
+		clearAuthCookies(ctx)
+	}
+	if err != nil {
+		fail(ctx, h.logger, "RefreshSession", err)
+		return
+	}
+
+	setAuthCookies(ctx, resp)
+	ctx.JSON(200, resp)
+}
+
+// @summary Verify email
+// @description Activates a pending account. The token comes from the link in the verification
+// @description email and is accepted either as the token query parameter or in the JSON body.
+// @accept json
+// @produce json
+// @param token query string false "Verification token"
+// @param verify body models.VerifyEmailReq false "Verification token"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Error
+// @router /auth/verify-email [get]
+// @router /auth/verify-email [post]
+func (h *userHandlerImpl) VerifyEmail(ctx *gin.Context) {
+	req := models.VerifyEmailReq{Token: ctx.Query("token")}
+
+	if req.Token == "" && ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			h.logger.Error("BindJSON error", "error", err)
+			response.Error(ctx, 400, "Invalid request body")
+			return
+		}
+	}
+	if req.Token == "" {
+		response.Error(ctx, 400, "Verification token is required")
 		return
 	}
 
-	is, err := h.authService.IsRefreshTokenValid(claims.Email)
+	resp, err := h.authService.VerifyEmail(ctx.Request.Context(), req.Token)
 	if err != nil {
-		h.logger.Error("IsRefreshTokenValid error", "error", err)
-		ctx.JSON(401, models.Error{Message: "Unauthorized"})
+		fail(ctx, h.logger, "VerifyEmail", err)
 		return
 	}
-	if !is {
-		ctx.JSON(401, models.Error{Message: "Invalid refresh token"})
+
+	ctx.JSON(200, resp)
+}
+
+// @summary Confirm email change
+// @description Switches the account to the new email address requested through UpdateUserProfile.
+// @description The token comes from the link emailed to the new address and is accepted either
+// @description as the token query parameter or in the JSON body.
+// @accept json
+// @produce json
+// @param token query string false "Email change token"
+// @param confirm body models.VerifyEmailReq false "Email change token"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Error
+// @Failure 409 {object} models.Error
+// @router /auth/confirm-email-change [get]
+// @router /auth/confirm-email-change [post]
+func (h *userHandlerImpl) ConfirmEmailChange(ctx *gin.Context) {
+	req := models.VerifyEmailReq{Token: ctx.Query("token")}
+
+	if req.Token == "" && ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			h.logger.Error("BindJSON error", "error", err)
+			response.Error(ctx, 400, "Invalid request body")
+			return
+		}
+	}
+	if req.Token == "" {
+		response.Error(ctx, 400, "Email change token is required")
 		return
 	}
 
-	accessToken, err := token.GeneratedJWTTokenAccess(models.User{
-		ID:    claims.ID,
-		Email: claims.Email,
-		Role:  claims.Role,
-	})
+	resp, err := h.authService.ConfirmEmailChange(ctx.Request.Context(), req.Token)
 	if err != nil {
-		h.logger.Error("GeneratedJwtTokenRefresh error", "error", err)
-		ctx.JSON(500, models.Error{
-			Message: "Error generating refresh token",
-		})
+		fail(ctx, h.logger, "ConfirmEmailChange", err)
 		return
 	}
 
-	ctx.SetCookie("access_token", accessToken, 3600, "/", "", false, true)
-	ctx.JSON(200, gin.H{
-		"access_token": accessToken,
+	ctx.JSON(200, resp)
+}
+
+// @summary Resend verification email
+// @description Sends a new verification link to a pending account. The response is the same
+// @description whether or not the address belongs to a pending account.
+// @accept json
+// @produce json
+// @param resend body models.ResendVerificationReq true "Email"
+// @Param Accept-Language header string false "Locale of the email when the account has none saved"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Error
+// @Failure 500 {object} models.Error
+// @router /auth/resend-verification [post]
+func (h *userHandlerImpl) ResendVerification(ctx *gin.Context) {
+	var req models.ResendVerificationReq
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("BindJSON error", "error", err)
+		response.Error(ctx, 400, "Invalid request body")
+		return
+	}
+
+	link, err := h.authService.ResendVerification(ctx.Request.Context(), req.Email)
+	if err != nil {
+		fail(ctx, h.logger, "ResendVerification", err)
+		return
+	}
+
+	if link != "" {
+		if err := h.authService.SendVerificationEmail(ctx.Request.Context(), req.Email, link, ctx.GetHeader("Accept-Language")); err != nil {
+			h.logger.Error("SendVerificationEmail error", "error", err)
+			response.Error(ctx, 500, "Error sending email")
+			return
+		}
+	}
+
+	ctx.JSON(200, models.Response{
+		Status:  "success",
+		Message: "If the account is awaiting verification, a new email has been sent",
 	})
 }
+
+// setAuthCookies stores the token pair in HttpOnly cookies for browser clients.
+func setAuthCookies(ctx *gin.Context, tokens *models.LoginUserResp) {
+	ctx.SetCookie("access_token", tokens.AccessToken, 3600, "/", "", false, true)
+	ctx.SetCookie("refresh_token", tokens.RefreshToken, int(token.RefreshTokenTTL.Seconds()), "/api/v1/auth/refresh-token", "", false, true)
+}
+
+func clearAuthCookies(ctx *gin.Context) {
+	ctx.SetCookie("access_token", "", -1, "/", "", false, true)
+	ctx.SetCookie("refresh_token", "", -1, "/api/v1/auth/refresh-token", "", false, true)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"auth-service/api/response"
+	"auth-service/models"
+	"auth-service/service"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebAuthnHandler interface {
+	RegisterBegin(ctx *gin.Context)
+	RegisterFinish(ctx *gin.Context)
+	LoginBegin(ctx *gin.Context)
+	LoginFinish(ctx *gin.Context)
+}
+
+type webAuthnHandlerImpl struct {
+	authService service.AuthService
+	logger      *slog.Logger
+}
+
+func NewWebAuthnHandler(authService service.AuthService, logger *slog.Logger) WebAuthnHandler {
+	return &webAuthnHandlerImpl{authService: authService, logger: logger}
+}
+
+// @Summary Begin passkey registration
+// @Description Returns the options for navigator.credentials.create() and a session_id for the finish request
+// @Produce json
+// @Success 200 {object} models.WebAuthnBeginResp
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/webauthn/register/begin [post]
+func (h *webAuthnHandlerImpl) RegisterBegin(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.BeginWebAuthnRegistration(ctx.Request.Context(), claims.ID)
+	if err != nil {
+		fail(ctx, h.logger, "BeginWebAuthnRegistration", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Finish passkey registration
+// @Description Verifies the attestation returned by navigator.credentials.create() and stores the credential
+// @Accept json
+// @Produce json
+// @Param session_id query string true "Session ID from the begin request"
+// @Param name query string false "Label for the passkey"
+// @Param credential body object true "PublicKeyCredential from the browser"
+// @Success 200 {object} models.WebAuthnCredential
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/webauthn/register/finish [post]
+func (h *webAuthnHandlerImpl) RegisterFinish(ctx *gin.Context) {
+	claims, ok := requireClaims(ctx, h.logger)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.FinishWebAuthnRegistration(ctx.Request.Context(), claims.ID, ctx.Query("session_id"), ctx.Query("name"), ctx.Request.Body)
+	if err != nil {
+		fail(ctx, h.logger, "FinishWebAuthnRegistration", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Begin passkey login
+// @Description Returns the options for navigator.credentials.get() and a session_id for the finish request.
+// @Description Without an email any discoverable passkey of this site can be used.
+// @Accept json
+// @Produce json
+// @Param login body models.WebAuthnLoginBeginReq false "Optional email"
+// @Success 200 {object} models.WebAuthnBeginResp
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/webauthn/login/begin [post]
+func (h *webAuthnHandlerImpl) LoginBegin(ctx *gin.Context) {
+	var req models.WebAuthnLoginBeginReq
+
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			h.logger.Error("BindJSON error", "error", err)
+			response.Error(ctx, 400, "Invalid request body")
+			return
+		}
+	}
+
+	resp, err := h.authService.BeginWebAuthnLogin(ctx.Request.Context(), req.Email)
+	if err != nil {
+		fail(ctx, h.logger, "BeginWebAuthnLogin", err)
+		return
+	}
+
+	ctx.JSON(200, resp)
+}
+
+// @Summary Finish passkey login
+// @Description Verifies the assertion returned by navigator.credentials.get() and issues the same
+// @Description access/refresh pair and cookies as /auth/login
+// @Accept json
+// @Produce json
+// @Param session_id query string true "Session ID from the begin request"
+// @Param device_name query string false "Name of the device for the session list"
+// @Param credential body object true "PublicKeyCredential from the browser"
+// @Success 200 {object} models.LoginUserResp
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /auth/webauthn/login/finish [post]
+func (h *webAuthnHandlerImpl) LoginFinish(ctx *gin.Context) {
+	user, err := h.authService.FinishWebAuthnLogin(ctx.Request.Context(), ctx.Query("session_id"), ctx.Request.Body)
+	if err != nil {
+		fail(ctx, h.logger, "FinishWebAuthnLogin", err)
+		return
+	}
+
+	resp, err := h.authService.StartSession(ctx.Request.Context(), user, models.ClientInfo{
+		IPAddress:  ctx.ClientIP(),
+		UserAgent:  ctx.Request.UserAgent(),
+		DeviceName: ctx.Query("device_name"),
+	})
+	if err != nil {
+		fail(ctx, h.logger, "StartSession", err)
+		return
+	}
+
+	setAuthCookies(ctx, resp)
+	ctx.JSON(200, resp)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"auth-service/api/token"
+	"auth-service/config"
+	"auth-service/models"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WellKnownHandler interface {
+	JWKS(ctx *gin.Context)
+	OpenIDConfiguration(ctx *gin.Context)
+}
+
+type wellKnownHandlerImpl struct {
+	logger *slog.Logger
+}
+
+func NewWellKnownHandler(logger *slog.Logger) WellKnownHandler {
+	return &wellKnownHandlerImpl{logger: logger}
+}
+
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify access tokens issued by this service
+// @Produce json
+// @Success 200 {object} token.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *wellKnownHandlerImpl) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(200, token.PublicJWKS())
+}
+
+// @Summary OpenID Connect discovery
+// @Description OpenID provider metadata for this service
+// @Produce json
+// @Success 200 {object} models.OpenIDConfiguration
+// @Router /.well-known/openid-configuration [get]
+func (h *wellKnownHandlerImpl) OpenIDConfiguration(ctx *gin.Context) {
+	issuer := config.Load().ISSUER_URL
+
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.JSON(200, models.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/authorize",
+		TokenEndpoint:                     issuer + "/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "password", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  token.SigningAlgorithms(),
+		ScopesSupported:                   []string{"openid", "email", "profile"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "email", "given_name", "family_name"},
+	})
+}
@@ -1,52 +1,70 @@
 package middleware
 
 import (
+	"auth-service/api/response"
 	"auth-service/api/token"
 	"auth-service/service"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func IsAuthenticated(service service.AuthService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		// Cookie'dan tokenni olish
+		// Authorization header yoki cookie'dan tokenni olish
 		tokenString, err := ctx.Cookie("access_token")
-		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{
-				"Error": "Authorization cookie not found",
-			})
-			ctx.Abort()
-			return
+		if bearer, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer "); ok {
+			tokenString, err = bearer, nil
 		}
-
-		ok, err := service.IsTokenBlacklisted(tokenString)
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{
-				"Error": "Unauthorized",
-			})
-			ctx.Abort()
+			response.Error(ctx, http.StatusUnauthorized, "Authorization token not found")
 			return
 		}
-		if ok {
-			ctx.JSON(http.StatusUnauthorized, gin.H{
-				"Error": "Token is blacklisted",
-			})
-			ctx.Abort()
+
+		// Personal access tokens are opaque and resolved from the database.
+		if token.IsPersonalToken(tokenString) {
+			claims, err := service.AuthenticatePersonalToken(ctx.Request.Context(), tokenString)
+			if err != nil {
+				response.Error(ctx, http.StatusUnauthorized, "Invalid token")
+				return
+			}
+			ctx.Set("claims", claims)
+			ctx.Next()
 			return
 		}
 
 		// JWT tokenni tekshirish va tasdiqlash
 		claims, err := token.ExtractAndValidateToken(tokenString)
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{
-				"Error": "Invalid token",
-			})
-			ctx.Abort()
+			response.Error(ctx, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+
+		revoked, err := service.IsTokenRevoked(ctx.Request.Context(), claims.Id)
+		if err != nil {
+			response.Error(ctx, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if revoked {
+			response.Error(ctx, http.StatusUnauthorized, "Token has been revoked")
 			return
 		}
 
+		if claims.SessionID != "" {
+			revoked, err := service.IsSessionRevoked(ctx.Request.Context(), claims.SessionID)
+			if err != nil {
+				response.Error(ctx, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			if revoked {
+				response.Error(ctx, http.StatusUnauthorized, "Session has been revoked")
+				return
+			}
+		}
+
 		// Foydalanuvchi ma'lumotlarini context ga qo'shish
 		ctx.Set("claims", claims)
 
@@ -54,9 +72,67 @@ func IsAuthenticated(service service.AuthService) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission lets the request through only when the access token
+// carries permission. It has to run after IsAuthenticated.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		val, _ := ctx.Get("claims")
+		claims, ok := val.(*token.Claims)
+		if !ok {
+			response.Error(ctx, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if !claims.HasPermission(permission) {
+			response.Error(ctx, http.StatusForbidden, "Missing permission "+permission)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireSessionToken rejects requests authenticated with a personal access
+// token. It guards account management, so that a leaked API token cannot be
+// used to mint new tokens or take over sessions and second factors. It has to
+// run after IsAuthenticated.
+func RequireSessionToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		val, _ := ctx.Get("claims")
+		claims, ok := val.(*token.Claims)
+		if !ok {
+			response.Error(ctx, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if claims.Type == token.TypePersonal {
+			response.Error(ctx, http.StatusForbidden, "Personal access tokens cannot be used here")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequestID tags every request with an ID, taken from the X-Request-ID header
+// when the client or a proxy sent a reasonable one and generated otherwise.
+// It is echoed in the response header and in error bodies.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(response.RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		c.Set(response.RequestIDKey, id)
+		c.Header(response.RequestIDHeader, id)
+		c.Next()
+	}
+}
+
 func LogMiddleware(logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		logger.Info("Request received",
+			slog.String("request_id", c.GetString(response.RequestIDKey)),
 			slog.String("method", c.Request.Method),
 			slog.String("path", c.Request.URL.Path),
 		)
@@ -64,6 +140,7 @@ func LogMiddleware(logger *slog.Logger) gin.HandlerFunc {
 		c.Next()
 
 		logger.Info("Response sent",
+			slog.String("request_id", c.GetString(response.RequestIDKey)),
 			slog.Int("status", c.Writer.Status()),
 		)
 	}
@@ -0,0 +1,44 @@
+// Package response writes error responses in the envelope shared by the
+// handlers and middleware.
+package response
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader carries the request ID in both directions.
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the gin context key the request ID is stored under.
+	RequestIDKey = "request_id"
+)
+
+// Error aborts the request with status and message. The code is derived from
+// status.
+func Error(ctx *gin.Context, status int, message string) {
+	ctx.AbortWithStatusJSON(status, models.Error{
+		Code:      string(apperr.CodeForHTTPStatus(status)),
+		Message:   message,
+		RequestID: ctx.GetString(RequestIDKey),
+	})
+}
+
+// Fail aborts the request with the status, code, message and details of the
+// domain error in err's chain. Other errors are reported as a bare internal
+// error; the caller is expected to have logged them.
+func Fail(ctx *gin.Context, err error) {
+	e, ok := apperr.As(err)
+	if !ok || e.Code == apperr.Internal {
+		Error(ctx, 500, "Internal server error")
+		return
+	}
+	ctx.AbortWithStatusJSON(apperr.HTTPStatus(e.Code), models.Error{
+		Code:      string(e.Code),
+		Message:   e.Message,
+		Details:   e.Details,
+		RequestID: ctx.GetString(RequestIDKey),
+	})
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"auth-service/api/handler"
 	"auth-service/api/middleware"
+	"auth-service/api/response"
 	"auth-service/config"
 	"auth-service/service"
 	"fmt"
@@ -48,7 +49,18 @@ func (c *controllerImpl) StartServer(cfg *config.Config) error {
 func (c *controllerImpl) SetupRoutes(authService service.AuthService, logger *slog.Logger) {
 	h := handler.NewMainHandler(authService, logger)
 
+	c.router.Use(middleware.RequestID())
+	c.router.NoRoute(func(ctx *gin.Context) {
+		response.Error(ctx, 404, "Not found")
+	})
+
 	c.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	c.router.GET("/.well-known/jwks.json", h.WellKnownHandler().JWKS)
+	c.router.GET("/.well-known/openid-configuration", h.WellKnownHandler().OpenIDConfiguration)
+	c.router.GET("/authorize", h.OAuthHandler().Authorize)
+	c.router.POST("/authorize", h.OAuthHandler().AuthorizeSubmit)
+	c.router.POST("/token", h.OAuthHandler().Token)
+	c.router.GET("/userinfo", middleware.IsAuthenticated(authService), h.OAuthHandler().UserInfo)
 	router := c.router.Group("/api/v1")
 
 	auth1 := router.Group("/auth")
@@ -57,12 +69,77 @@ func (c *controllerImpl) SetupRoutes(authService service.AuthService, logger *sl
 		auth1.POST("/reset-password", h.AuthHandler().ResetPassword)
 		auth1.POST("/register", h.AuthHandler().RegisterUser)
 		auth1.POST("/login", h.AuthHandler().LoginUser)
+		auth1.POST("/refresh-token", h.AuthHandler().RefreshToken)
+		auth1.GET("/verify-email", h.AuthHandler().VerifyEmail)
+		auth1.POST("/verify-email", h.AuthHandler().VerifyEmail)
+		auth1.GET("/confirm-email-change", h.AuthHandler().ConfirmEmailChange)
+		auth1.POST("/confirm-email-change", h.AuthHandler().ConfirmEmailChange)
+		auth1.POST("/resend-verification", h.AuthHandler().ResendVerification)
+		auth1.POST("/mfa/verify", h.AuthHandler().VerifyMFA)
+		auth1.POST("/webauthn/login/begin", h.WebAuthnHandler().LoginBegin)
+		auth1.POST("/webauthn/login/finish", h.WebAuthnHandler().LoginFinish)
 	}
 
 	auth := router.Group("/auth", middleware.IsAuthenticated(authService), middleware.LogMiddleware(logger))
 	{
-		auth.POST("/logout", h.AuthHandler().LogOutUser)
-		auth.POST("/roles", h.AuthHandler().ManageUserRoles)
-		auth.POST("/refresh-token", h.AuthHandler().RefreshToken)
+		auth.GET("/roles", middleware.RequirePermission(service.PermRolesRead), h.AuthHandler().ListRoles)
+		auth.POST("/roles", middleware.RequirePermission(service.PermRolesWrite), h.AuthHandler().ManageUserRoles)
+		auth.POST("/unlock", middleware.RequirePermission(service.PermAccountsUnlock), h.AuthHandler().UnlockAccount)
+
+		// Account management is not available to personal access tokens.
+		sessionOnly := middleware.RequireSessionToken()
+
+		auth.POST("/logout", sessionOnly, h.AuthHandler().LogOutUser)
+		auth.GET("/sessions", sessionOnly, h.SessionHandler().GetSessions)
+		auth.POST("/sessions/revoke-others", sessionOnly, h.SessionHandler().RevokeOtherSessions)
+		auth.DELETE("/sessions/:id", sessionOnly, h.SessionHandler().RevokeSession)
+
+		auth.GET("/tokens", sessionOnly, h.PersonalTokenHandler().ListTokens)
+		auth.POST("/tokens", sessionOnly, h.PersonalTokenHandler().CreateToken)
+		auth.DELETE("/tokens/:id", sessionOnly, h.PersonalTokenHandler().RevokeToken)
+
+		auth.GET("/mfa", sessionOnly, h.MFAHandler().GetStatus)
+		auth.POST("/mfa/totp/enroll", sessionOnly, h.MFAHandler().EnrollTOTP)
+		auth.POST("/mfa/totp/confirm", sessionOnly, h.MFAHandler().ConfirmTOTP)
+		auth.POST("/mfa/recovery-codes", sessionOnly, h.MFAHandler().RegenerateRecoveryCodes)
+		auth.DELETE("/mfa/users/:id", middleware.RequirePermission(service.PermMFAReset), h.MFAHandler().ResetUserMFA)
+
+		auth.POST("/webauthn/register/begin", sessionOnly, h.WebAuthnHandler().RegisterBegin)
+		auth.POST("/webauthn/register/finish", sessionOnly, h.WebAuthnHandler().RegisterFinish)
+
+		auth.GET("/emails", middleware.RequirePermission(service.PermEmailsRead), h.EmailHandler().ListEmails)
+		auth.GET("/emails/:id", middleware.RequirePermission(service.PermEmailsRead), h.EmailHandler().GetEmail)
+		auth.POST("/emails/:id/redrive", middleware.RequirePermission(service.PermEmailsWrite), h.EmailHandler().RedriveEmail)
+
+		auth.GET("/audit-events", middleware.RequirePermission(service.PermAuditRead), h.AuditHandler().ListEvents)
+		auth.GET("/audit-events/export", middleware.RequirePermission(service.PermAuditRead), h.AuditHandler().ExportEvents)
+	}
+
+	users := router.Group("/users", middleware.IsAuthenticated(authService), middleware.LogMiddleware(logger))
+	{
+		users.DELETE("/me", middleware.RequireSessionToken(), h.AccountHandler().DeleteAccount)
+		users.POST("/me/export", middleware.RequireSessionToken(), h.DataExportHandler().RequestExport)
+		users.GET("/me/exports/:id", middleware.RequireSessionToken(), h.DataExportHandler().GetExport)
+	}
+
+	// Download links are signed, so they work without signing in.
+	exports := router.Group("/exports", middleware.LogMiddleware(logger))
+	{
+		exports.GET("/:id/download", h.DataExportHandler().Download)
+	}
+
+	groups := router.Group("/groups", middleware.IsAuthenticated(authService), middleware.LogMiddleware(logger))
+	{
+		groups.POST("", h.GroupHandler().CreateGroup)
+		groups.GET("", h.GroupHandler().ListGroups)
+		groups.GET("/invitations", h.GroupHandler().ListInvitations)
+		groups.POST("/invitations/:id/accept", h.GroupHandler().AcceptInvitation)
+		groups.POST("/invitations/:id/decline", h.GroupHandler().DeclineInvitation)
+		groups.GET("/:id", h.GroupHandler().GetGroup)
+		groups.DELETE("/:id", h.GroupHandler().DeleteGroup)
+		groups.POST("/:id/invitations", h.GroupHandler().InviteMember)
+		groups.POST("/:id/leave", h.GroupHandler().LeaveGroup)
+		groups.PUT("/:id/members/:user_id", h.GroupHandler().UpdateMember)
+		groups.DELETE("/:id/members/:user_id", h.GroupHandler().RemoveMember)
 	}
 }
@@ -0,0 +1,71 @@
+package token
+
+import (
+	"auth-service/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrDownloadKeyNotConfigured = errors.New("EXPORT_SIGNING_KEY is not configured")
+
+// SignDownload returns the signature of a download link for resource that is
+// valid until expiresAt. Links are signed with EXPORT_SIGNING_KEY, so they can
+// be followed without signing in, for example from an email.
+func SignDownload(resource string, expiresAt time.Time) (string, error) {
+	mac, err := downloadMAC(resource, expiresAt.Unix())
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(mac), nil
+}
+
+// VerifyDownload checks a signature made by SignDownload for resource and
+// expires, a Unix timestamp, and that the link has not expired yet.
+func VerifyDownload(resource string, expires string, signature string) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() >= unix {
+		return false
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	want, err := downloadMAC(resource, unix)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, want)
+}
+
+// CheckDownloadKey reports whether EXPORT_SIGNING_KEY is set to a usable key.
+// The service refuses to start without one, since exports could not be
+// downloaded.
+func CheckDownloadKey() error {
+	_, err := downloadMAC("", 0)
+	return err
+}
+
+func downloadMAC(resource string, expires int64) ([]byte, error) {
+	encoded := config.Load().EXPORT_SIGNING_KEY
+	if encoded == "" {
+		return nil, ErrDownloadKeyNotConfigured
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode EXPORT_SIGNING_KEY: %w", err)
+	}
+	if len(key) < 32 {
+		return nil, fmt.Errorf("EXPORT_SIGNING_KEY must be at least 32 bytes, got %d", len(key))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(resource + "\n" + strconv.FormatInt(expires, 10)))
+	return mac.Sum(nil), nil
+}
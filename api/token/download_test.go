@@ -0,0 +1,38 @@
+package token
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignDownload(t *testing.T) {
+	t.Setenv("EXPORT_SIGNING_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+
+	expiresAt := time.Now().Add(time.Hour)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	signature, err := SignDownload("exports/1", expiresAt)
+	assert.NoError(t, err)
+	assert.True(t, VerifyDownload("exports/1", expires, signature))
+
+	assert.False(t, VerifyDownload("exports/2", expires, signature))
+	assert.False(t, VerifyDownload("exports/1", strconv.FormatInt(expiresAt.Unix()+1, 10), signature))
+	assert.False(t, VerifyDownload("exports/1", expires, signature+"x"))
+
+	past := time.Now().Add(-time.Minute)
+	signature, err = SignDownload("exports/1", past)
+	assert.NoError(t, err)
+	assert.False(t, VerifyDownload("exports/1", strconv.FormatInt(past.Unix(), 10), signature))
+
+	assert.NoError(t, CheckDownloadKey())
+
+	t.Setenv("EXPORT_SIGNING_KEY", "")
+	_, err = SignDownload("exports/1", expiresAt)
+	assert.ErrorIs(t, err, ErrDownloadKeyNotConfigured)
+	assert.ErrorIs(t, CheckDownloadKey(), ErrDownloadKeyNotConfigured)
+	t.Setenv("EXPORT_SIGNING_KEY", "c2hvcnQ=")
+	assert.Error(t, CheckDownloadKey())
+}
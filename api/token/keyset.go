@@ -0,0 +1,403 @@
+package token
+
+import (
+	"auth-service/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// manifestFile optionally lists the keys in JWT_KEYS_DIR together with their
+// rotation schedule. Without it every *.pem file is a key, its kid is the
+// file name and it becomes the signing key from its modification time on.
+const manifestFile = "keys.json"
+
+type manifestEntry struct {
+	Kid       string    `json:"kid"`
+	File      string    `json:"file"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+type keySet struct {
+	mu   sync.RWMutex
+	keys []*signingKey
+}
+
+var (
+	keys     = &keySet{}
+	loadOnce sync.Once
+)
+
+// ErrNoSigningKeys is returned when JWT_KEYS_DIR holds no keys and
+// JWT_ALLOW_EPHEMERAL_KEY is not set.
+var ErrNoSigningKeys = errors.New("no JWT signing keys found, add one to JWT_KEYS_DIR")
+
+// InitKeySet loads the signing keys from cfg.JWT_KEYS_DIR. When the directory
+// holds no keys it fails, unless cfg.JWT_ALLOW_EPHEMERAL_KEY is set: then an
+// ephemeral Ed25519 key is generated, which is only suitable for local
+// development because tokens do not survive a restart.
+func InitKeySet(cfg *config.Config, logger *slog.Logger) error {
+	var err error
+	loadOnce.Do(func() {
+		err = loadKeySet(cfg.JWT_KEYS_DIR, cfg.JWT_ALLOW_EPHEMERAL_KEY, logger)
+	})
+	return err
+}
+
+// WatchKeySet reloads the key directory every interval so that keys added
+// for a scheduled rotation are picked up without a restart.
+func WatchKeySet(cfg *config.Config, logger *slog.Logger) {
+	if cfg.JWT_KEYS_RELOAD_INTERVAL <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.JWT_KEYS_RELOAD_INTERVAL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := loadKeySet(cfg.JWT_KEYS_DIR, cfg.JWT_ALLOW_EPHEMERAL_KEY, logger); err != nil {
+			logger.Error("Reload JWT keys error", "error", err)
+		}
+	}
+}
+
+func ensureKeySet() {
+	loadOnce.Do(func() {
+		cfg := config.Load()
+		if err := loadKeySet(cfg.JWT_KEYS_DIR, cfg.JWT_ALLOW_EPHEMERAL_KEY, slog.Default()); err != nil {
+			slog.Default().Error("Load JWT keys error", "error", err)
+		}
+	})
+}
+
+func loadKeySet(dir string, allowEphemeral bool, logger *slog.Logger) error {
+	loaded, err := readKeys(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(loaded) == 0 {
+		keys.mu.RLock()
+		existing := len(keys.keys)
+		keys.mu.RUnlock()
+		if existing > 0 {
+			return nil
+		}
+		if !allowEphemeral {
+			return fmt.Errorf("%w: %q", ErrNoSigningKeys, dir)
+		}
+
+		logger.Warn("No JWT signing keys found, using an ephemeral key", "dir", dir)
+		key, err := ephemeralKey()
+		if err != nil {
+			return err
+		}
+		loaded = []*signingKey{key}
+	}
+
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].notBefore.Before(loaded[j].notBefore)
+	})
+
+	// A superseded key keeps verifying tokens until the last token it could
+	// have signed has expired.
+	for i := 0; i < len(loaded)-1; i++ {
+		if loaded[i].notAfter.IsZero() {
+			loaded[i].notAfter = loaded[i+1].notBefore.Add(maxTokenTTL())
+		}
+	}
+
+	keys.mu.Lock()
+	keys.keys = loaded
+	keys.mu.Unlock()
+
+	logger.Info("JWT keys loaded", "count", len(loaded))
+	return nil
+}
+
+func readKeys(dir string) ([]*signingKey, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err == nil {
+		var entries []manifestEntry
+		if err := json.Unmarshal(manifest, &entries); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", manifestFile, err)
+		}
+
+		var loaded []*signingKey
+		for _, e := range entries {
+			key, err := readKey(filepath.Join(dir, e.File), e.Kid)
+			if err != nil {
+				return nil, err
+			}
+			key.notBefore = e.NotBefore
+			key.notAfter = e.NotAfter
+			loaded = append(loaded, key)
+		}
+		return loaded, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []*signingKey
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := readKey(file, strings.TrimSuffix(filepath.Base(file), ".pem"))
+		if err != nil {
+			return nil, err
+		}
+		key.notBefore = info.ModTime()
+		loaded = append(loaded, key)
+	}
+	return loaded, nil
+}
+
+func readKey(file, kid string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, private: k}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: SigningMethodEdDSA, private: k}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", file, parsed)
+	}
+}
+
+func ephemeralKey() (*signingKey, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+
+	return &signingKey{
+		kid:       "ephemeral-" + base64.RawURLEncoding.EncodeToString(kid),
+		method:    SigningMethodEdDSA,
+		private:   private,
+		notBefore: time.Now(),
+	}, nil
+}
+
+func maxTokenTTL() time.Duration {
+	if RefreshTokenTTL > AccessTokenTTL {
+		return RefreshTokenTTL
+	}
+	return AccessTokenTTL
+}
+
+// currentKey returns the most recently activated key that is not retired.
+func (ks *keySet) currentKey() (*signingKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	for i := len(ks.keys) - 1; i >= 0; i-- {
+		key := ks.keys[i]
+		if key.notBefore.After(now) {
+			continue
+		}
+		if !key.notAfter.IsZero() && !key.notAfter.After(now) {
+			continue
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("no active JWT signing key")
+}
+
+func (ks *keySet) verificationKey(kid string) (*signingKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range ks.keys {
+		if key.kid != kid {
+			continue
+		}
+		if !key.notAfter.IsZero() && !key.notAfter.After(now) {
+			return nil, fmt.Errorf("JWT key %s is retired", kid)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown JWT key %s", kid)
+}
+
+func signClaims(claims jwt.Claims) (string, error) {
+	ensureKeySet()
+
+	key, err := keys.currentKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+
+	return token.SignedString(key.private)
+}
+
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	ensureKeySet()
+
+	kid, _ := token.Header["kid"].(string)
+	key, err := keys.verificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+
+	return key.private.Public(), nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS returns the public half of every key that is either usable for
+// verification or scheduled to become the signing key, so that verifiers can
+// cache a key before the first token signed with it shows up.
+func PublicJWKS() JWKSet {
+	ensureKeySet()
+
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	now := time.Now()
+	for _, key := range keys.keys {
+		if !key.notAfter.IsZero() && !key.notAfter.After(now) {
+			continue
+		}
+
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+type signingMethodEdDSA struct{}
+
+// SigningMethodEdDSA implements Ed25519 signatures (RFC 8037), which
+// dgrijalva/jwt-go does not ship with.
+var SigningMethodEdDSA = &signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(private, []byte(signingString))), nil
+}
+
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(public, []byte(signingString), sig) {
+		return errors.New("ed25519: verification error")
+	}
+	return nil
+}
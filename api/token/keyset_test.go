@@ -0,0 +1,118 @@
+package token
+
+import (
+	"auth-service/models"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKey(t *testing.T, dir, name string, key interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "old.pem", rsaKey)
+	writeKey(t, dir, "new.pem", edKey)
+
+	writeManifest := func(newFrom time.Time) {
+		manifest, _ := json.Marshal([]manifestEntry{
+			{Kid: "old", File: "old.pem", NotBefore: time.Now().Add(-time.Hour)},
+			{Kid: "new", File: "new.pem", NotBefore: newFrom},
+		})
+		if err := os.WriteFile(filepath.Join(dir, manifestFile), manifest, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeManifest(time.Now().Add(time.Hour))
+	assert.NoError(t, loadKeySet(dir, false, slog.Default()))
+	loadOnce.Do(func() {})
+
+	user := models.User{ID: "d70789c8-37e0-4de6-8195-d900abc0afb5", Email: "test_email@test.com", Role: "user"}
+
+	oldToken, err := GeneratedJWTTokenAccess(user, "")
+	assert.NoError(t, err)
+	assert.Len(t, PublicJWKS().Keys, 2)
+
+	writeManifest(time.Now().Add(-time.Minute))
+	assert.NoError(t, loadKeySet(dir, false, slog.Default()))
+
+	newToken, err := GeneratedJWTTokenAccess(user, "")
+	assert.NoError(t, err)
+
+	claims, err := ExtractAndValidateToken(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Email, claims.Email)
+
+	claims, err = ExtractAndValidateToken(newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Email, claims.Email)
+
+	_, err = ExtractClaims(newToken)
+	assert.Error(t, err)
+}
+
+func TestLoadKeySetRequiresKeys(t *testing.T) {
+	keys.mu.Lock()
+	saved := keys.keys
+	keys.keys = nil
+	keys.mu.Unlock()
+	defer func() {
+		keys.mu.Lock()
+		keys.keys = saved
+		keys.mu.Unlock()
+	}()
+
+	dir := t.TempDir()
+	assert.ErrorIs(t, loadKeySet(dir, false, slog.Default()), ErrNoSigningKeys)
+	assert.ErrorIs(t, loadKeySet("", false, slog.Default()), ErrNoSigningKeys)
+
+	assert.NoError(t, loadKeySet(dir, true, slog.Default()))
+	key, err := keys.currentKey()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key.kid, "ephemeral-"))
+}
+
+// useTestKeys signs the tokens of a test with a fresh Ed25519 key.
+func useTestKeys(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "test.pem", edKey)
+
+	loadOnce.Do(func() {})
+	if err := loadKeySet(dir, false, slog.Default()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,206 @@
+package token
+
+import (
+	"auth-service/config"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	MFAPendingTokenTTL = 5 * time.Minute
+
+	TypeMFAPending = "mfa_pending"
+
+	recoveryCodeCount = 10
+)
+
+var ErrMFAKeyNotConfigured = errors.New("MFA_ENCRYPTION_KEY is not configured")
+
+// TOTPKey is a freshly generated TOTP secret together with the otpauth:// URI
+// and a PNG QR code of that URI for authenticator apps.
+type TOTPKey struct {
+	Secret string
+	URL    string
+	QRCode []byte
+}
+
+func GenerateTOTPKey(accountName string) (*TOTPKey, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      config.Load().MFA_ISSUER,
+		AccountName: accountName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return &TOTPKey{Secret: key.Secret(), URL: key.URL(), QRCode: buf.Bytes()}, nil
+}
+
+// ValidateTOTP checks code against secret allowing one 30 second step of
+// clock drift in either direction. It returns the time step the code matched
+// so callers can reject a code that has already been used.
+func ValidateTOTP(secret, code string) (int64, bool) {
+	now := time.Now()
+	for _, skew := range []time.Duration{0, -30 * time.Second, 30 * time.Second} {
+		at := now.Add(skew)
+		ok, err := totp.ValidateCustom(code, secret, at, totp.ValidateOpts{
+			Period:    30,
+			Skew:      0,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err == nil && ok {
+			return at.Unix() / 30, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns one-time codes in the form xxxxx-xxxxx.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode makes user input comparable with a stored code hash.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) == 10 {
+		code = code[:5] + "-" + code[5:]
+	}
+	return code
+}
+
+// EncryptSecret seals a TOTP secret with AES-256-GCM under MFA_ENCRYPTION_KEY.
+func EncryptSecret(secret string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func DecryptSecret(encrypted string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted secret is too short")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// CheckMFAKey reports whether MFA_ENCRYPTION_KEY is set to a usable key. The
+// service refuses to start without one rather than store TOTP secrets that
+// cannot be read back.
+func CheckMFAKey() error {
+	_, err := secretCipher()
+	return err
+}
+
+func secretCipher() (cipher.AEAD, error) {
+	encoded := config.Load().MFA_ENCRYPTION_KEY
+	if encoded == "" {
+		return nil, ErrMFAKeyNotConfigured
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode MFA_ENCRYPTION_KEY: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("MFA_ENCRYPTION_KEY must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GeneratedMFAPendingToken is issued after a correct password when the user
+// has a second factor. It only grants access to /auth/mfa/verify.
+func GeneratedMFAPendingToken(userID string) (string, error) {
+	return signClaims(Claims{
+		ID:   userID,
+		Type: TypeMFAPending,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Issuer:    issuer(),
+			Subject:   userID,
+			ExpiresAt: time.Now().Add(MFAPendingTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	})
+}
+
+func ExtractMFAPendingClaims(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	if claims.Type != TypeMFAPending {
+		return nil, fmt.Errorf("not an mfa_pending token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,73 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	t.Setenv("MFA_ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+
+	encrypted, err := EncryptSecret("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	assert.NotContains(t, encrypted, "JBSWY3DPEHPK3PXP")
+
+	secret, err := DecryptSecret(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+
+	t.Setenv("MFA_ENCRYPTION_KEY", "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=")
+	_, err = DecryptSecret(encrypted)
+	assert.Error(t, err)
+	assert.NoError(t, CheckMFAKey())
+
+	t.Setenv("MFA_ENCRYPTION_KEY", "")
+	assert.ErrorIs(t, CheckMFAKey(), ErrMFAKeyNotConfigured)
+	t.Setenv("MFA_ENCRYPTION_KEY", "c2hvcnQ=")
+	assert.Error(t, CheckMFAKey())
+}
+
+func TestValidateTOTP(t *testing.T) {
+	key, err := GenerateTOTPKey("test_email@test.com")
+	assert.NoError(t, err)
+	assert.Contains(t, key.URL, "otpauth://totp/")
+	assert.NotEmpty(t, key.QRCode)
+
+	code, err := totp.GenerateCode(key.Secret, time.Now())
+	assert.NoError(t, err)
+
+	step, ok := ValidateTOTP(key.Secret, code)
+	assert.True(t, ok)
+	assert.NotZero(t, step)
+
+	_, ok = ValidateTOTP(key.Secret, "000000x")
+	assert.False(t, ok)
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, code, NormalizeRecoveryCode(" "+code[:5]+code[6:]+" "))
+	}
+}
+
+func TestMFAPendingToken(t *testing.T) {
+	useTestKeys(t)
+
+	pending, err := GeneratedMFAPendingToken("d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.NoError(t, err)
+
+	claims, err := ExtractMFAPendingClaims(pending)
+	assert.NoError(t, err)
+	assert.Equal(t, "d70789c8-37e0-4de6-8195-d900abc0afb5", claims.ID)
+
+	_, err = ExtractAndValidateToken(pending)
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package token
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+const (
+	ClientTokenTTL = time.Hour
+	IDTokenTTL     = time.Hour
+
+	TypeClient = "client"
+)
+
+// IDTokenUser holds the profile data that goes into an ID token.
+type IDTokenUser struct {
+	ID        string
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+type IDTokenClaims struct {
+	Email      string `json:"email"`
+	GivenName  string `json:"given_name"`
+	FamilyName string `json:"family_name"`
+	Nonce      string `json:"nonce,omitempty"`
+	jwt.StandardClaims
+}
+
+// GeneratedIDToken issues an OpenID Connect ID token for audience (the
+// OAuth client ID).
+func GeneratedIDToken(user IDTokenUser, audience string, nonce string) (string, error) {
+	return signClaims(IDTokenClaims{
+		Email:      user.Email,
+		GivenName:  user.FirstName,
+		FamilyName: user.LastName,
+		Nonce:      nonce,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Issuer:    issuer(),
+			Subject:   user.ID,
+			Audience:  audience,
+			ExpiresAt: time.Now().Add(IDTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	})
+}
+
+// GeneratedClientToken issues an access token for an OAuth client acting on
+// its own behalf (client_credentials grant).
+func GeneratedClientToken(clientID string, scope string) (string, error) {
+	return signClaims(Claims{
+		Type:     TypeClient,
+		ClientID: clientID,
+		Scope:    scope,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Issuer:    issuer(),
+			Subject:   clientID,
+			ExpiresAt: time.Now().Add(ClientTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	})
+}
+
+func ExtractClientClaims(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	if claims.Type != TypeClient {
+		return nil, fmt.Errorf("not a client token")
+	}
+
+	return claims, nil
+}
+
+// SigningAlgorithms lists the algorithms of the keys published in the JWKS.
+func SigningAlgorithms() []string {
+	var algs []string
+	seen := map[string]bool{}
+	for _, key := range PublicJWKS().Keys {
+		if !seen[key.Alg] {
+			seen[key.Alg] = true
+			algs = append(algs, key.Alg)
+		}
+	}
+	return algs
+}
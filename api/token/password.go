@@ -0,0 +1,173 @@
+package token
+
+import (
+	"auth-service/config"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+// GenerateResetCode returns a uniformly random 6 digit password reset code.
+func GenerateResetCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// HashPassword hashes the password with the algorithm and cost configured
+// through PASSWORD_HASH_ALGORITHM, BCRYPT_COST and ARGON2_*.
+func HashPassword(password string) (string, error) {
+	cfg := config.Load()
+
+	switch cfg.PASSWORD_HASH_ALGORITHM {
+	case AlgorithmArgon2id:
+		return hashArgon2id(password, argon2ParamsFromConfig(cfg))
+	case AlgorithmBcrypt, "":
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), cfg.BCRYPT_COST)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	default:
+		return "", fmt.Errorf("unsupported password hash algorithm: %s", cfg.PASSWORD_HASH_ALGORITHM)
+	}
+}
+
+// VerifyPassword reports whether password matches hashedPassword. Both bcrypt
+// and argon2id hashes are accepted regardless of the configured algorithm.
+// Rows created before passwords were hashed still hold the plain password, so
+// anything that is not a recognised hash is compared in constant time and
+// reported by NeedsRehash so it can be upgraded on the next login.
+func VerifyPassword(password, hashedPassword string) bool {
+	switch {
+	case isBcryptHash(hashedPassword):
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		ok, err := verifyArgon2id(password, hashedPassword)
+		return err == nil && ok
+	default:
+		return hashedPassword != "" && subtle.ConstantTimeCompare([]byte(password), []byte(hashedPassword)) == 1
+	}
+}
+
+// NeedsRehash reports whether hashedPassword was produced with a different
+// algorithm or weaker parameters than the ones currently configured.
+func NeedsRehash(hashedPassword string) bool {
+	cfg := config.Load()
+
+	switch cfg.PASSWORD_HASH_ALGORITHM {
+	case AlgorithmArgon2id:
+		params, _, _, err := decodeArgon2id(hashedPassword)
+		if err != nil {
+			return true
+		}
+		want := argon2ParamsFromConfig(cfg)
+		return params.memory != want.memory ||
+			params.iterations != want.iterations ||
+			params.parallelism != want.parallelism
+	default:
+		if !isBcryptHash(hashedPassword) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hashedPassword))
+		if err != nil {
+			return true
+		}
+		return cost != cfg.BCRYPT_COST
+	}
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func argon2ParamsFromConfig(cfg *config.Config) argon2Params {
+	return argon2Params{
+		memory:      uint32(cfg.ARGON2_MEMORY),
+		iterations:  uint32(cfg.ARGON2_ITERATIONS),
+		parallelism: uint8(cfg.ARGON2_PARALLELISM),
+		saltLength:  16,
+		keyLength:   32,
+	}
+}
+
+func hashArgon2id(password string, p argon2Params) (string, error) {
+	salt := make([]byte, p.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func verifyArgon2id(password, hash string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, err
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("incompatible argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	p.saltLength = uint32(len(salt))
+	p.keyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,57 @@
+package token
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashPasswordBcrypt(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", AlgorithmBcrypt)
+	t.Setenv("BCRYPT_COST", "4")
+
+	hash, err := HashPassword("test_password")
+	assert.NoError(t, err)
+
+	assert.True(t, VerifyPassword("test_password", hash))
+	assert.False(t, VerifyPassword("wrong_password", hash))
+	assert.False(t, NeedsRehash(hash))
+
+	t.Setenv("BCRYPT_COST", "5")
+	assert.True(t, NeedsRehash(hash))
+}
+
+func TestHashPasswordArgon2id(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", AlgorithmArgon2id)
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_ITERATIONS", "1")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+
+	hash, err := HashPassword("test_password")
+	assert.NoError(t, err)
+
+	assert.True(t, VerifyPassword("test_password", hash))
+	assert.False(t, VerifyPassword("wrong_password", hash))
+	assert.False(t, NeedsRehash(hash))
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", AlgorithmBcrypt)
+	assert.True(t, NeedsRehash(hash))
+}
+
+func TestVerifyLegacyPlainPassword(t *testing.T) {
+	assert.True(t, VerifyPassword("test_password", "test_password"))
+	assert.False(t, VerifyPassword("", ""))
+	assert.True(t, NeedsRehash("test_password"))
+}
+
+func TestGenerateResetCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := GenerateResetCode()
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^[0-9]{6}$`), code)
+		seen[code] = true
+	}
+	assert.Greater(t, len(seen), 90)
+}
@@ -0,0 +1,35 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	TypePersonal = "personal"
+
+	// PersonalTokenPrefix starts every personal access token, which tells them
+	// apart from JWTs without parsing and makes leaked ones easy to scan for.
+	PersonalTokenPrefix = "pat_"
+
+	personalTokenBytes = 32
+	// personalTokenDisplayLength is how much of a token is kept in clear to
+	// identify it in listings.
+	personalTokenDisplayLength = len(PersonalTokenPrefix) + 8
+)
+
+// GeneratePersonalToken returns a new random personal access token together
+// with its display prefix. Only the token's HashToken is meant to be stored.
+func GeneratePersonalToken() (string, string, error) {
+	b := make([]byte, personalTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, token[:personalTokenDisplayLength], nil
+}
+
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
@@ -0,0 +1,21 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratePersonalToken(t *testing.T) {
+	token, prefix, err := GeneratePersonalToken()
+	assert.NoError(t, err)
+	assert.True(t, IsPersonalToken(token))
+	assert.Equal(t, token[:len(prefix)], prefix)
+	assert.Len(t, token, len(PersonalTokenPrefix)+43)
+
+	other, _, err := GeneratePersonalToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	assert.False(t, IsPersonalToken("eyJhbGciOiJSUzI1NiJ9.e30.sig"))
+}
@@ -3,55 +3,97 @@ package token
 import (
 	"auth-service/config"
 	"auth-service/models"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
+const (
+	AccessTokenTTL  = 7 * 24 * time.Hour
+	RefreshTokenTTL = 7 * 24 * time.Hour
+
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
+func issuer() string {
+	return config.Load().ISSUER_URL
+}
+
 type Claims struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	// Permissions granted through the user's roles when the token was issued.
+	Permissions []string `json:"perms,omitempty"`
+	// Groups the user belonged to when the token was issued, with the role
+	// held in each.
+	Groups []models.GroupMembership `json:"groups,omitempty"`
 	jwt.StandardClaims
 }
 
-func GeneratedJWTTokenAccess(user models.User) (string, error) {
-	cfg := config.Load()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		ID:    user.ID,
-		Email: user.Email,
-		Role:  user.Role,
+func (c *Claims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// GroupRole returns the user's role in groupID, or "" when the token does not
+// list the group.
+func (c *Claims) GroupRole(groupID string) string {
+	for _, group := range c.Groups {
+		if group.GroupID == groupID {
+			return group.Role
+		}
+	}
+	return ""
+}
+
+func GeneratedJWTTokenAccess(user models.User, sessionID string) (string, error) {
+	return signClaims(Claims{
+		ID:          user.ID,
+		Email:       user.Email,
+		Role:        user.Role,
+		SessionID:   sessionID,
+		Type:        TypeAccess,
+		Permissions: user.Permissions,
+		Groups:      user.Groups,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(7 * 24 * time.Hour).Unix(),
+			Id:        uuid.NewString(),
+			Issuer:    issuer(),
+			Subject:   user.ID,
+			ExpiresAt: time.Now().Add(AccessTokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 	})
-
-	return token.SignedString([]byte(cfg.Jwt_SECRET_ACCESS))
 }
 
-func GeneratedJwtTokenRefresh(user models.User) (string, error) {
-	cfg := config.Load()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		ID:    user.ID,
-		Email: user.Email,
-		Role:  user.Role,
+func GeneratedJwtTokenRefresh(user models.User, sessionID string) (string, error) {
+	return signClaims(Claims{
+		ID:        user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
+		Type:      TypeRefresh,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(24 * 7 * time.Hour).Unix(),
+			Id:        uuid.NewString(),
+			Issuer:    issuer(),
+			Subject:   user.ID,
+			ExpiresAt: time.Now().Add(RefreshTokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 	})
-
-	return token.SignedString([]byte(cfg.Jwt_SECRET_REFRESH))
 }
 
 func ExtractAndValidateToken(tokenString string) (*Claims, error) {
-	cfg := config.Load()
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.Jwt_SECRET_ACCESS), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -65,15 +107,15 @@ func ExtractAndValidateToken(tokenString string) (*Claims, error) {
 	if !ok {
 		return nil, fmt.Errorf("invalid token claims")
 	}
+	if claims.Type != TypeAccess {
+		return nil, fmt.Errorf("not an access token")
+	}
 
 	return claims, nil
 }
 
 func ExtractClaims(tokenString string) (*Claims, error) {
-	cfg := config.Load()
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.Jwt_SECRET_REFRESH), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -87,18 +129,16 @@ func ExtractClaims(tokenString string) (*Claims, error) {
 	if !ok {
 		return nil, fmt.Errorf("invalid token claims")
 	}
+	if claims.Type != TypeRefresh {
+		return nil, fmt.Errorf("not a refresh token")
+	}
 
 	return claims, nil
 }
 
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hash), nil
-}
-
-func VerifyPassword(password, hashedPassword string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
+// HashToken returns the hex encoded SHA-256 of a token. Refresh tokens are
+// high-entropy, so a fast hash is enough to avoid storing them in clear.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -2,13 +2,17 @@ package main
 
 import (
 	"auth-service/api"
+	"auth-service/api/token"
 	"auth-service/cmd/server"
 	"auth-service/config"
+	"auth-service/pkg/events"
 	"auth-service/pkg/logs"
+	"auth-service/pkg/mail"
 	"auth-service/service"
 	"auth-service/storage"
 	"auth-service/storage/postgres"
 	"auth-service/storage/redis"
+	"context"
 	"log"
 )
 
@@ -18,6 +22,21 @@ func main() {
 	logger.Info("Application started")
 	cfg := config.Load()
 
+	if err := token.InitKeySet(cfg, logger); err != nil {
+		logger.Error("JWT keys error", "error", err)
+		log.Fatal(err)
+	}
+	go token.WatchKeySet(cfg, logger)
+
+	if err := token.CheckMFAKey(); err != nil {
+		logger.Error("MFA key error", "error", err)
+		log.Fatal(err)
+	}
+	if err := token.CheckDownloadKey(); err != nil {
+		logger.Error("Export signing key error", "error", err)
+		log.Fatal(err)
+	}
+
 	db, err := postgres.ConnectDB(cfg)
 	if err != nil {
 		logger.Error("Database connection error", "error", err)
@@ -34,6 +53,28 @@ func main() {
 
 	authService := service.NewAuthService(storage, logger)
 
+	mailer, err := mail.New(cfg)
+	if err != nil {
+		logger.Error("Mailer error", "error", err)
+		log.Fatal(err)
+	}
+	go service.NewEmailWorker(storage, mailer, logger).Run(context.Background())
+	go service.NewAccountDeletionWorker(storage, logger).Run(context.Background())
+
+	publisher, err := events.New(cfg, rdb)
+	if err != nil {
+		logger.Error("Event publisher error", "error", err)
+		log.Fatal(err)
+	}
+	go service.NewEventRelay(storage, publisher, logger).Run(context.Background())
+
+	sources, err := service.DialExportSources(cfg)
+	if err != nil {
+		logger.Error("Export sources error", "error", err)
+		log.Fatal(err)
+	}
+	go service.NewDataExportWorker(storage, sources, logger).Run(context.Background())
+
 	go func() {
 		log.Println("Stargin GRPC server")
 		logger.Info("Starting GRPC server")
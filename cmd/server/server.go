@@ -1,10 +1,14 @@
 package server
 
 import (
+	"auth-service/api/token"
 	"auth-service/config"
 	"auth-service/generated/user"
+	"auth-service/pkg/apperr"
+	"auth-service/pkg/grpcauth"
 	"auth-service/service"
 	"auth-service/storage"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
@@ -13,9 +17,18 @@ import (
 	"google.golang.org/grpc"
 )
 
+var ErrNoTLSCertificate = errors.New("no gRPC TLS certificate configured, set GRPC_TLS_CERT_FILE")
+
 func StartServer(logger *slog.Logger, storage storage.IStorage) {
 	cfg := config.Load()
 
+	userService := service.NewUserService(storage, logger)
+	opts, err := serverOptions(cfg, userService.VerifyUserToken, logger)
+	if err != nil {
+		logger.Error("gRPC server options error", "error", err)
+		log.Fatal(err)
+	}
+
 	log.Println("Server started")
 	listener, err := net.Listen("tcp", fmt.Sprintf("auth_app:%d", cfg.GRPC_PORT))
 	if err != nil {
@@ -25,13 +38,61 @@ func StartServer(logger *slog.Logger, storage storage.IStorage) {
 
 	log.Printf("Listening on %s\n", listener.Addr())
 
-	s := grpc.NewServer()
-	userService := service.NewUserService(storage, logger)
+	s := grpc.NewServer(opts...)
 	user.RegisterAuthServiceServer(s, userService)
 
-	
 	if err := s.Serve(listener); err != nil {
 		logger.Error("Serve error", "error", err)
 		log.Fatal(err)
 	}
 }
+
+// serverOptions sets up TLS, which is required unless GRPC_ALLOW_PLAINTEXT is
+// set, and the interceptors that turn errors into statuses and authenticate
+// the calling service and, for RPCs acting on behalf of a user, the end user.
+func serverOptions(cfg *config.Config, verifyUser grpcauth.UserTokenVerifier, logger *slog.Logger) ([]grpc.ServerOption, error) {
+	var (
+		opts         []grpc.ServerOption
+		interceptors = []grpc.UnaryServerInterceptor{apperr.UnaryServerInterceptor(logger)}
+	)
+
+	if cfg.GRPC_TLS_CERT_FILE != "" {
+		creds, err := grpcauth.ServerCredentials(cfg.GRPC_TLS_CERT_FILE, cfg.GRPC_TLS_KEY_FILE, cfg.GRPC_TLS_CLIENT_CA_FILE)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	} else if cfg.GRPC_ALLOW_PLAINTEXT {
+		logger.Warn("gRPC server is running without TLS")
+	} else {
+		return nil, ErrNoTLSCertificate
+	}
+
+	if cfg.GRPC_SERVICE_AUTH {
+		acl, err := grpcauth.ParseACL(cfg.GRPC_ALLOWED_CALLERS)
+		if err != nil {
+			return nil, err
+		}
+		interceptors = append(interceptors, grpcauth.UnaryServerInterceptor(acl, verifyServiceToken, logger))
+	} else {
+		logger.Warn("gRPC service authentication is disabled")
+	}
+
+	if cfg.GRPC_USER_AUTH {
+		interceptors = append(interceptors, grpcauth.UserUnaryServerInterceptor(service.UserRPCRules, verifyUser, logger))
+	} else {
+		logger.Warn("gRPC user authorization is disabled")
+	}
+
+	return append(opts, grpc.ChainUnaryInterceptor(interceptors...)), nil
+}
+
+// verifyServiceToken accepts access tokens issued by the client_credentials
+// grant and identifies the caller by their OAuth client ID.
+func verifyServiceToken(raw string) (string, error) {
+	claims, err := token.ExtractClientClaims(raw)
+	if err != nil {
+		return "", err
+	}
+	return claims.ClientID, nil
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"auth-service/config"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerOptionsRequireTLS(t *testing.T) {
+	_, err := serverOptions(&config.Config{}, nil, slog.Default())
+	assert.ErrorIs(t, err, ErrNoTLSCertificate)
+
+	opts, err := serverOptions(&config.Config{GRPC_ALLOW_PLAINTEXT: true}, nil, slog.Default())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, opts)
+}
@@ -3,25 +3,103 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
 )
 
 type Config struct {
-	HTTP_PORT          int    `yaml:"http_port"`
-	GRPC_PORT          int    `yaml:"grpc_port"`
-	DB_HOST            string `yaml:"db_host"`
-	DB_PORT            int    `yaml:"db_port"`
-	DB_USER            string `yaml:"db_user"`
-	DB_PASSWORD        string `yaml:"db_password"`
-	DB_NAME            string `yaml:"db_name"`
-	Redis_HOST         string `yaml:"redis_host"`
-	Redis_PORT         int    `yaml:"redis_port"`
-	Redis_PASSWORD     string `yaml:"redis_password"`
-	Redis_DB           int    `yaml:"redis_db"`
-	Jwt_SECRET_ACCESS  string `yaml:"jwt_secret"`
-	Jwt_SECRET_REFRESH string `yaml:"jwt_secret_refresh"`
+	HTTP_PORT      int    `yaml:"http_port"`
+	GRPC_PORT      int    `yaml:"grpc_port"`
+	DB_HOST        string `yaml:"db_host"`
+	DB_PORT        int    `yaml:"db_port"`
+	DB_USER        string `yaml:"db_user"`
+	DB_PASSWORD    string `yaml:"db_password"`
+	DB_NAME        string `yaml:"db_name"`
+	Redis_HOST     string `yaml:"redis_host"`
+	Redis_PORT     int    `yaml:"redis_port"`
+	Redis_PASSWORD string `yaml:"redis_password"`
+	Redis_DB       int    `yaml:"redis_db"`
+
+	ISSUER_URL               string        `yaml:"issuer_url"`
+	JWT_KEYS_DIR             string        `yaml:"jwt_keys_dir"`
+	JWT_KEYS_RELOAD_INTERVAL time.Duration `yaml:"jwt_keys_reload_interval"`
+	JWT_ALLOW_EPHEMERAL_KEY  bool          `yaml:"jwt_allow_ephemeral_key"`
+
+	PASSWORD_HASH_ALGORITHM string `yaml:"password_hash_algorithm"`
+	BCRYPT_COST             int    `yaml:"bcrypt_cost"`
+	ARGON2_MEMORY           int    `yaml:"argon2_memory"`
+	ARGON2_ITERATIONS       int    `yaml:"argon2_iterations"`
+	ARGON2_PARALLELISM      int    `yaml:"argon2_parallelism"`
+
+	MFA_ISSUER         string `yaml:"mfa_issuer"`
+	MFA_ENCRYPTION_KEY string `yaml:"mfa_encryption_key"`
+
+	WEBAUTHN_RP_ID      string `yaml:"webauthn_rp_id"`
+	WEBAUTHN_RP_NAME    string `yaml:"webauthn_rp_name"`
+	WEBAUTHN_RP_ORIGINS string `yaml:"webauthn_rp_origins"`
+
+	EMAIL_VERIFICATION_GRACE_PERIOD time.Duration `yaml:"email_verification_grace_period"`
+
+	THROTTLE_MAX_FAILURES    int           `yaml:"throttle_max_failures"`
+	THROTTLE_IP_MAX_FAILURES int           `yaml:"throttle_ip_max_failures"`
+	THROTTLE_FAILURE_WINDOW  time.Duration `yaml:"throttle_failure_window"`
+	THROTTLE_LOCKOUT         time.Duration `yaml:"throttle_lockout"`
+	THROTTLE_BACKOFF_AFTER   int           `yaml:"throttle_backoff_after"`
+	THROTTLE_BACKOFF_BASE    time.Duration `yaml:"throttle_backoff_base"`
+	THROTTLE_BACKOFF_MAX     time.Duration `yaml:"throttle_backoff_max"`
+	RESET_CODE_MAX_ATTEMPTS  int           `yaml:"reset_code_max_attempts"`
+
+	MAIL_DRIVER        string        `yaml:"mail_driver"`
+	MAIL_FROM          string        `yaml:"mail_from"`
+	MAIL_FILE_PATH     string        `yaml:"mail_file_path"`
+	MAIL_SEND_TIMEOUT  time.Duration `yaml:"mail_send_timeout"`
+	MAIL_POLL_INTERVAL time.Duration `yaml:"mail_poll_interval"`
+	MAIL_BATCH_SIZE    int           `yaml:"mail_batch_size"`
+	MAIL_MAX_ATTEMPTS  int           `yaml:"mail_max_attempts"`
+	MAIL_RETRY_BASE    time.Duration `yaml:"mail_retry_base"`
+	MAIL_RETRY_MAX     time.Duration `yaml:"mail_retry_max"`
+	SMTP_HOST          string        `yaml:"smtp_host"`
+	SMTP_PORT          int           `yaml:"smtp_port"`
+	SMTP_USERNAME      string        `yaml:"smtp_username"`
+	SMTP_PASSWORD      string        `yaml:"smtp_password"`
+	SMTP_TLS           string        `yaml:"smtp_tls"`
+
+	PAT_DEFAULT_TTL time.Duration `yaml:"pat_default_ttl"`
+	PAT_MAX_TTL     time.Duration `yaml:"pat_max_ttl"`
+
+	GRPC_TLS_CERT_FILE      string `yaml:"grpc_tls_cert_file"`
+	GRPC_TLS_KEY_FILE       string `yaml:"grpc_tls_key_file"`
+	GRPC_TLS_CLIENT_CA_FILE string `yaml:"grpc_tls_client_ca_file"`
+	GRPC_ALLOW_PLAINTEXT    bool   `yaml:"grpc_allow_plaintext"`
+	GRPC_SERVICE_AUTH       bool   `yaml:"grpc_service_auth"`
+	GRPC_ALLOWED_CALLERS    string `yaml:"grpc_allowed_callers"`
+	GRPC_USER_AUTH          bool   `yaml:"grpc_user_auth"`
+
+	ACCOUNT_DELETION_GRACE_PERIOD  time.Duration `yaml:"account_deletion_grace_period"`
+	ACCOUNT_DELETION_POLL_INTERVAL time.Duration `yaml:"account_deletion_poll_interval"`
+	ACCOUNT_DELETION_BATCH_SIZE    int           `yaml:"account_deletion_batch_size"`
+
+	EVENTS_DRIVER          string        `yaml:"events_driver"`
+	EVENTS_STREAM          string        `yaml:"events_stream"`
+	EVENTS_POLL_INTERVAL   time.Duration `yaml:"events_poll_interval"`
+	EVENTS_BATCH_SIZE      int           `yaml:"events_batch_size"`
+	EVENTS_PUBLISH_TIMEOUT time.Duration `yaml:"events_publish_timeout"`
+	EVENTS_RETRY_BASE      time.Duration `yaml:"events_retry_base"`
+	EVENTS_RETRY_MAX       time.Duration `yaml:"events_retry_max"`
+	EVENTS_RETENTION       time.Duration `yaml:"events_retention"`
+
+	EXPORT_DIR            string        `yaml:"export_dir"`
+	EXPORT_SIGNING_KEY    string        `yaml:"export_signing_key"`
+	EXPORT_LINK_TTL       time.Duration `yaml:"export_link_ttl"`
+	EXPORT_POLL_INTERVAL  time.Duration `yaml:"export_poll_interval"`
+	EXPORT_MAX_ATTEMPTS   int           `yaml:"export_max_attempts"`
+	EXPORT_SOURCE_TIMEOUT time.Duration `yaml:"export_source_timeout"`
+	BUDGETING_GRPC_ADDR   string        `yaml:"budgeting_grpc_addr"`
+	FINANCE_GRPC_ADDR     string        `yaml:"finance_grpc_addr"`
+	GOALS_GRPC_ADDR       string        `yaml:"goals_grpc_addr"`
+	GRPC_CLIENT_CA_FILE   string        `yaml:"grpc_client_ca_file"`
 }
 
 func Load() *Config {
@@ -45,8 +123,85 @@ func Load() *Config {
 	config.Redis_PASSWORD = cast.ToString(coalesce("REDIS_PASSWORD", ""))
 	config.Redis_DB = cast.ToInt(coalesce("REDIS_DB", 0))
 
-	config.Jwt_SECRET_ACCESS = cast.ToString(coalesce("JWT_SECRET_ACCESS", "your_secret_access"))
-	config.Jwt_SECRET_REFRESH = cast.ToString(coalesce("JWT_SECRET_REFRESH", "your_secret_refresh"))
+	config.ISSUER_URL = cast.ToString(coalesce("ISSUER_URL", "http://localhost:8081"))
+	config.JWT_KEYS_DIR = cast.ToString(coalesce("JWT_KEYS_DIR", "keys"))
+	config.JWT_KEYS_RELOAD_INTERVAL = cast.ToDuration(coalesce("JWT_KEYS_RELOAD_INTERVAL", "5m"))
+	config.JWT_ALLOW_EPHEMERAL_KEY = cast.ToBool(coalesce("JWT_ALLOW_EPHEMERAL_KEY", false))
+
+	config.PASSWORD_HASH_ALGORITHM = cast.ToString(coalesce("PASSWORD_HASH_ALGORITHM", "bcrypt"))
+	config.BCRYPT_COST = cast.ToInt(coalesce("BCRYPT_COST", 12))
+	config.ARGON2_MEMORY = cast.ToInt(coalesce("ARGON2_MEMORY", 64*1024))
+	config.ARGON2_ITERATIONS = cast.ToInt(coalesce("ARGON2_ITERATIONS", 3))
+	config.ARGON2_PARALLELISM = cast.ToInt(coalesce("ARGON2_PARALLELISM", 2))
+
+	config.MFA_ISSUER = cast.ToString(coalesce("MFA_ISSUER", "Personal Finance Tracker"))
+	config.MFA_ENCRYPTION_KEY = cast.ToString(coalesce("MFA_ENCRYPTION_KEY", ""))
+
+	config.WEBAUTHN_RP_ID = cast.ToString(coalesce("WEBAUTHN_RP_ID", "localhost"))
+	config.WEBAUTHN_RP_NAME = cast.ToString(coalesce("WEBAUTHN_RP_NAME", "Personal Finance Tracker"))
+	config.WEBAUTHN_RP_ORIGINS = cast.ToString(coalesce("WEBAUTHN_RP_ORIGINS", "http://localhost:8081"))
+
+	config.EMAIL_VERIFICATION_GRACE_PERIOD = cast.ToDuration(coalesce("EMAIL_VERIFICATION_GRACE_PERIOD", "0s"))
+
+	config.THROTTLE_MAX_FAILURES = cast.ToInt(coalesce("THROTTLE_MAX_FAILURES", 10))
+	config.THROTTLE_IP_MAX_FAILURES = cast.ToInt(coalesce("THROTTLE_IP_MAX_FAILURES", 100))
+	config.THROTTLE_FAILURE_WINDOW = cast.ToDuration(coalesce("THROTTLE_FAILURE_WINDOW", "15m"))
+	config.THROTTLE_LOCKOUT = cast.ToDuration(coalesce("THROTTLE_LOCKOUT", "15m"))
+	config.THROTTLE_BACKOFF_AFTER = cast.ToInt(coalesce("THROTTLE_BACKOFF_AFTER", 3))
+	config.THROTTLE_BACKOFF_BASE = cast.ToDuration(coalesce("THROTTLE_BACKOFF_BASE", "1s"))
+	config.THROTTLE_BACKOFF_MAX = cast.ToDuration(coalesce("THROTTLE_BACKOFF_MAX", "5m"))
+	config.RESET_CODE_MAX_ATTEMPTS = cast.ToInt(coalesce("RESET_CODE_MAX_ATTEMPTS", 5))
+
+	config.MAIL_DRIVER = cast.ToString(coalesce("MAIL_DRIVER", "stdout"))
+	config.MAIL_FROM = cast.ToString(coalesce("MAIL_FROM", "Personal Finance Tracker <no-reply@localhost>"))
+	config.MAIL_FILE_PATH = cast.ToString(coalesce("MAIL_FILE_PATH", "mail.log"))
+	config.MAIL_SEND_TIMEOUT = cast.ToDuration(coalesce("MAIL_SEND_TIMEOUT", "30s"))
+	config.MAIL_POLL_INTERVAL = cast.ToDuration(coalesce("MAIL_POLL_INTERVAL", "5s"))
+	config.MAIL_BATCH_SIZE = cast.ToInt(coalesce("MAIL_BATCH_SIZE", 20))
+	config.MAIL_MAX_ATTEMPTS = cast.ToInt(coalesce("MAIL_MAX_ATTEMPTS", 8))
+	config.MAIL_RETRY_BASE = cast.ToDuration(coalesce("MAIL_RETRY_BASE", "30s"))
+	config.MAIL_RETRY_MAX = cast.ToDuration(coalesce("MAIL_RETRY_MAX", "1h"))
+	config.SMTP_HOST = cast.ToString(coalesce("SMTP_HOST", ""))
+	config.SMTP_PORT = cast.ToInt(coalesce("SMTP_PORT", 587))
+	config.SMTP_USERNAME = cast.ToString(coalesce("SMTP_USERNAME", ""))
+	config.SMTP_PASSWORD = cast.ToString(coalesce("SMTP_PASSWORD", ""))
+	config.SMTP_TLS = cast.ToString(coalesce("SMTP_TLS", "starttls"))
+
+	config.PAT_DEFAULT_TTL = cast.ToDuration(coalesce("PAT_DEFAULT_TTL", "2160h"))
+	config.PAT_MAX_TTL = cast.ToDuration(coalesce("PAT_MAX_TTL", "8760h"))
+
+	config.GRPC_TLS_CERT_FILE = cast.ToString(coalesce("GRPC_TLS_CERT_FILE", ""))
+	config.GRPC_TLS_KEY_FILE = cast.ToString(coalesce("GRPC_TLS_KEY_FILE", ""))
+	config.GRPC_TLS_CLIENT_CA_FILE = cast.ToString(coalesce("GRPC_TLS_CLIENT_CA_FILE", ""))
+	config.GRPC_ALLOW_PLAINTEXT = cast.ToBool(coalesce("GRPC_ALLOW_PLAINTEXT", false))
+	config.GRPC_SERVICE_AUTH = cast.ToBool(coalesce("GRPC_SERVICE_AUTH", true))
+	config.GRPC_ALLOWED_CALLERS = cast.ToString(coalesce("GRPC_ALLOWED_CALLERS",
+		"ValidateToken=*;CheckPermission=*;ListUserGroups=*;GetGroupMembership=*"))
+	config.GRPC_USER_AUTH = cast.ToBool(coalesce("GRPC_USER_AUTH", true))
+
+	config.ACCOUNT_DELETION_GRACE_PERIOD = cast.ToDuration(coalesce("ACCOUNT_DELETION_GRACE_PERIOD", "720h"))
+	config.ACCOUNT_DELETION_POLL_INTERVAL = cast.ToDuration(coalesce("ACCOUNT_DELETION_POLL_INTERVAL", "10m"))
+	config.ACCOUNT_DELETION_BATCH_SIZE = cast.ToInt(coalesce("ACCOUNT_DELETION_BATCH_SIZE", 50))
+
+	config.EVENTS_DRIVER = cast.ToString(coalesce("EVENTS_DRIVER", "redis"))
+	config.EVENTS_STREAM = cast.ToString(coalesce("EVENTS_STREAM", "auth-service.events"))
+	config.EVENTS_POLL_INTERVAL = cast.ToDuration(coalesce("EVENTS_POLL_INTERVAL", "1s"))
+	config.EVENTS_BATCH_SIZE = cast.ToInt(coalesce("EVENTS_BATCH_SIZE", 100))
+	config.EVENTS_PUBLISH_TIMEOUT = cast.ToDuration(coalesce("EVENTS_PUBLISH_TIMEOUT", "5s"))
+	config.EVENTS_RETRY_BASE = cast.ToDuration(coalesce("EVENTS_RETRY_BASE", "1s"))
+	config.EVENTS_RETRY_MAX = cast.ToDuration(coalesce("EVENTS_RETRY_MAX", "5m"))
+	config.EVENTS_RETENTION = cast.ToDuration(coalesce("EVENTS_RETENTION", "168h"))
+
+	config.EXPORT_DIR = cast.ToString(coalesce("EXPORT_DIR", "exports"))
+	config.EXPORT_SIGNING_KEY = cast.ToString(coalesce("EXPORT_SIGNING_KEY", ""))
+	config.EXPORT_LINK_TTL = cast.ToDuration(coalesce("EXPORT_LINK_TTL", "72h"))
+	config.EXPORT_POLL_INTERVAL = cast.ToDuration(coalesce("EXPORT_POLL_INTERVAL", "10s"))
+	config.EXPORT_MAX_ATTEMPTS = cast.ToInt(coalesce("EXPORT_MAX_ATTEMPTS", 3))
+	config.EXPORT_SOURCE_TIMEOUT = cast.ToDuration(coalesce("EXPORT_SOURCE_TIMEOUT", "30s"))
+	config.BUDGETING_GRPC_ADDR = cast.ToString(coalesce("BUDGETING_GRPC_ADDR", ""))
+	config.FINANCE_GRPC_ADDR = cast.ToString(coalesce("FINANCE_GRPC_ADDR", ""))
+	config.GOALS_GRPC_ADDR = cast.ToString(coalesce("GOALS_GRPC_ADDR", ""))
+	config.GRPC_CLIENT_CA_FILE = cast.ToString(coalesce("GRPC_CLIENT_CA_FILE", ""))
 
 	return config
 }
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    user_email VARCHAR(255) NOT NULL,
+    token VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID DEFAULT GEN_RANDOM_UUID() PRIMARY KEY,
+    user_id UUID,
+    event_type VARCHAR(64) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id UUID DEFAULT GEN_RANDOM_UUID() PRIMARY KEY,
+    client_id VARCHAR(255) UNIQUE NOT NULL,
+    client_secret_hash VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL,
+    grant_types TEXT[] NOT NULL DEFAULT '{}',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ
+);
@@ -0,0 +1 @@
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS redirect_uris TEXT[] NOT NULL DEFAULT '{}';
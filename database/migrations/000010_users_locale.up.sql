@@ -0,0 +1,5 @@
+-- An empty locale means the user never picked one; emails then follow the
+-- Accept-Language header of the request that triggered them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(8) NOT NULL DEFAULT '';
+
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS text TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,67 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(64) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(64) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role ON user_roles(role);
+
+INSERT INTO roles (name, description) VALUES
+    ('user', 'Regular account holder'),
+    ('admin', 'Operator with access to every administrative endpoint')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'List and view any user'),
+    ('users:write', 'Change any user'),
+    ('roles:read', 'List roles and their permissions'),
+    ('roles:write', 'Grant and revoke roles'),
+    ('accounts:unlock', 'Lift brute-force lockouts'),
+    ('mfa:reset', 'Remove two-factor authentication of a user'),
+    ('emails:read', 'View the outgoing email queue'),
+    ('emails:write', 'Re-drive dead-lettered emails'),
+    ('budgets:read', 'View own budgets'),
+    ('budgets:write', 'Change own budgets'),
+    ('transactions:read', 'View own transactions'),
+    ('transactions:write', 'Change own transactions'),
+    ('reports:read', 'View own reports')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission)
+    SELECT 'user', name FROM permissions
+    WHERE name IN ('budgets:read', 'budgets:write', 'transactions:read', 'transactions:write', 'reports:read')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role, permission)
+    SELECT 'admin', name FROM permissions
+ON CONFLICT DO NOTHING;
+
+-- users.role used to be free-form; anything that is not a known role becomes
+-- a regular user before the column is tied to the roles table.
+UPDATE users SET role = 'user' WHERE role NOT IN (SELECT name FROM roles);
+
+ALTER TABLE users
+    ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles(name);
+
+INSERT INTO user_roles (user_id, role)
+    SELECT id, role FROM users
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS groups (
+    id UUID DEFAULT GEN_RANDOM_UUID() PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+    joined_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members(user_id);
+
+CREATE TABLE IF NOT EXISTS group_invitations (
+    id UUID DEFAULT GEN_RANDOM_UUID() PRIMARY KEY,
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    responded_at TIMESTAMPTZ
+);
+
+-- One open invitation per address and group; answered ones are kept as history.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_invitations_pending
+    ON group_invitations(group_id, email) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_group_invitations_email ON group_invitations(email);
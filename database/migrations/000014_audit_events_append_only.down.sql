@@ -0,0 +1,9 @@
+DELETE FROM permissions WHERE name = 'audit:read';
+
+DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;
+DROP TRIGGER IF EXISTS audit_events_no_update ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+
+DROP INDEX IF EXISTS idx_audit_events_event_type;
+DROP INDEX IF EXISTS idx_audit_events_actor_id;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS actor_id;
@@ -0,0 +1,8 @@
+-- Set when the user asks for their account to be deleted. Once it has passed
+-- the account is anonymized and deleted_at is set; the column is cleared when
+-- the user.deleted event has been published, or when the user signs in again
+-- before the date.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at
+    ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID DEFAULT GEN_RANDOM_UUID() PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- pending, building, ready, failed or expired
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    file_path TEXT NOT NULL DEFAULT '',
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_data_exports_due ON data_exports(next_attempt_at) WHERE status IN ('pending', 'building');
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports(expires_at) WHERE status = 'ready';
+-- One export in progress per user at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_in_progress
+    ON data_exports(user_id) WHERE status IN ('pending', 'building');
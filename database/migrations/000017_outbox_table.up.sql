@@ -0,0 +1,17 @@
+-- Domain events, written in the same transaction as the change they describe
+-- and published by the relay. The ID doubles as the idempotency key.
+CREATE TABLE IF NOT EXISTS outbox (
+    id UUID DEFAULT GEN_RANDOM_UUID() PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    -- No foreign key: events outlive anonymized and removed accounts.
+    user_id UUID NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox(next_attempt_at) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_published_at ON outbox(published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- The OAuth client a session was started for. Only that client may exchange
+-- the session's refresh token; it is empty for sessions of the first-party
+-- login API.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_id VARCHAR(255) NOT NULL DEFAULT '';
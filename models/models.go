@@ -1,10 +1,17 @@
 package models
 
 type User struct {
-	ID       string `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	Locale    string `json:"locale"`
+	CreatedAt string `json:"created_at"`
+	// Permissions are resolved from the user's roles when a token is issued.
+	Permissions []string `json:"permissions,omitempty"`
+	// Groups are the user's household memberships when a token is issued.
+	Groups []GroupMembership `json:"groups,omitempty"`
 }
 
 type RegisterUser struct {
@@ -12,11 +19,15 @@ type RegisterUser struct {
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	Password  string `json:"password"`
+	// Locale of the emails sent to the user: en, ru or uz. When empty the
+	// request's Accept-Language header decides.
+	Locale string `json:"locale"`
 }
 
 type LoginUserReq struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
 }
 
 type LoginUserResp struct {
@@ -24,20 +35,129 @@ type LoginUserResp struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type MFAPendingResp struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type MFAVerifyReq struct {
+	MFAToken   string `json:"mfa_token"`
+	Code       string `json:"code"`
+	DeviceName string `json:"device_name"`
+}
+
+type MFACodeReq struct {
+	Code string `json:"code"`
+}
+
+type UserMFA struct {
+	UserID          string `json:"user_id"`
+	SecretEncrypted string `json:"-"`
+	Enabled         bool   `json:"enabled"`
+	EnabledAt       string `json:"enabled_at"`
+}
+
+type TOTPEnrollResp struct {
+	Secret     string `json:"secret"`
+	OtpauthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"`
+}
+
+type RecoveryCodesResp struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+type WebAuthnCredential struct {
+	ID              string   `json:"id"`
+	UserID          string   `json:"user_id"`
+	Name            string   `json:"name"`
+	CredentialID    []byte   `json:"-"`
+	PublicKey       []byte   `json:"-"`
+	AttestationType string   `json:"attestation_type"`
+	Transports      []string `json:"transports"`
+	AAGUID          []byte   `json:"-"`
+	SignCount       uint32   `json:"sign_count"`
+	BackupEligible  bool     `json:"backup_eligible"`
+	BackupState     bool     `json:"backup_state"`
+	CreatedAt       string   `json:"created_at"`
+	LastUsedAt      string   `json:"last_used_at"`
+}
+
+type WebAuthnLoginBeginReq struct {
+	Email string `json:"email"`
+}
+
+type WebAuthnBeginResp struct {
+	SessionID string      `json:"session_id"`
+	Options   interface{} `json:"options"`
+}
+
+type VerifyEmailReq struct {
+	Token string `json:"token"`
+}
+
+// EmailChange is a change of the user's email to Email that waits for the
+// new address to be confirmed.
+type EmailChange struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+type ResendVerificationReq struct {
+	Email string `json:"email"`
+}
+
+type UnlockAccountReq struct {
+	Email string `json:"email"`
+}
+
 type UpdateUserProfile struct {
 	Email     string `json:"email"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 }
 
+// DeleteAccountReq re-confirms an account deletion with the password and,
+// when two-factor authentication is enabled, a TOTP or recovery code.
+type DeleteAccountReq struct {
+	Password string `json:"password"`
+	MFACode  string `json:"mfa_code"`
+}
+
+// AccountDeletionResp tells when a scheduled account deletion takes effect.
+type AccountDeletionResp struct {
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	DeleteAt string `json:"delete_at"`
+}
+
 type Response struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
 }
 
+// ManageUserRoles replaces the roles of a user. Role sets a single role;
+// Roles sets several, the first one being reported as the user's role.
 type ManageUserRoles struct {
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	Email string   `json:"email"`
+	Role  string   `json:"role"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RolesList struct {
+	Roles []Role `json:"roles"`
 }
 
 type ForgotPassword struct {
@@ -50,12 +170,291 @@ type ResetPassword struct {
 	Code     string `json:"code"`
 }
 
-type RefreshToken struct {
-	Email        string `json:"email"`
+type Session struct {
+	ID               string `json:"id"`
+	UserID           string `json:"user_id"`
+	DeviceName       string `json:"device_name"`
+	UserAgent        string `json:"user_agent"`
+	IPAddress        string `json:"ip_address"`
+	ClientID         string `json:"client_id,omitempty"`
+	RefreshTokenHash string `json:"-"`
+	CreatedAt        string `json:"created_at"`
+	LastUsedAt       string `json:"last_used_at"`
+	ExpiresAt        string `json:"expires_at"`
+	Current          bool   `json:"current"`
+}
+
+type SessionsList struct {
+	Sessions []Session `json:"sessions"`
+}
+
+type RefreshTokenReq struct {
 	RefreshToken string `json:"refresh_token"`
-	ExpiresAt    string `json:"expires_at"`
 }
 
+type ClientInfo struct {
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	DeviceName string `json:"device_name"`
+	// ClientID is the OAuth client the request was made through, if any.
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// AuditEvent is an entry of the audit log. UserID is the account the event
+// is about and ActorID whoever caused it when that is someone else, such as
+// the admin who changed the user's roles.
+type AuditEvent struct {
+	ID        string            `json:"id"`
+	UserID    string            `json:"user_id"`
+	ActorID   string            `json:"actor_id,omitempty"`
+	EventType string            `json:"event_type"`
+	IPAddress string            `json:"ip_address"`
+	UserAgent string            `json:"user_agent"`
+	Metadata  map[string]string `json:"metadata"`
+	CreatedAt string            `json:"created_at"`
+}
+
+// AuditEventFilter selects audit events. Empty fields match everything; From
+// and To are RFC 3339 timestamps bounding created_at.
+type AuditEventFilter struct {
+	UserID    string
+	ActorID   string
+	EventType string
+	IPAddress string
+	From      string
+	To        string
+	Limit     int
+	Offset    int
+}
+
+type AuditEventsList struct {
+	Events []AuditEvent `json:"events"`
+	Total  int          `json:"total"`
+}
+
+// Event is a domain event in the outbox, such as user.deleted. ID is its
+// idempotency key; the outbox keeps an ID only once.
+type Event struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	UserID     string            `json:"user_id"`
+	Data       map[string]string `json:"data,omitempty"`
+	OccurredAt string            `json:"occurred_at"`
+	Attempts   int               `json:"-"`
+}
+
+type EmailMessage struct {
+	ID            string `json:"id"`
+	To            string `json:"to"`
+	Subject       string `json:"subject"`
+	HTML          string `json:"-"`
+	Text          string `json:"-"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"last_error"`
+	NextAttemptAt string `json:"next_attempt_at"`
+	CreatedAt     string `json:"created_at"`
+	SentAt        string `json:"sent_at,omitempty"`
+}
+
+// DataExport is an archive of the personal data held about a user, built in
+// the background. DownloadURL is only set while the archive is ready.
+type DataExport struct {
+	ID          string `json:"id"`
+	UserID      string `json:"-"`
+	Email       string `json:"-"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"-"`
+	LastError   string `json:"-"`
+	FilePath    string `json:"-"`
+	SizeBytes   int64  `json:"size_bytes,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+type EmailMessagesList struct {
+	Messages []EmailMessage `json:"messages"`
+}
+
+type GroupMembership struct {
+	GroupID string `json:"id"`
+	Role    string `json:"role"`
+}
+
+type Group struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Role      string        `json:"role,omitempty"`
+	CreatedAt string        `json:"created_at"`
+	Members   []GroupMember `json:"members,omitempty"`
+}
+
+type GroupMember struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	JoinedAt string `json:"joined_at"`
+}
+
+type GroupsList struct {
+	Groups []Group `json:"groups"`
+}
+
+type CreateGroupReq struct {
+	Name string `json:"name"`
+}
+
+type GroupInvitation struct {
+	ID        string `json:"id"`
+	GroupID   string `json:"group_id"`
+	GroupName string `json:"group_name"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	InvitedBy string `json:"invited_by"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type GroupInvitationsList struct {
+	Invitations []GroupInvitation `json:"invitations"`
+}
+
+type InviteGroupMemberReq struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateGroupMemberReq struct {
+	Role string `json:"role"`
+}
+
+// PersonalToken is a user-created API token for scripts and integrations.
+// The token itself is only returned once, by PersonalTokenCreated.
+type PersonalToken struct {
+	ID         string   `json:"id"`
+	UserID     string   `json:"-"`
+	Email      string   `json:"-"`
+	Role       string   `json:"-"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	TokenHash  string   `json:"-"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+}
+
+type PersonalTokenCreated struct {
+	PersonalToken
+	Token string `json:"token"`
+}
+
+type PersonalTokensList struct {
+	Tokens []PersonalToken `json:"tokens"`
+}
+
+// CreatePersonalTokenReq describes a new token. Scopes must be permissions
+// the user holds; ExpiresInDays defaults to PAT_DEFAULT_TTL.
+type CreatePersonalTokenReq struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type OAuthClient struct {
+	ID               string   `json:"id"`
+	ClientID         string   `json:"client_id"`
+	ClientSecretHash string   `json:"-"`
+	Name             string   `json:"name"`
+	GrantTypes       []string `json:"grant_types"`
+	Scopes           []string `json:"scopes"`
+	RedirectURIs     []string `json:"redirect_uris"`
+}
+
+type AuthorizeReq struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Email               string `form:"email"`
+	Password            string `form:"password"`
+	MFAToken            string `form:"mfa_token"`
+	MFACode             string `form:"mfa_code"`
+	Action              string `form:"action"`
+}
+
+type AuthorizationCode struct {
+	ClientID      string `json:"client_id"`
+	RedirectURI   string `json:"redirect_uri"`
+	UserID        string `json:"user_id"`
+	Scope         string `json:"scope"`
+	Nonce         string `json:"nonce"`
+	CodeChallenge string `json:"code_challenge"`
+}
+
+type TokenReq struct {
+	GrantType    string `form:"grant_type"`
+	Username     string `form:"username"`
+	Password     string `form:"password"`
+	RefreshToken string `form:"refresh_token"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type TokenResp struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type UserInfo struct {
+	Sub        string `json:"sub"`
+	Email      string `json:"email"`
+	GivenName  string `json:"given_name"`
+	FamilyName string `json:"family_name"`
+}
+
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// Error is the body of every error response. Code is one of the apperr
+// codes, such as not_found or rate_limited; RequestID matches the
+// X-Request-ID response header.
 type Error struct {
-	Message string `json:"message"`
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
 }
@@ -0,0 +1,130 @@
+// Package apperr defines the errors repositories and services return to
+// describe what went wrong in terms callers can act on. The transports map
+// them to gRPC status codes and to the HTTP error envelope.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code classifies an error. It is sent to clients as is, so values are part
+// of the API.
+type Code string
+
+const (
+	NotFound         Code = "not_found"
+	Conflict         Code = "conflict"
+	InvalidArgument  Code = "invalid_argument"
+	Unauthenticated  Code = "unauthenticated"
+	PermissionDenied Code = "permission_denied"
+	RateLimited      Code = "rate_limited"
+	Internal         Code = "internal"
+)
+
+// Error is a domain error. Sentinels are declared with New and compared with
+// errors.Is; errors derived from them with WithDetail still match.
+type Error struct {
+	Code    Code
+	Message string
+	Details map[string]string
+
+	base  *Error
+	cause error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap returns an error with code and message caused by err. The cause is
+// kept for logs and errors.Is but never shown to clients.
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, cause: err}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	var errs []error
+	if e.base != nil {
+		errs = append(errs, e.base)
+	}
+	if e.cause != nil {
+		errs = append(errs, e.cause)
+	}
+	return errs
+}
+
+// WithDetail returns a copy of e with key set in its details.
+func (e *Error) WithDetail(key, value string) *Error {
+	details := make(map[string]string, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	details[key] = value
+	return &Error{Code: e.Code, Message: e.Message, Details: details, base: e, cause: e.cause}
+}
+
+// As returns the domain error in err's chain, if there is one.
+func As(err error) (*Error, bool) {
+	var target *Error
+	if errors.As(err, &target) {
+		return target, true
+	}
+	return nil, false
+}
+
+// CodeOf returns the code of the domain error in err's chain, or Internal
+// for any other error.
+func CodeOf(err error) Code {
+	if e, ok := As(err); ok {
+		return e.Code
+	}
+	return Internal
+}
+
+// HTTPStatus returns the HTTP status code for code.
+func HTTPStatus(code Code) int {
+	switch code {
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case InvalidArgument:
+		return http.StatusBadRequest
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case PermissionDenied:
+		return http.StatusForbidden
+	case RateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeForHTTPStatus is the inverse of HTTPStatus, for responses written from
+// a status code rather than from an error.
+func CodeForHTTPStatus(status int) Code {
+	switch status {
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusConflict:
+		return Conflict
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	case http.StatusTooManyRequests:
+		return RateLimited
+	}
+	if status >= 400 && status < 500 {
+		return InvalidArgument
+	}
+	return Internal
+}
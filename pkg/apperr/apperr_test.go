@@ -0,0 +1,80 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errUserNotFound = New(NotFound, "user not found")
+
+func TestErrorMatching(t *testing.T) {
+	detailed := errUserNotFound.WithDetail("user_id", "42")
+	assert.ErrorIs(t, detailed, errUserNotFound)
+	assert.ErrorIs(t, fmt.Errorf("lookup: %w", detailed), errUserNotFound)
+	assert.Equal(t, map[string]string{"user_id": "42"}, detailed.Details)
+	assert.Nil(t, errUserNotFound.Details)
+
+	cause := errors.New("connection refused")
+	wrapped := Wrap(Internal, "database unavailable", cause)
+	assert.ErrorIs(t, wrapped, cause)
+	assert.Equal(t, "database unavailable: connection refused", wrapped.Error())
+
+	assert.Equal(t, NotFound, CodeOf(fmt.Errorf("lookup: %w", detailed)))
+	assert.Equal(t, Internal, CodeOf(cause))
+}
+
+func TestStatusMapping(t *testing.T) {
+	for _, code := range []Code{NotFound, Conflict, InvalidArgument, Unauthenticated, PermissionDenied, RateLimited, Internal} {
+		assert.Equal(t, code, CodeForHTTPStatus(HTTPStatus(code)))
+	}
+	assert.Equal(t, InvalidArgument, CodeForHTTPStatus(422))
+	assert.Equal(t, Internal, CodeForHTTPStatus(503))
+	assert.Equal(t, codes.ResourceExhausted, GRPCCode(RateLimited))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(slog.Default())
+	call := func(err error) error {
+		_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/auth_service.AuthService/GetUserProfile"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, err
+			})
+		return err
+	}
+
+	assert.NoError(t, call(nil))
+
+	st := status.Convert(call(fmt.Errorf("get profile: %w", errUserNotFound.WithDetail("user_id", "42"))))
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "user not found", st.Message())
+	if assert.Len(t, st.Details(), 1) {
+		info := st.Details()[0].(*errdetails.ErrorInfo)
+		assert.Equal(t, "not_found", info.Reason)
+		assert.Equal(t, ErrorDomain, info.Domain)
+		assert.Equal(t, "42", info.Metadata["user_id"])
+	}
+
+	st = status.Convert(call(errors.New(`pq: relation "users" does not exist`)))
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "internal error", st.Message())
+
+	st = status.Convert(call(status.Error(codes.Unauthenticated, "user token is required")))
+	assert.Equal(t, codes.Unauthenticated, st.Code())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/auth_service.AuthService/GetUserProfile"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, errors.New("pq: canceling statement due to user request")
+		})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
@@ -0,0 +1,78 @@
+package apperr
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail attached to gRPC errors.
+const ErrorDomain = "auth-service"
+
+// GRPCCode returns the gRPC status code for code.
+func GRPCCode(code Code) codes.Code {
+	switch code {
+	case NotFound:
+		return codes.NotFound
+	case Conflict:
+		return codes.AlreadyExists
+	case InvalidArgument:
+		return codes.InvalidArgument
+	case Unauthenticated:
+		return codes.Unauthenticated
+	case PermissionDenied:
+		return codes.PermissionDenied
+	case RateLimited:
+		return codes.ResourceExhausted
+	}
+	return codes.Internal
+}
+
+// GRPCStatus lets grpc-go send e with its proper code. The code and details
+// travel as an ErrorInfo so clients can branch on them without parsing the
+// message.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(GRPCCode(e.Code), e.Message)
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   string(e.Code),
+		Domain:   ErrorDomain,
+		Metadata: e.Details,
+	})
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// UnaryServerInterceptor converts errors returned by handlers into statuses.
+// Domain errors keep their code; errors that are neither domain errors nor
+// statuses are logged and hidden behind Internal, so that database or Redis
+// messages never reach clients, unless the request was cancelled or timed out.
+func UnaryServerInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if e, ok := As(err); ok {
+			if e.Code == Internal {
+				logger.Error("Internal error", "method", info.FullMethod, "error", err)
+			}
+			return nil, e.GRPCStatus().Err()
+		}
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		// The caller gave up or its deadline passed, which is what the query
+		// or call that failed reports in its own words.
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		logger.Error("Internal error", "method", info.FullMethod, "error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+}
@@ -0,0 +1,61 @@
+// Package dataexport writes the archives of personal data exports: a zip with
+// every section of the export as JSON and as CSV.
+package dataexport
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Archive writes the sections of an export into a zip.
+type Archive struct {
+	zw *zip.Writer
+}
+
+func NewArchive(w io.Writer) *Archive {
+	return &Archive{zw: zip.NewWriter(w)}
+}
+
+// Add writes the section name twice: v as name.json, and header and rows as
+// name.csv. Values a client or another user could have chosen should pass
+// through Cell first.
+func (a *Archive) Add(name string, v any, header []string, rows [][]string) error {
+	f, err := a.zw.Create(name + ".json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	f, err = a.zw.Create(name + ".csv")
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// Close finishes the zip; it does not close the underlying writer.
+func (a *Archive) Close() error {
+	return a.zw.Close()
+}
+
+// Cell defuses values that spreadsheets would otherwise evaluate as formulas.
+func Cell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
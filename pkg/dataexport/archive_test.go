@@ -0,0 +1,41 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewArchive(&buf)
+	err := archive.Add("sessions", []map[string]string{{"id": "1", "user_agent": "=cmd"}},
+		[]string{"id", "user_agent"}, [][]string{{"1", Cell("=cmd")}})
+	assert.NoError(t, err)
+	assert.NoError(t, archive.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+
+	assert.Contains(t, files["sessions.json"], `"user_agent": "=cmd"`)
+	assert.Equal(t, "id,user_agent\n1,'=cmd\n", files["sessions.csv"])
+}
+
+func TestCell(t *testing.T) {
+	assert.Equal(t, "'=SUM(A1)", Cell("=SUM(A1)"))
+	assert.Equal(t, "'@x", Cell("@x"))
+	assert.Equal(t, "Firefox", Cell("Firefox"))
+	assert.Equal(t, "", Cell(""))
+}
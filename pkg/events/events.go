@@ -0,0 +1,41 @@
+// Package events publishes the auth service's domain events, such as
+// user.registered, to the services that follow them.
+package events
+
+import (
+	"auth-service/config"
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Event is a domain event. ID is its idempotency key: delivery is at least
+// once, so an event may arrive more than once and consumers should drop the
+// IDs they have already seen.
+type Event struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	UserID     string            `json:"user_id"`
+	Data       map[string]string `json:"data,omitempty"`
+	OccurredAt string            `json:"occurred_at"`
+}
+
+// EventPublisher hands an event to a broker. A nil error means the broker has
+// accepted it. Implementations must be safe for concurrent use.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// New returns the EventPublisher selected by cfg.EVENTS_DRIVER: "redis" or
+// "memory".
+func New(cfg *config.Config, client *redis.Client) (EventPublisher, error) {
+	switch cfg.EVENTS_DRIVER {
+	case "redis":
+		return NewRedisStreamPublisher(client, cfg.EVENTS_STREAM), nil
+	case "memory":
+		return NewMemoryPublisher(), nil
+	default:
+		return nil, fmt.Errorf("unknown events driver %q", cfg.EVENTS_DRIVER)
+	}
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"auth-service/config"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryPublisher(t *testing.T) {
+	p := NewMemoryPublisher()
+	event := Event{ID: "0b0e4cb1-8d2a-4f0e-9c39-6d5a64c1f0aa", Type: "user.registered", UserID: "d70789c8-37e0-4de6-8195-d900abc0afb5"}
+
+	assert.NoError(t, p.Publish(context.Background(), event))
+	assert.NoError(t, p.Publish(context.Background(), event))
+	assert.Equal(t, []Event{event}, p.Published())
+
+	p.Err = errors.New("broker down")
+	assert.Error(t, p.Publish(context.Background(), Event{ID: "other"}))
+	assert.Len(t, p.Published(), 1)
+}
+
+func TestStreamValues(t *testing.T) {
+	values, err := streamValues(Event{
+		ID:         "0b0e4cb1-8d2a-4f0e-9c39-6d5a64c1f0aa",
+		Type:       "user.email_changed",
+		UserID:     "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		Data:       map[string]string{"email": "new_email@test.com"},
+		OccurredAt: "2024-05-01T12:30:00Z",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "0b0e4cb1-8d2a-4f0e-9c39-6d5a64c1f0aa", values["id"])
+	assert.Equal(t, "user.email_changed", values["type"])
+	assert.JSONEq(t, `{
+		"id": "0b0e4cb1-8d2a-4f0e-9c39-6d5a64c1f0aa",
+		"type": "user.email_changed",
+		"user_id": "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		"data": {"email": "new_email@test.com"},
+		"occurred_at": "2024-05-01T12:30:00Z"
+	}`, values["event"].(string))
+}
+
+func TestNewUnknownDriver(t *testing.T) {
+	_, err := New(&config.Config{EVENTS_DRIVER: "carrier-pigeon"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher keeps published events in memory so tests can inspect them.
+// Like a well-behaved consumer, it keeps an event only once however often it
+// is published.
+type MemoryPublisher struct {
+	mu        sync.Mutex
+	published []Event
+	seen      map[string]bool
+	// Err, when set, is returned by Publish instead of keeping the event.
+	Err error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{seen: map[string]bool{}}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, event Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return p.Err
+	}
+	if !p.seen[event.ID] {
+		p.seen[event.ID] = true
+		p.published = append(p.published, event)
+	}
+	return nil
+}
+
+// Published returns a copy of the events published so far, oldest first.
+func (p *MemoryPublisher) Published() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Event(nil), p.published...)
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamPublisher appends events to a Redis stream that other services
+// read with consumer groups.
+type RedisStreamPublisher struct {
+	client *redis.Client
+	stream string
+}
+
+func NewRedisStreamPublisher(client *redis.Client, stream string) *RedisStreamPublisher {
+	return &RedisStreamPublisher{client: client, stream: stream}
+}
+
+func (p *RedisStreamPublisher) Publish(ctx context.Context, event Event) error {
+	values, err := streamValues(event)
+	if err != nil {
+		return err
+	}
+	return p.client.XAdd(ctx, &redis.XAddArgs{Stream: p.stream, Values: values}).Err()
+}
+
+// streamValues are the fields of an event's stream entry: the ID and the type,
+// for deduplication and filtering without decoding, and the event as JSON.
+func streamValues(event Event) (map[string]interface{}, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"id":    event.ID,
+		"type":  event.Type,
+		"event": string(payload),
+	}, nil
+}
@@ -0,0 +1,56 @@
+// Package grpcauth authenticates the services calling the gRPC API and checks
+// them against a per-method allow-list.
+package grpcauth
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// AnyService in an ACL entry allows every authenticated service.
+const AnyService = "*"
+
+// ACL maps a method name, such as GetUsersList, to the identities of the
+// services allowed to call it. Methods that are not listed cannot be called.
+type ACL map[string][]string
+
+// ParseACL reads an allow-list in the form
+//
+//	ValidateToken=*;GetUserProfile=budgeting,reporting;GetUsersList=admin-panel
+//
+// where identities are OAuth client IDs or certificate SANs.
+func ParseACL(s string) (ACL, error) {
+	acl := ACL{}
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		method, callers, ok := strings.Cut(entry, "=")
+		method = strings.TrimSpace(method)
+		if !ok || method == "" {
+			return nil, fmt.Errorf("invalid ACL entry %q, want Method=service1,service2", entry)
+		}
+		for _, caller := range strings.Split(callers, ",") {
+			if caller = strings.TrimSpace(caller); caller != "" {
+				acl[method] = append(acl[method], caller)
+			}
+		}
+	}
+	return acl, nil
+}
+
+// Allows reports which of identities may call fullMethod
+// (/package.Service/Method).
+func (a ACL) Allows(fullMethod string, identities []string) (string, bool) {
+	allowed := a[path.Base(fullMethod)]
+	for _, identity := range identities {
+		if slices.Contains(allowed, identity) || slices.Contains(allowed, AnyService) {
+			return identity, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,39 @@
+package grpcauth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseACL(t *testing.T) {
+	acl, err := ParseACL(" ValidateToken=* ; GetUserProfile=budgeting, reporting;;GetUsersList=")
+	assert.NoError(t, err)
+	assert.Equal(t, ACL{
+		"ValidateToken":  {"*"},
+		"GetUserProfile": {"budgeting", "reporting"},
+	}, acl)
+
+	_, err = ParseACL("GetUserProfile")
+	assert.Error(t, err)
+}
+
+func TestACLAllows(t *testing.T) {
+	acl := ACL{
+		"ValidateToken":  {AnyService},
+		"GetUserProfile": {"budgeting"},
+	}
+
+	identity, ok := acl.Allows("/auth_service.AuthService/GetUserProfile", []string{"reporting", "budgeting"})
+	assert.True(t, ok)
+	assert.Equal(t, "budgeting", identity)
+
+	_, ok = acl.Allows("/auth_service.AuthService/GetUserProfile", []string{"reporting"})
+	assert.False(t, ok)
+
+	_, ok = acl.Allows("/auth_service.AuthService/ValidateToken", []string{"reporting"})
+	assert.True(t, ok)
+
+	_, ok = acl.Allows("/auth_service.AuthService/ChangePassword", []string{"budgeting"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,23 @@
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceToken(t *testing.T) {
+	creds := ServiceToken{Source: func() (string, error) { return "abc", nil }}
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{ServiceTokenMetadataKey: "Bearer abc"}, md)
+	assert.False(t, creds.RequireTransportSecurity())
+
+	creds = ServiceToken{Source: func() (string, error) { return "", errors.New("no key") }, Secure: true}
+	_, err = creds.GetRequestMetadata(context.Background())
+	assert.Error(t, err)
+	assert.True(t, creds.RequireTransportSecurity())
+}
@@ -0,0 +1,96 @@
+package grpcauth
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceTokenMetadataKey carries the calling service's client_credentials
+// access token as "Bearer <token>". The authorization key is left to the end
+// user's token.
+const ServiceTokenMetadataKey = "x-service-authorization"
+
+// TokenVerifier validates a service access token and returns the OAuth client
+// ID it was issued to.
+type TokenVerifier func(token string) (string, error)
+
+type serviceKey struct{}
+
+// ServiceFromContext returns the identity of the calling service, as set by
+// UnaryServerInterceptor.
+func ServiceFromContext(ctx context.Context) (string, bool) {
+	service, ok := ctx.Value(serviceKey{}).(string)
+	return service, ok
+}
+
+// UnaryServerInterceptor authenticates the calling service by the SANs of its
+// verified client certificate and by the token in ServiceTokenMetadataKey,
+// and lets the call through when one of those identities is allowed to call
+// the method by acl.
+func UnaryServerInterceptor(acl ACL, verify TokenVerifier, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		identities := peerIdentities(ctx)
+
+		if raw := serviceToken(ctx); raw != "" {
+			clientID, err := verify(raw)
+			if err != nil {
+				logger.Warn("Service token rejected", "method", info.FullMethod, "error", err)
+				return nil, status.Error(codes.Unauthenticated, "invalid service token")
+			}
+			identities = append(identities, clientID)
+		}
+		if len(identities) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "service credentials are required")
+		}
+
+		identity, ok := acl.Allows(info.FullMethod, identities)
+		if !ok {
+			logger.Warn("Service call denied", "method", info.FullMethod, "identities", identities)
+			return nil, status.Errorf(codes.PermissionDenied, "service is not allowed to call %s", info.FullMethod)
+		}
+
+		return handler(context.WithValue(ctx, serviceKey{}, identity), req)
+	}
+}
+
+func serviceToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get(ServiceTokenMetadataKey) {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return token
+		}
+	}
+	return ""
+}
+
+// peerIdentities returns the DNS and URI SANs of the client certificate of a
+// mutual TLS connection. Certificates are only present once the TLS stack has
+// verified them against the client CA.
+func peerIdentities(ctx context.Context) []string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return nil
+	}
+
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	identities := append([]string{}, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return identities
+}
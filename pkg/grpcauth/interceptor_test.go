@@ -0,0 +1,76 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	acl := ACL{
+		"GetUserProfile": {"budgeting", "spiffe://finance/reporting"},
+		"ValidateToken":  {AnyService},
+	}
+	verify := func(token string) (string, error) {
+		if token == "good" {
+			return "budgeting", nil
+		}
+		return "", errors.New("bad token")
+	}
+	interceptor := UnaryServerInterceptor(acl, verify, slog.Default())
+
+	call := func(ctx context.Context, method string) (string, error) {
+		var caller string
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/auth_service.AuthService/" + method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				caller, _ = ServiceFromContext(ctx)
+				return nil, nil
+			})
+		return caller, err
+	}
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(ServiceTokenMetadataKey, "Bearer "+token))
+	}
+	withCert := func(cert *x509.Certificate) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+		})
+	}
+
+	_, err := call(context.Background(), "GetUserProfile")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = call(withToken("bad"), "GetUserProfile")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	caller, err := call(withToken("good"), "GetUserProfile")
+	assert.NoError(t, err)
+	assert.Equal(t, "budgeting", caller)
+
+	_, err = call(withToken("good"), "ChangePassword")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	reporting, _ := url.Parse("spiffe://finance/reporting")
+	caller, err = call(withCert(&x509.Certificate{URIs: []*url.URL{reporting}}), "GetUserProfile")
+	assert.NoError(t, err)
+	assert.Equal(t, "spiffe://finance/reporting", caller)
+
+	caller, err = call(withCert(&x509.Certificate{DNSNames: []string{"goals"}}), "ValidateToken")
+	assert.NoError(t, err)
+	assert.Equal(t, "goals", caller)
+
+	_, err = call(withCert(&x509.Certificate{DNSNames: []string{"goals"}}), "GetUserProfile")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
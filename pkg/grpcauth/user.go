@@ -0,0 +1,108 @@
+package grpcauth
+
+import (
+	"auth-service/api/token"
+	"auth-service/pkg/apperr"
+	"context"
+	"errors"
+	"log/slog"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UserTokenMetadataKey carries the end user's access token or personal access
+// token as "Bearer <token>".
+const UserTokenMetadataKey = "authorization"
+
+// UserRule describes who may call a method on behalf of a user. With Self set
+// the caller may act on their own records, identified by the request's Id or
+// UserId field; holders of Permission may act on anyone's.
+type UserRule struct {
+	Self       bool
+	Permission string
+}
+
+// UserTokenVerifier validates an end user's token. ErrInvalidUserToken, or
+// any error wrapping it, is reported to the caller as Unauthenticated; other
+// errors as Internal.
+type UserTokenVerifier func(ctx context.Context, token string) (*token.Claims, error)
+
+var ErrInvalidUserToken = apperr.New(apperr.Unauthenticated, "invalid user token")
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the end user's claims set by
+// UserUnaryServerInterceptor.
+func ClaimsFromContext(ctx context.Context) (*token.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*token.Claims)
+	return claims, ok
+}
+
+// UserUnaryServerInterceptor authenticates the end user of the methods listed
+// in rules and enforces their rule. Methods without a rule do not act on
+// behalf of a user and are passed through untouched.
+func UserUnaryServerInterceptor(rules map[string]UserRule, verify UserTokenVerifier, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rule, ok := rules[path.Base(info.FullMethod)]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		raw := userToken(ctx)
+		if raw == "" {
+			return nil, status.Error(codes.Unauthenticated, "user token is required")
+		}
+		claims, err := verify(ctx, raw)
+		if errors.Is(err, ErrInvalidUserToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid user token")
+		}
+		if err != nil {
+			logger.Error("User token verification error", "method", info.FullMethod, "error", err)
+			return nil, status.Error(codes.Internal, "error verifying user token")
+		}
+
+		if !rule.allows(claims, req) {
+			logger.Warn("User call denied", "method", info.FullMethod, "user_id", claims.ID)
+			return nil, status.Errorf(codes.PermissionDenied, "not allowed to call %s for this user", info.FullMethod)
+		}
+
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+func (r UserRule) allows(claims *token.Claims, req interface{}) bool {
+	if r.Self && claims.ID != "" && targetUserID(req) == claims.ID {
+		return true
+	}
+	return r.Permission != "" && claims.HasPermission(r.Permission)
+}
+
+// targetUserID returns the user a request acts on. Requests name it either Id
+// or UserId.
+func targetUserID(req interface{}) string {
+	switch r := req.(type) {
+	case interface{ GetUserId() string }:
+		return r.GetUserId()
+	case interface{ GetId() string }:
+		return r.GetId()
+	}
+	return ""
+}
+
+func userToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get(UserTokenMetadataKey) {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return token
+		}
+	}
+	return ""
+}
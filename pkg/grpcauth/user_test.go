@@ -0,0 +1,85 @@
+package grpcauth
+
+import (
+	"auth-service/api/token"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type userIDReq struct{ UserId string }
+
+func (r *userIDReq) GetUserId() string { return r.UserId }
+
+type idReq struct{ Id string }
+
+func (r *idReq) GetId() string { return r.Id }
+
+func TestUserUnaryServerInterceptor(t *testing.T) {
+	rules := map[string]UserRule{
+		"GetUserProfile": {Self: true, Permission: "users:read"},
+		"GetUsersList":   {Permission: "users:read"},
+	}
+	verify := func(ctx context.Context, raw string) (*token.Claims, error) {
+		switch raw {
+		case "user":
+			return &token.Claims{ID: "user-1"}, nil
+		case "admin":
+			return &token.Claims{ID: "admin-1", Permissions: []string{"users:read"}}, nil
+		case "broken":
+			return nil, errors.New("redis is down")
+		}
+		return nil, ErrInvalidUserToken
+	}
+	interceptor := UserUnaryServerInterceptor(rules, verify, slog.Default())
+
+	call := func(ctx context.Context, method string, req interface{}) (*token.Claims, error) {
+		var claims *token.Claims
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/auth_service.AuthService/" + method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				claims, _ = ClaimsFromContext(ctx)
+				return nil, nil
+			})
+		return claims, err
+	}
+	withToken := func(raw string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(UserTokenMetadataKey, "Bearer "+raw))
+	}
+
+	_, err := call(context.Background(), "GetUserProfile", &idReq{Id: "user-1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = call(withToken("bad"), "GetUserProfile", &idReq{Id: "user-1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = call(withToken("broken"), "GetUserProfile", &idReq{Id: "user-1"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	claims, err := call(withToken("user"), "GetUserProfile", &idReq{Id: "user-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", claims.ID)
+
+	_, err = call(withToken("user"), "GetUserProfile", &userIDReq{UserId: "user-2"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	claims, err = call(withToken("admin"), "GetUserProfile", &userIDReq{UserId: "user-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin-1", claims.ID)
+
+	_, err = call(withToken("user"), "GetUsersList", &idReq{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = call(withToken("admin"), "GetUsersList", &idReq{})
+	assert.NoError(t, err)
+
+	claims, err = call(context.Background(), "ValidateToken", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, claims)
+}
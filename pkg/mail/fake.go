@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake keeps sent messages in memory so tests can inspect them.
+type Fake struct {
+	mu   sync.Mutex
+	sent []Message
+	// Err, when set, is returned by Send instead of recording the message.
+	Err error
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Send(ctx context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return f.Err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of the messages sent so far.
+func (f *Fake) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Message(nil), f.sent...)
+}
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+type fileMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+// NewFileMailer writes every message to w instead of delivering it. It is
+// meant for local development, where w is usually stdout or a log file.
+func NewFileMailer(w io.Writer, from string) Mailer {
+	return &fileMailer{w: w, from: from}
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	data, err := encode(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := io.WriteString(m.w, "-----\r\n"); err != nil {
+		return err
+	}
+	_, err = m.w.Write(data)
+	return err
+}
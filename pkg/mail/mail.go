@@ -0,0 +1,138 @@
+// Package mail delivers the emails the auth service sends to its users.
+package mail
+
+import (
+	"auth-service/config"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is sent as multipart/alternative with a plain text and an HTML
+// part; Text may be empty.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Mailer delivers a message. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the Mailer selected by cfg.MAIL_DRIVER: "smtp", "file" or
+// "stdout".
+func New(cfg *config.Config) (Mailer, error) {
+	switch cfg.MAIL_DRIVER {
+	case "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			Host:     cfg.SMTP_HOST,
+			Port:     cfg.SMTP_PORT,
+			Username: cfg.SMTP_USERNAME,
+			Password: cfg.SMTP_PASSWORD,
+			TLS:      cfg.SMTP_TLS,
+			From:     cfg.MAIL_FROM,
+		})
+	case "file":
+		f, err := os.OpenFile(cfg.MAIL_FILE_PATH, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return NewFileMailer(f, cfg.MAIL_FROM), nil
+	case "stdout":
+		return NewFileMailer(os.Stdout, cfg.MAIL_FROM), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.MAIL_DRIVER)
+	}
+}
+
+// encode renders msg as an RFC 5322 message.
+func encode(from string, msg Message) ([]byte, error) {
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, fmt.Errorf("invalid subject")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: %s\r\n", messageID(from))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.Text == "" {
+		writeSinglePart(&buf, "text/html", msg.HTML)
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+
+	// Clients show the last part they understand, so HTML goes last.
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		w, err := parts.CreatePart(partHeader(part.contentType))
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(w, part.body)
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func partHeader(contentType string) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=\"UTF-8\"")
+	header.Set("Content-Transfer-Encoding", "base64")
+	return header
+}
+
+func writeSinglePart(buf *bytes.Buffer, contentType string, body string) {
+	fmt.Fprintf(buf, "Content-Type: %s; charset=\"UTF-8\"\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	writeBase64(buf, body)
+}
+
+func writeBase64(w io.Writer, body string) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewFileMailer(&buf, "Personal Finance Tracker <no-reply@example.com>")
+
+	msg, err := VerificationEmail("test_email@test.com", "ru", "https://example.com/verify?token=abc")
+	assert.NoError(t, err)
+	assert.NoError(t, mailer.Send(context.Background(), msg))
+
+	parsed, err := mail.ReadMessage(strings.NewReader(strings.TrimPrefix(buf.String(), "-----\r\n")))
+	assert.NoError(t, err)
+	assert.Equal(t, "test_email@test.com", parsed.Header.Get("To"))
+	assert.Contains(t, parsed.Header.Get("Message-ID"), "@example.com>")
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Подтвердите адрес электронной почты", subject)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	var contentTypes []string
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		contentTypes = append(contentTypes, part.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), "https://example.com/verify?token=abc")
+	}
+	assert.Equal(t, []string{`text/plain; charset="UTF-8"`, `text/html; charset="UTF-8"`}, contentTypes)
+}
+
+func TestEncodeRejectsHeaderInjection(t *testing.T) {
+	_, err := encode("no-reply@example.com", Message{To: "test_email@test.com\r\nBcc: other@test.com"})
+	assert.Error(t, err)
+
+	_, err = encode("no-reply@example.com", Message{To: "test_email@test.com", Subject: "Hi\r\nBcc: other@test.com"})
+	assert.Error(t, err)
+}
+
+func TestNewSMTPMailer(t *testing.T) {
+	_, err := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", Port: 587, TLS: "ssl", From: "no-reply@example.com"})
+	assert.Error(t, err)
+
+	_, err = NewSMTPMailer(SMTPConfig{Port: 587, TLS: TLSStartTLS, From: "no-reply@example.com"})
+	assert.Error(t, err)
+
+	_, err = NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", Port: 465, TLS: TLSImplicit, From: "no-reply@example.com"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,129 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// TLS modes for SMTPConfig.TLS.
+const (
+	TLSStartTLS = "starttls"
+	TLSImplicit = "tls"
+	TLSNone     = "none"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// TLS is TLSStartTLS (upgrade a plain connection, usually port 587),
+	// TLSImplicit (TLS from the first byte, usually port 465) or TLSNone,
+	// which is only meant for a local relay.
+	TLS  string
+	From string
+}
+
+type smtpMailer struct {
+	cfg  SMTPConfig
+	from string
+}
+
+func NewSMTPMailer(cfg SMTPConfig) (Mailer, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("SMTP host is not configured")
+	}
+	switch cfg.TLS {
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", cfg.TLS)
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	return &smtpMailer{cfg: cfg, from: from.Address}, nil
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	data, err := encode(m.cfg.From, msg)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// net/smtp does not take a context; closing the connection unblocks it.
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	if m.cfg.TLS == TLSStartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return fmt.Errorf("STARTTLS: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *smtpMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if m.cfg.TLS == TLSImplicit {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: m.cfg.Host}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
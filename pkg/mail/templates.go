@@ -0,0 +1,215 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	texttemplate "text/template"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Templates of the transactional emails. Each one has an HTML and a plain
+// text variant in templates/ and its strings in locales/<locale>.json under
+// the template's name.
+const (
+	TemplatePasswordReset   = "password_reset"
+	TemplateVerification    = "verification"
+	TemplateNewDeviceLogin  = "new_device_login"
+	TemplateEmailChange     = "email_change"
+	TemplateAccountDeletion = "account_deletion"
+	TemplateGroupInvitation = "group_invitation"
+	TemplateDataExport      = "data_export"
+)
+
+// DefaultLocale is used when neither the user nor the request asks for a
+// supported locale.
+const DefaultLocale = "en"
+
+// SupportedLocales lists the locales with a catalog, DefaultLocale first.
+var SupportedLocales = []string{"en", "ru", "uz"}
+
+var (
+	//go:embed templates/*.html templates/*.txt
+	templatesFS embed.FS
+	//go:embed locales/*.json
+	localesFS embed.FS
+
+	htmlTemplates = map[string]*htmltemplate.Template{}
+	textTemplates = map[string]*texttemplate.Template{}
+	// catalogs maps locale to template name to key to translated string.
+	catalogs = map[string]map[string]map[string]string{}
+
+	localeMatcher language.Matcher
+)
+
+func init() {
+	layout := htmltemplate.Must(htmltemplate.ParseFS(templatesFS, "templates/layout.html"))
+
+	for _, name := range []string{TemplatePasswordReset, TemplateVerification, TemplateNewDeviceLogin, TemplateEmailChange, TemplateAccountDeletion, TemplateGroupInvitation, TemplateDataExport} {
+		htmlTemplates[name] = htmltemplate.Must(htmltemplate.Must(layout.Clone()).ParseFS(templatesFS, "templates/"+name+".html"))
+		textTemplates[name] = texttemplate.Must(texttemplate.ParseFS(templatesFS, "templates/"+name+".txt"))
+	}
+
+	tags := make([]language.Tag, len(SupportedLocales))
+	for i, locale := range SupportedLocales {
+		data, err := localesFS.ReadFile(path.Join("locales", locale+".json"))
+		if err != nil {
+			panic(err)
+		}
+		var catalog map[string]map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("locales/%s.json: %v", locale, err))
+		}
+		catalogs[locale] = catalog
+		tags[i] = language.Make(locale)
+	}
+	localeMatcher = language.NewMatcher(tags)
+}
+
+// IsSupportedLocale reports whether there is a catalog for locale.
+func IsSupportedLocale(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// ResolveLocale picks the locale of an email: the user's saved locale when it
+// is supported, otherwise the best match for an Accept-Language header, and
+// DefaultLocale when nothing matches.
+func ResolveLocale(userLocale string, acceptLanguage string) string {
+	if IsSupportedLocale(userLocale) {
+		return userLocale
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, i, confidence := localeMatcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return SupportedLocales[i]
+}
+
+func PasswordResetEmail(to string, locale string, code string) (Message, error) {
+	return Render(TemplatePasswordReset, locale, to, struct {
+		Code string
+	}{
+		Code: code,
+	})
+}
+
+// VerificationEmail carries the link that activates a newly registered
+// account.
+func VerificationEmail(to string, locale string, link string) (Message, error) {
+	return Render(TemplateVerification, locale, to, struct {
+		Link string
+	}{
+		Link: link,
+	})
+}
+
+// NewDeviceLoginEmail warns the user about a sign-in from a device the
+// account was not used on before.
+func NewDeviceLoginEmail(to string, locale string, device string, ipAddress string, at time.Time) (Message, error) {
+	return Render(TemplateNewDeviceLogin, locale, to, struct {
+		Device    string
+		IPAddress string
+		Time      string
+	}{
+		Device:    device,
+		IPAddress: ipAddress,
+		Time:      at.UTC().Format("2006-01-02 15:04 MST"),
+	})
+}
+
+// EmailChangeEmail is sent to the new address and carries the link that
+// confirms the change.
+func EmailChangeEmail(to string, locale string, newEmail string, link string) (Message, error) {
+	return Render(TemplateEmailChange, locale, to, struct {
+		NewEmail string
+		Link     string
+	}{
+		NewEmail: newEmail,
+		Link:     link,
+	})
+}
+
+func AccountDeletionEmail(to string, locale string, deleteAt time.Time) (Message, error) {
+	return Render(TemplateAccountDeletion, locale, to, struct {
+		Date string
+	}{
+		Date: deleteAt.UTC().Format("2006-01-02"),
+	})
+}
+
+// GroupInvitationEmail tells the invitee who invited them to which group and
+// with which role; the invitation is answered after signing in.
+func GroupInvitationEmail(to string, locale string, inviter string, groupName string, role string, expiresAt time.Time) (Message, error) {
+	return Render(TemplateGroupInvitation, locale, to, struct {
+		Inviter   string
+		GroupName string
+		Role      string
+		Date      string
+	}{
+		Inviter:   inviter,
+		GroupName: groupName,
+		Role:      role,
+		Date:      expiresAt.UTC().Format("2006-01-02"),
+	})
+}
+
+// DataExportEmail carries the signed link to a finished personal data export.
+func DataExportEmail(to string, locale string, link string, expiresAt time.Time) (Message, error) {
+	return Render(TemplateDataExport, locale, to, struct {
+		Link string
+		Date string
+	}{
+		Link: link,
+		Date: expiresAt.UTC().Format("2006-01-02 15:04 MST"),
+	})
+}
+
+// Render builds the message for template name in locale. Unsupported locales
+// fall back to DefaultLocale.
+func Render(name string, locale string, to string, data interface{}) (Message, error) {
+	htmlTemplate, ok := htmlTemplates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+	if !IsSupportedLocale(locale) {
+		locale = DefaultLocale
+	}
+
+	texts := make(map[string]string)
+	for k, v := range catalogs[locale]["common"] {
+		texts[k] = v
+	}
+	for k, v := range catalogs[locale][name] {
+		texts[k] = v
+	}
+	view := struct {
+		Locale string
+		T      map[string]string
+		D      interface{}
+	}{
+		Locale: locale,
+		T:      texts,
+		D:      data,
+	}
+
+	var html, text bytes.Buffer
+	if err := htmlTemplate.ExecuteTemplate(&html, "layout.html", view); err != nil {
+		return Message{}, err
+	}
+	if err := textTemplates[name].Execute(&text, view); err != nil {
+		return Message{}, err
+	}
+
+	return Message{To: to, Subject: texts["subject"], HTML: html.String(), Text: text.String()}, nil
+}
@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, locale := range SupportedLocales {
+		for name, texts := range catalogs[DefaultLocale] {
+			for key := range texts {
+				assert.NotEmpty(t, catalogs[locale][name][key], "%s: %s.%s is missing", locale, name, key)
+			}
+		}
+	}
+}
+
+func TestRenderAllTemplates(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	for _, locale := range SupportedLocales {
+		messages := []func() (Message, error){
+			func() (Message, error) { return PasswordResetEmail("test_email@test.com", locale, "123456") },
+			func() (Message, error) {
+				return VerificationEmail("test_email@test.com", locale, "https://example.com/v")
+			},
+			func() (Message, error) {
+				return NewDeviceLoginEmail("test_email@test.com", locale, "Firefox on Linux", "203.0.113.7", at)
+			},
+			func() (Message, error) {
+				return EmailChangeEmail("new_email@test.com", locale, "new_email@test.com", "https://example.com/c")
+			},
+			func() (Message, error) { return AccountDeletionEmail("test_email@test.com", locale, at) },
+			func() (Message, error) {
+				return GroupInvitationEmail("test_email@test.com", locale, "owner@test.com", "Home", "viewer", at)
+			},
+			func() (Message, error) {
+				return DataExportEmail("test_email@test.com", locale, "https://example.com/e", at)
+			},
+		}
+
+		for _, render := range messages {
+			msg, err := render()
+			assert.NoError(t, err)
+			assert.NotEmpty(t, msg.Subject)
+			assert.Contains(t, msg.HTML, `lang="`+locale+`"`)
+			assert.NotContains(t, msg.Text, "<no value>")
+			assert.NotEmpty(t, msg.Text)
+		}
+	}
+
+	msg, err := NewDeviceLoginEmail("test_email@test.com", "uz", "Firefox on Linux", "203.0.113.7", at)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hisobingizga yangi kirish", msg.Subject)
+	assert.Contains(t, msg.Text, "203.0.113.7")
+	assert.Contains(t, msg.Text, "2024-05-01 12:30 UTC")
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	msg, err := EmailChangeEmail("new_email@test.com", "en", "<script>@test.com", "https://example.com/c")
+	assert.NoError(t, err)
+	assert.NotContains(t, msg.HTML, "<script>")
+}
+
+func TestResolveLocale(t *testing.T) {
+	assert.Equal(t, "ru", ResolveLocale("ru", "uz-UZ,uz;q=0.9"))
+	assert.Equal(t, "uz", ResolveLocale("", "uz-UZ,uz;q=0.9,ru;q=0.8"))
+	assert.Equal(t, "ru", ResolveLocale("", "de-DE,ru;q=0.7,en;q=0.5"))
+	assert.Equal(t, "en", ResolveLocale("de", "fr-FR"))
+	assert.Equal(t, "en", ResolveLocale("", ""))
+	assert.Equal(t, "en", ResolveLocale("", "not a header;;"))
+}
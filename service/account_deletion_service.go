@@ -0,0 +1,119 @@
+package service
+
+import (
+	"auth-service/api/token"
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/mail"
+	"auth-service/storage"
+	"auth-service/storage/postgres"
+	"context"
+	"time"
+)
+
+// RequestAccountDeletion schedules the deletion of the caller's account after
+// ACCOUNT_DELETION_GRACE_PERIOD. The password, and a second factor when one is
+// enabled, has to be confirmed. Every session and personal access token is
+// revoked right away; signing in again before the date cancels the deletion.
+func (s *authServiceImpl) RequestAccountDeletion(ctx context.Context, claims *token.Claims, req models.DeleteAccountReq, client models.ClientInfo, acceptLanguage string) (*models.AccountDeletionResp, error) {
+	hash, err := s.storage.UserRepository().GetPasswordHash(ctx, claims.ID)
+	if err != nil {
+		s.logger.Error("GetPasswordHash error", "error", err)
+		return nil, err
+	}
+	if !token.VerifyPassword(req.Password, hash) {
+		return nil, ErrIncorrectPassword
+	}
+
+	enabled, err := s.IsMFAEnabled(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		if req.MFACode == "" {
+			return nil, ErrMFARequired
+		}
+		if err := s.verifyMFACode(ctx, claims.ID, req.MFACode); err != nil {
+			return nil, err
+		}
+	}
+
+	// The deletion is only scheduled together with the revocations, so that
+	// an account is never left scheduled with sessions that still work.
+	deleteAt := time.Now().Add(config.Load().ACCOUNT_DELETION_GRACE_PERIOD)
+	var sessionIDs []string
+	err = s.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if _, err := tx.AccountDeletionRepository().ScheduleDeletion(ctx, claims.ID, deleteAt); err != nil {
+			return err
+		}
+		var err error
+		sessionIDs, err = revokeUserTokens(ctx, tx, claims.ID)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Schedule account deletion error", "error", err)
+		return nil, err
+	}
+	if err := revokeSessionTokens(ctx, s.storage, sessionIDs); err != nil {
+		s.logger.Error("revokeSessionTokens error", "error", err)
+		return nil, err
+	}
+
+	s.recordClientAuditEvent(ctx, claims.ID, postgres.AuditAccountDeletionRequested, client, map[string]string{
+		"delete_at": deleteAt.UTC().Format(time.RFC3339),
+	})
+
+	// The deletion stands even if the notice cannot be queued.
+	msg, err := mail.AccountDeletionEmail(claims.Email, s.emailLocale(ctx, claims.Email, acceptLanguage), deleteAt)
+	if err != nil {
+		s.logger.Error("AccountDeletionEmail error", "error", err)
+	} else {
+		_ = s.sendEmail(ctx, msg)
+	}
+
+	return &models.AccountDeletionResp{
+		Status:   "success",
+		Message:  "Account scheduled for deletion, sign in again before then to keep it",
+		DeleteAt: deleteAt.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// cancelAccountDeletion is called in the transaction of every sign-in, so
+// that a session is never opened for an account that stays scheduled for
+// deletion.
+func (s *authServiceImpl) cancelAccountDeletion(ctx context.Context, st storage.IStorage, userID string, client models.ClientInfo) error {
+	cancelled, err := st.AccountDeletionRepository().CancelDeletion(ctx, userID)
+	if err != nil {
+		s.logger.Error("CancelDeletion error", "error", err)
+		return err
+	}
+	if cancelled {
+		_, err = st.AuditRepository().RecordEvent(ctx, clientAuditEvent(userID, postgres.AuditAccountDeletionCancelled, client, nil))
+	}
+	return err
+}
+
+// revokeUserTokens revokes every session and personal access token of the
+// user in Postgres. It returns the IDs of the revoked sessions, whose access
+// tokens are revoked with revokeSessionTokens once st is committed.
+func revokeUserTokens(ctx context.Context, st storage.IStorage, userID string) ([]string, error) {
+	ids, err := st.SessionRepository().RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := st.PersonalTokenRepository().RevokeUserTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// revokeSessionTokens makes the access tokens of the sessions stop working.
+func revokeSessionTokens(ctx context.Context, st storage.IStorage, ids []string) error {
+	for _, id := range ids {
+		if _, err := st.RedisStore().RevokeSession(ctx, id, token.AccessTokenTTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/storage"
+	"auth-service/storage/postgres"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// AccountDeletionWorker carries out the account deletions whose grace period
+// has passed: it revokes what is left of the user's tokens and anonymizes the
+// account, which emits user.deleted. After a failure the deletion is retried.
+// Several workers, also in different processes, can run against the same
+// database.
+type AccountDeletionWorker struct {
+	storage storage.IStorage
+	logger  *slog.Logger
+}
+
+func NewAccountDeletionWorker(storage storage.IStorage, logger *slog.Logger) *AccountDeletionWorker {
+	return &AccountDeletionWorker{storage: storage, logger: logger}
+}
+
+// Run looks for due deletions every ACCOUNT_DELETION_POLL_INTERVAL until ctx
+// is cancelled.
+func (w *AccountDeletionWorker) Run(ctx context.Context) {
+	cfg := config.Load()
+
+	ticker := time.NewTicker(cfg.ACCOUNT_DELETION_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx, cfg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *AccountDeletionWorker) drain(ctx context.Context, cfg *config.Config) {
+	ids, err := w.storage.AccountDeletionRepository().GetDueDeletions(ctx, cfg.ACCOUNT_DELETION_BATCH_SIZE)
+	if err != nil {
+		w.logger.Error("GetDueDeletions error", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := w.delete(ctx, id); err != nil {
+			w.logger.Error("Account deletion failed", "error", err, "user_id", id)
+		}
+	}
+}
+
+// errDeletionCancelled rolls back the revocations of a deletion that was
+// cancelled by signing in after it was picked up.
+var errDeletionCancelled = errors.New("account deletion cancelled")
+
+func (w *AccountDeletionWorker) delete(ctx context.Context, userID string) error {
+	var sessionIDs []string
+	err := w.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if sessionIDs, err = revokeUserTokens(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		anonymized, err := tx.AccountDeletionRepository().AnonymizeUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !anonymized {
+			return errDeletionCancelled
+		}
+		return nil
+	})
+	if errors.Is(err, errDeletionCancelled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := revokeSessionTokens(ctx, w.storage, sessionIDs); err != nil {
+		return err
+	}
+
+	recordAudit(ctx, w.storage, w.logger, models.AuditEvent{
+		UserID:    userID,
+		EventType: postgres.AuditAccountDeleted,
+	})
+	w.logger.Info("Account deleted", "user_id", userID)
+	return nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/storage"
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditEventsLimit = 50
+	maxAuditEventsLimit     = 500
+	maxAuditExportRows      = 10000
+)
+
+var ErrInvalidAuditFilter = apperr.New(apperr.InvalidArgument, "invalid audit event filter")
+
+func (s *authServiceImpl) recordAuditEvent(ctx context.Context, userID string, eventType string, metadata map[string]string) {
+	recordAudit(ctx, s.storage, s.logger, models.AuditEvent{
+		UserID:    userID,
+		EventType: eventType,
+		Metadata:  metadata,
+	})
+}
+
+// recordClientAuditEvent records an event caused by a request of client.
+func (s *authServiceImpl) recordClientAuditEvent(ctx context.Context, userID string, eventType string, client models.ClientInfo, metadata map[string]string) {
+	recordAudit(ctx, s.storage, s.logger, clientAuditEvent(userID, eventType, client, metadata))
+}
+
+// clientAuditEvent is the event recordClientAuditEvent records. Operations
+// that write it in their own transaction use it directly, so that a failure
+// rolls them back.
+func clientAuditEvent(userID string, eventType string, client models.ClientInfo, metadata map[string]string) models.AuditEvent {
+	return models.AuditEvent{
+		UserID:    userID,
+		EventType: eventType,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Metadata:  metadata,
+	}
+}
+
+// recordAudit appends event to the audit log. A failure is logged but does
+// not fail the operation being audited.
+func recordAudit(ctx context.Context, st storage.IStorage, logger *slog.Logger, event models.AuditEvent) {
+	_, err := st.AuditRepository().RecordEvent(ctx, event)
+	if err != nil {
+		logger.Error("RecordEvent error", "error", err, "event_type", event.EventType)
+	}
+}
+
+// ListAuditEvents returns a page of the audit log. A zero limit means the
+// default page size.
+func (s *authServiceImpl) ListAuditEvents(ctx context.Context, filter models.AuditEventFilter) (*models.AuditEventsList, error) {
+	return listAuditEvents(ctx, s.storage, s.logger, filter)
+}
+
+// ExportAuditEvents returns up to maxAuditExportRows events matching filter,
+// ignoring its limit and offset. Total tells whether the export is complete.
+func (s *authServiceImpl) ExportAuditEvents(ctx context.Context, filter models.AuditEventFilter) (*models.AuditEventsList, error) {
+	filter.Limit, filter.Offset = maxAuditExportRows, 0
+	return queryAuditEvents(ctx, s.storage, s.logger, filter)
+}
+
+func listAuditEvents(ctx context.Context, st storage.IStorage, logger *slog.Logger, filter models.AuditEventFilter) (*models.AuditEventsList, error) {
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditEventsLimit
+	}
+	if filter.Limit > maxAuditEventsLimit {
+		return nil, ErrInvalidAuditFilter.WithDetail("field", "limit")
+	}
+	return queryAuditEvents(ctx, st, logger, filter)
+}
+
+func queryAuditEvents(ctx context.Context, st storage.IStorage, logger *slog.Logger, filter models.AuditEventFilter) (*models.AuditEventsList, error) {
+	if err := validateAuditFilter(filter); err != nil {
+		return nil, err
+	}
+
+	events, err := st.AuditRepository().ListEvents(ctx, filter)
+	if err != nil {
+		logger.Error("ListEvents error", "error", err)
+		return nil, err
+	}
+	total, err := st.AuditRepository().CountEvents(ctx, filter)
+	if err != nil {
+		logger.Error("CountEvents error", "error", err)
+		return nil, err
+	}
+	return &models.AuditEventsList{Events: events, Total: total}, nil
+}
+
+// validateAuditFilter rejects filters the database could not compare, naming
+// the offending field in the error details.
+func validateAuditFilter(filter models.AuditEventFilter) error {
+	for _, id := range [][2]string{{"user_id", filter.UserID}, {"actor_id", filter.ActorID}} {
+		if id[1] == "" {
+			continue
+		}
+		if _, err := uuid.Parse(id[1]); err != nil {
+			return ErrInvalidAuditFilter.WithDetail("field", id[0])
+		}
+	}
+	for _, bound := range [][2]string{{"from", filter.From}, {"to", filter.To}} {
+		if bound[1] == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, bound[1]); err != nil {
+			return ErrInvalidAuditFilter.WithDetail("field", bound[0])
+		}
+	}
+	if filter.Limit <= 0 {
+		return ErrInvalidAuditFilter.WithDetail("field", "limit")
+	}
+	if filter.Offset < 0 {
+		return ErrInvalidAuditFilter.WithDetail("field", "offset")
+	}
+	return nil
+}
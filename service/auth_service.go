@@ -1,27 +1,120 @@
 package service
 
 import (
+	"auth-service/api/token"
+	"auth-service/config"
 	"auth-service/models"
+	"auth-service/pkg/apperr"
 	"auth-service/storage"
+	"auth-service/storage/postgres"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCredentials  = apperr.New(apperr.Unauthenticated, "invalid email or password")
+	ErrInvalidRefreshToken = apperr.New(apperr.Unauthenticated, "invalid refresh token")
+	ErrRefreshTokenReused  = apperr.New(apperr.Unauthenticated, "refresh token reuse detected")
+	ErrInvalidClient       = apperr.New(apperr.Unauthenticated, "invalid client")
+	ErrUnauthorizedClient  = apperr.New(apperr.PermissionDenied, "client is not allowed to use this grant type")
+	ErrInvalidScope        = apperr.New(apperr.InvalidArgument, "requested scope is not allowed")
+	ErrInvalidRedirectURI  = apperr.New(apperr.InvalidArgument, "redirect_uri is not registered for this client")
+	ErrInvalidRequest      = apperr.New(apperr.InvalidArgument, "invalid authorization request")
+	ErrInvalidGrant        = apperr.New(apperr.InvalidArgument, "invalid authorization code")
 )
 
+const authorizationCodeTTL = time.Minute
+
 type AuthService interface {
-	RegisterUser(user models.RegisterUser) (*models.Response, error)
-	EmailExists(email string) (bool, error)
-	LoginUser(login models.LoginUserReq) (*models.User, error)
-	DeleteUser(id string) (*models.Response, error)
-	ResetPassword(reset models.ResetPassword) (*models.Response, error)
-	SaveRefreshToken(refreshToken models.RefreshToken) (*models.Response, error)
-	InvalidateRefreshToken(email string) (*models.Response, error)
-	IsRefreshTokenValid(email string) (bool, error)
-	UpdateUserRoles(manage models.ManageUserRoles) (*models.Response, error)
-
-	AddTokenBlacklist(token string, expirationTime time.Duration) (*models.Response, error)
-	IsTokenBlacklisted(token string) (bool, error)
-	StoreCode(email, code string, expirationTime time.Duration) (*models.Response, error)
-	IsCodeValid(email, code string) (bool, error)
+	RegisterUser(ctx context.Context, user models.RegisterUser) (*models.Response, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	LoginUser(ctx context.Context, login models.LoginUserReq, client models.ClientInfo) (*models.User, error)
+	LogOut(ctx context.Context, claims *token.Claims, client models.ClientInfo) (*models.Response, error)
+	RequestAccountDeletion(ctx context.Context, claims *token.Claims, req models.DeleteAccountReq, client models.ClientInfo, acceptLanguage string) (*models.AccountDeletionResp, error)
+	RequestDataExport(ctx context.Context, userID string, client models.ClientInfo) (*models.DataExport, error)
+	GetDataExport(ctx context.Context, userID string, id string) (*models.DataExport, error)
+	OpenDataExport(ctx context.Context, id string, expires string, signature string, client models.ClientInfo) (*models.DataExport, error)
+	ResetPassword(ctx context.Context, reset models.ResetPassword, client models.ClientInfo) (*models.Response, error)
+	UpdateUserRoles(ctx context.Context, manage models.ManageUserRoles, adminID string, client models.ClientInfo) (*models.Response, error)
+	ListRoles(ctx context.Context) (*models.RolesList, error)
+	ListAuditEvents(ctx context.Context, filter models.AuditEventFilter) (*models.AuditEventsList, error)
+	ExportAuditEvents(ctx context.Context, filter models.AuditEventFilter) (*models.AuditEventsList, error)
+
+	StartSession(ctx context.Context, user *models.User, client models.ClientInfo) (*models.LoginUserResp, error)
+	RefreshSession(ctx context.Context, refreshToken string, client models.ClientInfo) (*models.LoginUserResp, error)
+	GetUserSessions(ctx context.Context, userID string, currentID string) (*models.SessionsList, error)
+	RevokeSession(ctx context.Context, userID string, sessionID string) (*models.Response, error)
+	RevokeOtherSessions(ctx context.Context, userID string, currentID string) (*models.Response, error)
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+
+	GetUserInfo(ctx context.Context, userID string) (*models.UserInfo, error)
+	AuthenticateClient(ctx context.Context, clientID string, clientSecret string) (*models.OAuthClient, error)
+	IssueClientToken(client *models.OAuthClient, scope string) (*models.TokenResp, error)
+	IssueIDToken(ctx context.Context, userID string, audience string, nonce string) (string, error)
+	ValidateAuthorizeRequest(ctx context.Context, req models.AuthorizeReq) (*models.OAuthClient, error)
+	CreateAuthorizationCode(ctx context.Context, client *models.OAuthClient, req models.AuthorizeReq, userID string) (string, error)
+	ExchangeAuthorizationCode(ctx context.Context, client *models.OAuthClient, req models.TokenReq, info models.ClientInfo) (*models.LoginUserResp, *models.AuthorizationCode, error)
+
+	CreateEmailVerification(ctx context.Context, email string) (string, error)
+	VerifyEmail(ctx context.Context, verificationToken string) (*models.Response, error)
+	ConfirmEmailChange(ctx context.Context, changeToken string) (*models.Response, error)
+	ResendVerification(ctx context.Context, email string) (string, error)
+
+	IsMFAEnabled(ctx context.Context, userID string) (bool, error)
+	StartMFAChallenge(userID string) (*models.MFAPendingResp, error)
+	CompleteMFA(ctx context.Context, mfaToken string, code string) (*models.User, error)
+	GetMFAStatus(ctx context.Context, userID string) (*models.MFAStatus, error)
+	EnrollTOTP(ctx context.Context, userID string, email string) (*models.TOTPEnrollResp, error)
+	ConfirmTOTP(ctx context.Context, userID string, code string) (*models.RecoveryCodesResp, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID string, code string) (*models.RecoveryCodesResp, error)
+	ResetMFA(ctx context.Context, userID string, adminID string) (*models.Response, error)
+
+	BeginWebAuthnRegistration(ctx context.Context, userID string) (*models.WebAuthnBeginResp, error)
+	FinishWebAuthnRegistration(ctx context.Context, userID string, sessionID string, name string, body io.Reader) (*models.WebAuthnCredential, error)
+	BeginWebAuthnLogin(ctx context.Context, email string) (*models.WebAuthnBeginResp, error)
+	FinishWebAuthnLogin(ctx context.Context, sessionID string, body io.Reader) (*models.User, error)
+
+	CheckThrottle(ctx context.Context, action string, email string, ip string) (time.Duration, error)
+	RecordFailure(ctx context.Context, action string, email string, ip string) (time.Duration, error)
+	ClearFailures(ctx context.Context, action string, email string, ip string)
+	UnlockAccount(ctx context.Context, email string, adminID string) (*models.Response, error)
+
+	SendPasswordResetEmail(ctx context.Context, email string, code string, acceptLanguage string) error
+	SendVerificationEmail(ctx context.Context, email string, link string, acceptLanguage string) error
+	GetEmailMessage(ctx context.Context, id string) (*models.EmailMessage, error)
+	ListEmailMessages(ctx context.Context, status string, limit int, offset int) (*models.EmailMessagesList, error)
+	RedriveEmailMessage(ctx context.Context, id string) (*models.Response, error)
+
+	CreateGroup(ctx context.Context, userID string, name string) (*models.Group, error)
+	ListGroups(ctx context.Context, userID string) (*models.GroupsList, error)
+	GetGroup(ctx context.Context, userID string, groupID string) (*models.Group, error)
+	DeleteGroup(ctx context.Context, userID string, groupID string) (*models.Response, error)
+	InviteGroupMember(ctx context.Context, userID string, inviterEmail string, groupID string, invite models.InviteGroupMemberReq, acceptLanguage string) (*models.GroupInvitation, error)
+	ListGroupInvitations(ctx context.Context, email string) (*models.GroupInvitationsList, error)
+	RespondToGroupInvitation(ctx context.Context, userID string, email string, invitationID string, accept bool) (*models.GroupInvitation, error)
+	UpdateGroupMember(ctx context.Context, userID string, groupID string, memberID string, role string) (*models.Response, error)
+	RemoveGroupMember(ctx context.Context, userID string, groupID string, memberID string) (*models.Response, error)
+
+	CreatePersonalToken(ctx context.Context, userID string, req models.CreatePersonalTokenReq) (*models.PersonalTokenCreated, error)
+	ListPersonalTokens(ctx context.Context, userID string) (*models.PersonalTokensList, error)
+	RevokePersonalToken(ctx context.Context, userID string, id string) (*models.Response, error)
+	AuthenticatePersonalToken(ctx context.Context, raw string) (*token.Claims, error)
+
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	StoreCode(ctx context.Context, email, code string, expirationTime time.Duration) (*models.Response, error)
+	IsCodeValid(ctx context.Context, email, code string) (bool, error)
 }
 
 type authServiceImpl struct {
@@ -36,8 +129,15 @@ func NewAuthService(storage storage.IStorage, logger *slog.Logger) AuthService {
 	}
 }
 
-func (s *authServiceImpl) RegisterUser(user models.RegisterUser) (*models.Response, error) {
-	resp, err := s.storage.AuthRepository().RegisterUser(user)
+func (s *authServiceImpl) RegisterUser(ctx context.Context, user models.RegisterUser) (*models.Response, error) {
+	hash, err := token.HashPassword(user.Password)
+	if err != nil {
+		s.logger.Error("HashPassword error", "error", err)
+		return nil, err
+	}
+	user.Password = hash
+
+	resp, err := s.storage.AuthRepository().RegisterUser(ctx, user)
 	if err != nil {
 		s.logger.Error("RegisterUser error", "error", err)
 		return nil, err
@@ -45,8 +145,8 @@ func (s *authServiceImpl) RegisterUser(user models.RegisterUser) (*models.Respon
 	return resp, nil
 }
 
-func (s *authServiceImpl) EmailExists(email string) (bool, error) {
-	resp, err := s.storage.AuthRepository().EmailExists(email)
+func (s *authServiceImpl) EmailExists(ctx context.Context, email string) (bool, error) {
+	resp, err := s.storage.AuthRepository().EmailExists(ctx, email)
 	if err != nil {
 		s.logger.Error("EmailExists error", "error", err)
 		return false, err
@@ -54,89 +154,576 @@ func (s *authServiceImpl) EmailExists(email string) (bool, error) {
 	return resp, nil
 }
 
-func (s *authServiceImpl) LoginUser(login models.LoginUserReq) (*models.User, error) {
-	resp, err := s.storage.AuthRepository().LoginUser(login)
+func (s *authServiceImpl) LoginUser(ctx context.Context, login models.LoginUserReq, client models.ClientInfo) (*models.User, error) {
+	resp, err := s.storage.AuthRepository().LoginUser(ctx, login)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		s.recordLoginFailure(ctx, "", login.Email, "unknown_email", client)
+	}
 	if err != nil {
 		s.logger.Error("LoginUser error", "error", err)
 		return nil, err
 	}
+
+	if !token.VerifyPassword(login.Password, resp.Password) {
+		s.recordLoginFailure(ctx, resp.ID, login.Email, "invalid_password", client)
+		return nil, ErrInvalidCredentials
+	}
+
+	if token.NeedsRehash(resp.Password) {
+		s.rehashPassword(ctx, resp.ID, login.Password)
+	}
+
+	if resp.Status == postgres.StatusPending && !withinVerificationGracePeriod(resp.CreatedAt) {
+		s.recordLoginFailure(ctx, resp.ID, login.Email, "email_not_verified", client)
+		return nil, ErrEmailNotVerified
+	}
 	return resp, nil
 }
 
-func (s *authServiceImpl) DeleteUser(id string) (*models.Response, error) {
-	resp, err := s.storage.AuthRepository().LogOutUser(id)
+func (s *authServiceImpl) recordLoginFailure(ctx context.Context, userID string, email string, reason string, client models.ClientInfo) {
+	s.recordClientAuditEvent(ctx, userID, postgres.AuditLoginFailed, client, map[string]string{
+		"email":  email,
+		"reason": reason,
+	})
+}
+
+// rehashPassword upgrades a stored hash to the configured algorithm and cost.
+// Failures are only logged: the login itself has already succeeded.
+func (s *authServiceImpl) rehashPassword(ctx context.Context, id, password string) {
+	hash, err := token.HashPassword(password)
+	if err != nil {
+		s.logger.Error("HashPassword error", "error", err)
+		return
+	}
+
+	_, err = s.storage.AuthRepository().UpdatePasswordHash(ctx, id, hash)
+	if err != nil {
+		s.logger.Error("UpdatePasswordHash error", "error", err)
+	}
+}
+
+// LogOut ends the session the access token belongs to: the token is revoked
+// for the rest of its lifetime and the session's refresh token with the
+// session. Other sessions and the account itself are left alone.
+func (s *authServiceImpl) LogOut(ctx context.Context, claims *token.Claims, client models.ClientInfo) (*models.Response, error) {
+	if ttl := time.Until(time.Unix(claims.ExpiresAt, 0)); claims.Id != "" && ttl > 0 {
+		_, err := s.storage.RedisStore().RevokeToken(ctx, claims.Id, ttl)
+		if err != nil {
+			s.logger.Error("RevokeToken error", "error", err)
+			return nil, err
+		}
+	}
+
+	if claims.SessionID != "" {
+		_, err := s.RevokeSession(ctx, claims.ID, claims.SessionID)
+		if err != nil && !errors.Is(err, postgres.ErrSessionNotFound) {
+			return nil, err
+		}
+	}
+
+	s.recordClientAuditEvent(ctx, claims.ID, postgres.AuditLogout, client, map[string]string{
+		"session_id": claims.SessionID,
+	})
+	return &models.Response{
+		Status:  "success",
+		Message: "User logged out",
+	}, nil
+}
+
+func (s *authServiceImpl) ResetPassword(ctx context.Context, reset models.ResetPassword, client models.ClientInfo) (*models.Response, error) {
+	hash, err := token.HashPassword(reset.Password)
 	if err != nil {
-		s.logger.Error("LogOutUser error", "error", err)
+		s.logger.Error("HashPassword error", "error", err)
 		return nil, err
 	}
-	return resp, nil
+
+	// Whoever knew the old password is signed out everywhere.
+	var revoked []string
+	err = s.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		userID, err := tx.AuthRepository().ResetPassword(ctx, reset.Email, hash)
+		if err != nil {
+			s.logger.Error("ResetPassword error", "error", err)
+			return err
+		}
+
+		if userID != "" {
+			revoked, err = tx.SessionRepository().RevokeUserSessions(ctx, userID)
+			if err != nil {
+				s.logger.Error("RevokeUserSessions error", "error", err)
+				return err
+			}
+		}
+
+		_, err = tx.AuditRepository().RecordEvent(ctx, clientAuditEvent(userID, postgres.AuditPasswordReset, client, map[string]string{
+			"email": reset.Email,
+		}))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := revokeSessionTokens(ctx, s.storage, revoked); err != nil {
+		s.logger.Error("RevokeSession error", "error", err)
+		return nil, err
+	}
+	return &models.Response{
+		Status:  "success",
+		Message: "Password reset successfully",
+	}, nil
 }
 
-func (s *authServiceImpl) ResetPassword(reset models.ResetPassword) (*models.Response, error) {
-	resp, err := s.storage.AuthRepository().ResetPassword(reset.Email, reset.Password)
+// StartSession opens a new session for an authenticated user and returns the
+// access/refresh pair bound to it. Signing in cancels a pending account
+// deletion.
+func (s *authServiceImpl) StartSession(ctx context.Context, user *models.User, client models.ClientInfo) (*models.LoginUserResp, error) {
+	sessionID := uuid.NewString()
+
+	permissions, err := s.storage.RoleRepository().GetUserPermissions(ctx, user.ID)
 	if err != nil {
-		s.logger.Error("ResetPassword error", "error", err)
+		s.logger.Error("GetUserPermissions error", "error", err)
 		return nil, err
 	}
-	return resp, nil
+	groups, err := s.storage.GroupRepository().GetUserMemberships(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("GetUserMemberships error", "error", err)
+		return nil, err
+	}
+	claims := models.User{ID: user.ID, Email: user.Email, Role: user.Role, Permissions: permissions, Groups: groups}
+
+	accessToken, err := token.GeneratedJWTTokenAccess(claims, sessionID)
+	if err != nil {
+		s.logger.Error("GeneratedJWTTokenAccess error", "error", err)
+		return nil, err
+	}
+
+	refreshToken, err := token.GeneratedJwtTokenRefresh(claims, sessionID)
+	if err != nil {
+		s.logger.Error("GeneratedJwtTokenRefresh error", "error", err)
+		return nil, err
+	}
+
+	newDevice, err := s.storage.SessionRepository().IsNewDevice(ctx, user.ID, client.UserAgent)
+	if err != nil {
+		s.logger.Error("IsNewDevice error", "error", err)
+		return nil, err
+	}
+
+	err = s.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		_, err := tx.SessionRepository().CreateSession(ctx, models.Session{
+			ID:               sessionID,
+			UserID:           user.ID,
+			DeviceName:       client.DeviceName,
+			UserAgent:        client.UserAgent,
+			IPAddress:        client.IPAddress,
+			ClientID:         client.ClientID,
+			RefreshTokenHash: token.HashToken(refreshToken),
+			ExpiresAt:        time.Now().Add(token.RefreshTokenTTL).Format("2006-01-02 15:04:05"),
+		})
+		if err != nil {
+			s.logger.Error("CreateSession error", "error", err)
+			return err
+		}
+
+		_, err = tx.AuditRepository().RecordEvent(ctx, clientAuditEvent(user.ID, postgres.AuditLoginSucceeded, client, map[string]string{
+			"session_id":  sessionID,
+			"device_name": client.DeviceName,
+		}))
+		if err != nil {
+			return err
+		}
+		return s.cancelAccountDeletion(ctx, tx, user.ID, client)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if newDevice {
+		s.sendNewDeviceAlert(ctx, user.Email, client)
+	}
+	return &models.LoginUserResp{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
 }
 
-func (s *authServiceImpl) SaveRefreshToken(refreshToken models.RefreshToken) (*models.Response, error) {
-	resp, err := s.storage.AuthRepository().SaveRefreshToken(refreshToken)
+// RefreshSession exchanges a refresh token for a new access/refresh pair and
+// rotates the session's stored hash. A token that verifies but is no longer the
+// session's current one has already been exchanged, so the session (the token
+// family) is revoked and the reuse is written to the audit log.
+func (s *authServiceImpl) RefreshSession(ctx context.Context, refreshToken string, client models.ClientInfo) (*models.LoginUserResp, error) {
+	claims, err := token.ExtractClaims(refreshToken)
+	if err != nil || claims.SessionID == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.storage.SessionRepository().GetSession(ctx, claims.SessionID)
+	if err != nil {
+		s.logger.Error("GetSession error", "error", err)
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.UserID != claims.ID {
+		return nil, ErrInvalidRefreshToken
+	}
+	// A refresh token only works for the client it was issued to, and
+	// tokens of the login API only without one.
+	if session.ClientID != client.ClientID {
+		s.logger.Warn("Refresh token presented by another client", "session_id", session.ID, "client_id", client.ClientID)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Roles and group memberships may have changed since the session started,
+	// so the new access token gets the current ones rather than the old ones.
+	profile, err := s.storage.UserRepository().GetUserProfile(ctx, claims.ID)
 	if err != nil {
-		s.logger.Error("SaveRefreshToken error", "error", err)
+		s.logger.Error("GetUserProfile error", "error", err)
+		return nil, ErrInvalidRefreshToken
+	}
+	permissions, err := s.storage.RoleRepository().GetUserPermissions(ctx, claims.ID)
+	if err != nil {
+		s.logger.Error("GetUserPermissions error", "error", err)
 		return nil, err
 	}
-	return resp, nil
+	groups, err := s.storage.GroupRepository().GetUserMemberships(ctx, claims.ID)
+	if err != nil {
+		s.logger.Error("GetUserMemberships error", "error", err)
+		return nil, err
+	}
+	user := models.User{ID: claims.ID, Email: profile.Email, Role: profile.Role, Permissions: permissions, Groups: groups}
+
+	accessToken, err := token.GeneratedJWTTokenAccess(user, session.ID)
+	if err != nil {
+		s.logger.Error("GeneratedJWTTokenAccess error", "error", err)
+		return nil, err
+	}
+	newRefreshToken, err := token.GeneratedJwtTokenRefresh(user, session.ID)
+	if err != nil {
+		s.logger.Error("GeneratedJwtTokenRefresh error", "error", err)
+		return nil, err
+	}
+
+	var reused bool
+	err = s.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		rotated, err := tx.SessionRepository().RotateRefreshToken(
+			ctx,
+			session.ID,
+			token.HashToken(refreshToken),
+			token.HashToken(newRefreshToken),
+			time.Now().Add(token.RefreshTokenTTL).Format("2006-01-02 15:04:05"),
+		)
+		if err != nil {
+			s.logger.Error("RotateRefreshToken error", "error", err)
+			return err
+		}
+		if !rotated {
+			reused = true
+			return s.handleRefreshTokenReuse(ctx, tx, session, client)
+		}
+
+		_, err = tx.AuditRepository().RecordEvent(ctx, clientAuditEvent(session.UserID, postgres.AuditTokenRefreshed, client, map[string]string{
+			"session_id": session.ID,
+		}))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		if _, err := s.storage.RedisStore().RevokeSession(ctx, session.ID, token.AccessTokenTTL); err != nil {
+			s.logger.Error("RevokeSession error", "error", err)
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return &models.LoginUserResp{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+// handleRefreshTokenReuse revokes the session in the transaction of the
+// failed rotation; its access tokens are revoked in Redis once that commits.
+func (s *authServiceImpl) handleRefreshTokenReuse(ctx context.Context, st storage.IStorage, session *models.Session, client models.ClientInfo) error {
+	s.logger.Warn("Refresh token reuse detected", "user_id", session.UserID, "session_id", session.ID)
+
+	// The session may have been revoked in the meantime, which is no reason
+	// not to record the reuse.
+	_, err := st.SessionRepository().RevokeSession(ctx, session.UserID, session.ID)
+	if err != nil && !errors.Is(err, postgres.ErrSessionNotFound) {
+		s.logger.Error("RevokeSession error", "error", err)
+		return err
+	}
+
+	_, err = st.AuditRepository().RecordEvent(ctx, clientAuditEvent(session.UserID, postgres.AuditRefreshTokenReuse, client, map[string]string{
+		"session_id":  session.ID,
+		"device_name": session.DeviceName,
+	}))
+	return err
+}
+
+func (s *authServiceImpl) GetUserSessions(ctx context.Context, userID string, currentID string) (*models.SessionsList, error) {
+	sessions, err := s.storage.SessionRepository().GetUserSessions(ctx, userID)
+	if err != nil {
+		s.logger.Error("GetUserSessions error", "error", err)
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return &models.SessionsList{Sessions: sessions}, nil
 }
 
-func (s *authServiceImpl) InvalidateRefreshToken(email string) (*models.Response, error) {
-	resp, err := s.storage.AuthRepository().InvalidateRefreshToken(email)
+// RevokeSession marks the session revoked in Postgres and in Redis, so that
+// access tokens already issued for it are rejected until they expire.
+func (s *authServiceImpl) RevokeSession(ctx context.Context, userID string, sessionID string) (*models.Response, error) {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return nil, postgres.ErrSessionNotFound
+	}
+
+	resp, err := s.storage.SessionRepository().RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		s.logger.Error("RevokeSession error", "error", err)
+		return nil, err
+	}
+
+	_, err = s.storage.RedisStore().RevokeSession(ctx, sessionID, token.AccessTokenTTL)
 	if err != nil {
-		s.logger.Error("InvalidateRefreshToken error", "error", err)
+		s.logger.Error("RevokeSession error", "error", err)
 		return nil, err
 	}
 	return resp, nil
 }
 
-func (s *authServiceImpl) IsRefreshTokenValid(email string) (bool, error) {
-	resp, err := s.storage.AuthRepository().IsRefreshTokenValid(email)
+func (s *authServiceImpl) RevokeOtherSessions(ctx context.Context, userID string, currentID string) (*models.Response, error) {
+	if currentID == "" {
+		return nil, fmt.Errorf("current session is unknown")
+	}
+
+	ids, err := s.storage.SessionRepository().RevokeOtherSessions(ctx, userID, currentID)
+	if err != nil {
+		s.logger.Error("RevokeOtherSessions error", "error", err)
+		return nil, err
+	}
+
+	for _, id := range ids {
+		_, err = s.storage.RedisStore().RevokeSession(ctx, id, token.AccessTokenTTL)
+		if err != nil {
+			s.logger.Error("RevokeSession error", "error", err)
+			return nil, err
+		}
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: fmt.Sprintf("%d other session(s) revoked", len(ids)),
+	}, nil
+}
+
+func (s *authServiceImpl) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	resp, err := s.storage.RedisStore().IsSessionRevoked(ctx, sessionID)
 	if err != nil {
-		s.logger.Error("IsRefreshTokenValid error", "error", err)
+		s.logger.Error("IsSessionRevoked error", "error", err)
 		return false, err
 	}
 	return resp, nil
 }
 
-func (s *authServiceImpl) UpdateUserRoles(manage models.ManageUserRoles) (*models.Response, error) {
-	resp, err := s.storage.AuthRepository().ManageUserRoles(manage.Email, manage.Role)
+func (s *authServiceImpl) GetUserInfo(ctx context.Context, userID string) (*models.UserInfo, error) {
+	profile, err := s.storage.UserRepository().GetUserProfile(ctx, userID)
 	if err != nil {
-		s.logger.Error("ManageUserRoles error", "error", err)
+		s.logger.Error("GetUserProfile error", "error", err)
 		return nil, err
 	}
-	return resp, nil
+
+	return &models.UserInfo{
+		Sub:        profile.Id,
+		Email:      profile.Email,
+		GivenName:  profile.FirstName,
+		FamilyName: profile.LastName,
+	}, nil
+}
+
+// AuthenticateClient looks up an OAuth client and checks its secret. Public
+// clients are registered without a secret and must not send one.
+func (s *authServiceImpl) AuthenticateClient(ctx context.Context, clientID string, clientSecret string) (*models.OAuthClient, error) {
+	client, err := s.storage.OAuthClientRepository().GetClient(ctx, clientID)
+	if err != nil {
+		s.logger.Error("GetClient error", "error", err)
+		return nil, ErrInvalidClient
+	}
+
+	if client.ClientSecretHash == "" {
+		if clientSecret != "" {
+			return nil, ErrInvalidClient
+		}
+		return client, nil
+	}
+	if !token.VerifyPassword(clientSecret, client.ClientSecretHash) {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
 }
 
-func (s *authServiceImpl) AddTokenBlacklist(token string, expirationTime time.Duration) (*models.Response, error) {
-	resp, err := s.storage.RedisStore().AddTokenBlacklist(token, expirationTime)
+func (s *authServiceImpl) IssueClientToken(client *models.OAuthClient, scope string) (*models.TokenResp, error) {
+	if client.ClientSecretHash == "" || !slices.Contains(client.GrantTypes, "client_credentials") {
+		return nil, ErrUnauthorizedClient
+	}
+
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		requested = client.Scopes
+	}
+	for _, sc := range requested {
+		if !slices.Contains(client.Scopes, sc) {
+			return nil, ErrInvalidScope
+		}
+	}
+	scope = strings.Join(requested, " ")
+
+	accessToken, err := token.GeneratedClientToken(client.ClientID, scope)
 	if err != nil {
-		s.logger.Error("AddTokenBlacklist error", "error", err)
+		s.logger.Error("GeneratedClientToken error", "error", err)
 		return nil, err
 	}
-	return resp, nil
+
+	return &models.TokenResp{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(token.ClientTokenTTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+func (s *authServiceImpl) IssueIDToken(ctx context.Context, userID string, audience string, nonce string) (string, error) {
+	profile, err := s.storage.UserRepository().GetUserProfile(ctx, userID)
+	if err != nil {
+		s.logger.Error("GetUserProfile error", "error", err)
+		return "", err
+	}
+
+	idToken, err := token.GeneratedIDToken(token.IDTokenUser{
+		ID:        profile.Id,
+		Email:     profile.Email,
+		FirstName: profile.FirstName,
+		LastName:  profile.LastName,
+	}, audience, nonce)
+	if err != nil {
+		s.logger.Error("GeneratedIDToken error", "error", err)
+		return "", err
+	}
+	return idToken, nil
+}
+
+// ValidateAuthorizeRequest checks the client and redirect URI of an
+// /authorize request. Errors from here must be shown to the user instead of
+// being sent to the redirect URI, which is not trusted yet.
+func (s *authServiceImpl) ValidateAuthorizeRequest(ctx context.Context, req models.AuthorizeReq) (*models.OAuthClient, error) {
+	client, err := s.storage.OAuthClientRepository().GetClient(ctx, req.ClientID)
+	if err != nil {
+		s.logger.Error("GetClient error", "error", err)
+		return nil, ErrInvalidClient
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, ErrInvalidRedirectURI
+	}
+	if !slices.Contains(client.GrantTypes, "authorization_code") {
+		return nil, ErrUnauthorizedClient
+	}
+	return client, nil
 }
 
-func (s *authServiceImpl) IsTokenBlacklisted(token string) (bool, error) {
-	resp, err := s.storage.RedisStore().IsTokenBlacklisted(token)
+// CreateAuthorizationCode issues a single-use code for userID. PKCE with the
+// S256 method is mandatory for every client.
+func (s *authServiceImpl) CreateAuthorizationCode(ctx context.Context, client *models.OAuthClient, req models.AuthorizeReq, userID string) (string, error) {
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return "", ErrInvalidRequest
+	}
+	for _, sc := range strings.Fields(req.Scope) {
+		if !slices.Contains(client.Scopes, sc) {
+			return "", ErrInvalidScope
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := base64.RawURLEncoding.EncodeToString(raw)
+
+	_, err := s.storage.RedisStore().StoreAuthorizationCode(ctx, code, models.AuthorizationCode{
+		ClientID:      client.ClientID,
+		RedirectURI:   req.RedirectURI,
+		UserID:        userID,
+		Scope:         req.Scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+	}, authorizationCodeTTL)
 	if err != nil {
-		s.logger.Error("IsTokenBlacklisted error", "error", err)
+		s.logger.Error("StoreAuthorizationCode error", "error", err)
+		return "", err
+	}
+	return code, nil
+}
+
+func (s *authServiceImpl) ExchangeAuthorizationCode(ctx context.Context, client *models.OAuthClient, req models.TokenReq, info models.ClientInfo) (*models.LoginUserResp, *models.AuthorizationCode, error) {
+	if client == nil {
+		return nil, nil, ErrInvalidClient
+	}
+
+	code, err := s.storage.RedisStore().ConsumeAuthorizationCode(ctx, req.Code)
+	if err != nil {
+		s.logger.Error("ConsumeAuthorizationCode error", "error", err)
+		return nil, nil, ErrInvalidGrant
+	}
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, nil, ErrInvalidGrant
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, nil, ErrInvalidGrant
+	}
+
+	profile, err := s.storage.UserRepository().GetUserProfile(ctx, code.UserID)
+	if err != nil {
+		s.logger.Error("GetUserProfile error", "error", err)
+		return nil, nil, ErrInvalidGrant
+	}
+
+	tokens, err := s.StartSession(ctx, &models.User{
+		ID:    profile.Id,
+		Email: profile.Email,
+		Role:  profile.Role,
+	}, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, code, nil
+}
+
+// verifyCodeChallenge implements the S256 PKCE check from RFC 7636.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// IsTokenRevoked reports whether the access token with the jti tokenID was
+// revoked by logging out.
+func (s *authServiceImpl) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	resp, err := s.storage.RedisStore().IsTokenRevoked(ctx, tokenID)
+	if err != nil {
+		s.logger.Error("IsTokenRevoked error", "error", err)
 		return false, err
 	}
 	return resp, nil
 }
 
-func (s *authServiceImpl) StoreCode(email, code string, expirationTime time.Duration) (*models.Response, error) {
-	resp, err := s.storage.RedisStore().StoreCode(email, code, expirationTime)
+func (s *authServiceImpl) StoreCode(ctx context.Context, email, code string, expirationTime time.Duration) (*models.Response, error) {
+	resp, err := s.storage.RedisStore().StoreCode(ctx, email, code, expirationTime)
 	if err != nil {
 		s.logger.Error("StoreCode error", "error", err)
 		return nil, err
@@ -144,11 +731,33 @@ func (s *authServiceImpl) StoreCode(email, code string, expirationTime time.Dura
 	return resp, nil
 }
 
-func (s *authServiceImpl) IsCodeValid(email, code string) (bool, error) {
-	resp, err := s.storage.RedisStore().IsCodeValid(email, code)
+// IsCodeValid checks a password reset code. Every check counts as an
+// attempt; after RESET_CODE_MAX_ATTEMPTS of them the code is deleted and a new
+// one has to be requested. A valid code is deleted as well, so it can be used
+// once.
+func (s *authServiceImpl) IsCodeValid(ctx context.Context, email, code string) (bool, error) {
+	attempts, err := s.storage.RedisStore().IncrementCodeAttempts(ctx, email)
+	if err != nil {
+		s.logger.Error("IncrementCodeAttempts error", "error", err)
+		return false, err
+	}
+	if attempts > int64(config.Load().RESET_CODE_MAX_ATTEMPTS) {
+		if err := s.storage.RedisStore().DeleteCode(ctx, email); err != nil {
+			s.logger.Error("DeleteCode error", "error", err)
+		}
+		return false, nil
+	}
+
+	resp, err := s.storage.RedisStore().IsCodeValid(ctx, email, code)
 	if err != nil {
 		s.logger.Error("IsCodeValid error", "error", err)
 		return false, err
 	}
+
+	if resp {
+		if err := s.storage.RedisStore().DeleteCode(ctx, email); err != nil {
+			s.logger.Error("DeleteCode error", "error", err)
+		}
+	}
 	return resp, nil
 }
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"auth-service/models"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// RFC 7636, Appendix B.
+	const (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+	long := strings.Repeat("a", 128)
+	longChallenge := "aDbPE7rEAOkQUHHNavRwhN-srU5eMCyUv-0k4BOvtz4"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"rfc 7636 vector", verifier, challenge, true},
+		{"wrong verifier", strings.Replace(verifier, "d", "e", 1), challenge, false},
+		{"plain challenge", verifier, verifier, false},
+		{"empty challenge", verifier, "", false},
+		{"empty verifier", "", challenge, false},
+		{"verifier of 42 characters", verifier[:42], challenge, false},
+		{"verifier of 43 characters", verifier, challenge, true},
+		{"verifier of 128 characters", long, longChallenge, true},
+		{"verifier of 129 characters", long + "a", longChallenge, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, verifyCodeChallenge(tt.verifier, tt.challenge))
+		})
+	}
+}
+
+func TestCreateAuthorizationCodeRequiresS256(t *testing.T) {
+	s := &authServiceImpl{logger: slog.Default()}
+	client := &models.OAuthClient{ClientID: "web", Scopes: []string{"openid"}}
+
+	for _, method := range []string{"", "plain", "s256"} {
+		_, err := s.CreateAuthorizationCode(context.Background(), client, models.AuthorizeReq{
+			ClientID:            "web",
+			Scope:               "openid",
+			CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+			CodeChallengeMethod: method,
+		}, "d70789c8-37e0-4de6-8195-d900abc0afb5")
+		assert.ErrorIs(t, err, ErrInvalidRequest, "method %q", method)
+	}
+
+	_, err := s.CreateAuthorizationCode(context.Background(), client, models.AuthorizeReq{
+		ClientID:            "web",
+		Scope:               "openid",
+		CodeChallengeMethod: "S256",
+	}, "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"auth-service/api/token"
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/storage/postgres"
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestDataExport queues an export of the caller's personal data. The
+// archive is built in the background and a download link is emailed once it
+// is ready; only one export can be in progress at a time.
+func (s *authServiceImpl) RequestDataExport(ctx context.Context, userID string, client models.ClientInfo) (*models.DataExport, error) {
+	export, err := s.storage.DataExportRepository().CreateExport(ctx, userID)
+	if errors.Is(err, postgres.ErrDataExportInProgress) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Error("CreateExport error", "error", err)
+		return nil, err
+	}
+
+	s.recordClientAuditEvent(ctx, userID, postgres.AuditDataExportRequested, client, map[string]string{
+		"export_id": export.ID,
+	})
+	return export, nil
+}
+
+// GetDataExport returns an export of the caller, with its download link when
+// it is ready.
+func (s *authServiceImpl) GetDataExport(ctx context.Context, userID string, id string) (*models.DataExport, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, postgres.ErrDataExportNotFound
+	}
+
+	export, err := s.storage.DataExportRepository().GetExport(ctx, id)
+	if errors.Is(err, postgres.ErrDataExportNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Error("GetExport error", "error", err)
+		return nil, err
+	}
+	if export.UserID != userID {
+		return nil, postgres.ErrDataExportNotFound
+	}
+
+	if export.Status == postgres.ExportReady {
+		expiresAt, err := time.Parse(time.RFC3339, export.ExpiresAt)
+		if err != nil {
+			s.logger.Error("Parse expires_at error", "error", err)
+			return nil, err
+		}
+		if export.DownloadURL, err = dataExportURL(export.ID, expiresAt); err != nil {
+			s.logger.Error("dataExportURL error", "error", err)
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+// OpenDataExport checks a download link of an export and returns the export,
+// whose FilePath holds the archive. Links carry their own authorization, so an
+// invalid or expired one is reported as not found.
+func (s *authServiceImpl) OpenDataExport(ctx context.Context, id string, expires string, signature string, client models.ClientInfo) (*models.DataExport, error) {
+	if !token.VerifyDownload(dataExportResource(id), expires, signature) {
+		return nil, postgres.ErrDataExportNotFound
+	}
+
+	export, err := s.storage.DataExportRepository().GetExport(ctx, id)
+	if errors.Is(err, postgres.ErrDataExportNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Error("GetExport error", "error", err)
+		return nil, err
+	}
+	if export.Status != postgres.ExportReady {
+		return nil, postgres.ErrDataExportNotFound
+	}
+
+	s.recordClientAuditEvent(ctx, export.UserID, postgres.AuditDataExportDownloaded, client, map[string]string{
+		"export_id": export.ID,
+	})
+	return export, nil
+}
+
+func dataExportResource(id string) string {
+	return "data_export:" + id
+}
+
+// dataExportURL returns the signed download link of an export.
+func dataExportURL(id string, expiresAt time.Time) (string, error) {
+	signature, err := token.SignDownload(dataExportResource(id), expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", signature)
+	return config.Load().ISSUER_URL + "/api/v1/exports/" + id + "/download?" + query.Encode(), nil
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"auth-service/api/token"
+	"auth-service/config"
+	"auth-service/generated/budgeting"
+	"auth-service/pkg/dataexport"
+	"auth-service/pkg/grpcauth"
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ServiceClientID is the OAuth client ID this service identifies itself with
+// when it calls other services.
+const ServiceClientID = "auth-service"
+
+const (
+	// exportPageSize is how many records are asked for per call.
+	exportPageSize = 100
+	// exportMaxPages stops paging through a service that ignores the page.
+	exportMaxPages = 1000
+)
+
+// ExportSources are the clients of the services whose data about the user is
+// added to data exports. Any of them may be nil, and its data is left out.
+type ExportSources struct {
+	Budgeting budgeting.BudgetingServiceClient
+	Finance   budgeting.FinanceManagementServiceClient
+	Goals     budgeting.GoalsManagemenServiceClient
+}
+
+// DialExportSources connects to the services whose address is configured.
+// Calls carry a service token of ServiceClientID, and use TLS when
+// GRPC_CLIENT_CA_FILE is set.
+func DialExportSources(cfg *config.Config) (ExportSources, error) {
+	var sources ExportSources
+
+	transport := insecure.NewCredentials()
+	if cfg.GRPC_CLIENT_CA_FILE != "" {
+		var err error
+		transport, err = grpcauth.ClientCredentials(cfg.GRPC_TLS_CERT_FILE, cfg.GRPC_TLS_KEY_FILE, cfg.GRPC_CLIENT_CA_FILE)
+		if err != nil {
+			return sources, err
+		}
+	}
+	dial := func(addr string) (*grpc.ClientConn, error) {
+		return grpc.NewClient(addr, grpc.WithTransportCredentials(transport),
+			grpc.WithPerRPCCredentials(grpcauth.ServiceToken{
+				Source: func() (string, error) { return token.GeneratedClientToken(ServiceClientID, "") },
+				Secure: cfg.GRPC_CLIENT_CA_FILE != "",
+			}))
+	}
+
+	if cfg.BUDGETING_GRPC_ADDR != "" {
+		conn, err := dial(cfg.BUDGETING_GRPC_ADDR)
+		if err != nil {
+			return sources, err
+		}
+		sources.Budgeting = budgeting.NewBudgetingServiceClient(conn)
+	}
+	if cfg.FINANCE_GRPC_ADDR != "" {
+		conn, err := dial(cfg.FINANCE_GRPC_ADDR)
+		if err != nil {
+			return sources, err
+		}
+		sources.Finance = budgeting.NewFinanceManagementServiceClient(conn)
+	}
+	if cfg.GOALS_GRPC_ADDR != "" {
+		conn, err := dial(cfg.GOALS_GRPC_ADDR)
+		if err != nil {
+			return sources, err
+		}
+		sources.Goals = budgeting.NewGoalsManagemenServiceClient(conn)
+	}
+
+	return sources, nil
+}
+
+// addRemoteSections adds the data of the configured services to archive,
+// giving each service timeout to answer. A failing service fails the export,
+// which is then retried, rather than silently leaving the data out.
+func (s ExportSources) addRemoteSections(ctx context.Context, archive *dataexport.Archive, userID string, timeout time.Duration) error {
+	if s.Finance != nil {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		accounts, err := s.accounts(ctx, userID)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(accounts))
+		for _, a := range accounts {
+			rows = append(rows, []string{a.Id, dataexport.Cell(a.Name), a.Type, formatAmount(a.Balance), a.Currency})
+		}
+		if err := archive.Add("accounts", accounts, []string{"id", "name", "type", "balance", "currency"}, rows); err != nil {
+			return err
+		}
+
+		transactions, err := s.transactions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		rows = make([][]string, 0, len(transactions))
+		for _, t := range transactions {
+			rows = append(rows, []string{t.Id, t.AccountId, t.CategoryId, formatAmount(t.Amount), t.Type,
+				dataexport.Cell(t.Description), t.Date})
+		}
+		err = archive.Add("transactions", transactions,
+			[]string{"id", "account_id", "category_id", "amount", "type", "description", "date"}, rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.Budgeting != nil {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		budgets, err := s.budgets(ctx, userID)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(budgets))
+		for _, b := range budgets {
+			rows = append(rows, []string{b.Id, b.CategoryId, formatAmount(b.Amount), b.Period, b.StartDate, b.EndDate})
+		}
+		err = archive.Add("budgets", budgets,
+			[]string{"id", "category_id", "amount", "period", "start_date", "end_date"}, rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.Goals != nil {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		goals, err := s.goals(ctx, userID)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(goals))
+		for _, g := range goals {
+			rows = append(rows, []string{g.Id, dataexport.Cell(g.Name), formatAmount(g.TargetAmount),
+				formatAmount(g.CurrentAmount), g.Deadline, g.Status})
+		}
+		err = archive.Add("goals", goals,
+			[]string{"id", "name", "target_amount", "current_amount", "deadline", "status"}, rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s ExportSources) accounts(ctx context.Context, userID string) ([]*budgeting.Account, error) {
+	var accounts []*budgeting.Account
+	for page := 0; page < exportMaxPages; page++ {
+		resp, err := s.Finance.GetAccountsList(ctx, &budgeting.GetAccountsListReq{
+			UserId: userID,
+			Limit:  exportPageSize,
+			Offset: int64(len(accounts)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, resp.Accounts...)
+		if len(resp.Accounts) == 0 || int64(len(accounts)) >= resp.TotalCount {
+			break
+		}
+	}
+	return accounts, nil
+}
+
+func (s ExportSources) transactions(ctx context.Context, userID string) ([]*budgeting.Transaction, error) {
+	var transactions []*budgeting.Transaction
+	for page := int64(1); page <= exportMaxPages; page++ {
+		resp, err := s.Finance.GetTransactionsList(ctx, &budgeting.GetTransactionsListReq{
+			UserId: userID,
+			Page:   page,
+			Limit:  exportPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, resp.Transactions...)
+		if len(resp.Transactions) == 0 || int64(len(transactions)) >= resp.TotalCount {
+			break
+		}
+	}
+	return transactions, nil
+}
+
+func (s ExportSources) budgets(ctx context.Context, userID string) ([]*budgeting.Budget, error) {
+	var budgets []*budgeting.Budget
+	for page := int64(1); page <= exportMaxPages; page++ {
+		resp, err := s.Budgeting.GetBudgetsList(ctx, &budgeting.GetBudgetsReq{
+			UserId: userID,
+			Page:   page,
+			Limit:  exportPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, resp.Budgets...)
+		if len(resp.Budgets) == 0 || int64(len(budgets)) >= resp.TotalCount {
+			break
+		}
+	}
+	return budgets, nil
+}
+
+func (s ExportSources) goals(ctx context.Context, userID string) ([]*budgeting.Goal, error) {
+	var goals []*budgeting.Goal
+	for page := int64(1); page <= exportMaxPages; page++ {
+		resp, err := s.Goals.GetGoals(ctx, &budgeting.GetGoalsReq{
+			UserId: userID,
+			Page:   page,
+			Limit:  exportPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, resp.Goals...)
+		if len(resp.Goals) == 0 || int64(len(goals)) >= resp.TotalCount {
+			break
+		}
+	}
+	return goals, nil
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/dataexport"
+	"auth-service/pkg/mail"
+	"auth-service/storage"
+	"auth-service/storage/postgres"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// dataExportBatchSize is how many exports a worker claims at once; building
+	// one can take a while, so they are not claimed far ahead.
+	dataExportBatchSize = 2
+	// auditExportPageSize is how many audit events are read per query.
+	auditExportPageSize = 1000
+)
+
+// DataExportWorker builds the archives of requested data exports, emails their
+// download links and removes the archives once the links have expired.
+// Several workers, also in different processes, can run against the same
+// database.
+type DataExportWorker struct {
+	storage storage.IStorage
+	sources ExportSources
+	logger  *slog.Logger
+}
+
+func NewDataExportWorker(storage storage.IStorage, sources ExportSources, logger *slog.Logger) *DataExportWorker {
+	return &DataExportWorker{storage: storage, sources: sources, logger: logger}
+}
+
+// Run looks for requested and expired exports every EXPORT_POLL_INTERVAL
+// until ctx is cancelled.
+func (w *DataExportWorker) Run(ctx context.Context) {
+	cfg := config.Load()
+
+	ticker := time.NewTicker(cfg.EXPORT_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		w.expire(ctx)
+		w.drain(ctx, cfg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *DataExportWorker) expire(ctx context.Context) {
+	paths, err := w.storage.DataExportRepository().ExpireExports(ctx)
+	if err != nil {
+		w.logger.Error("ExpireExports error", "error", err)
+		return
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			w.logger.Error("Remove data export error", "error", err, "path", path)
+		}
+	}
+}
+
+func (w *DataExportWorker) drain(ctx context.Context, cfg *config.Config) {
+	// A claimed export is leased for long enough to build it once; if this
+	// process dies meanwhile another worker picks it up after the lease.
+	lease := 4*cfg.EXPORT_SOURCE_TIMEOUT + 5*time.Minute
+
+	for ctx.Err() == nil {
+		exports, err := w.storage.DataExportRepository().ClaimDueExports(ctx, dataExportBatchSize, lease)
+		if err != nil {
+			w.logger.Error("ClaimDueExports error", "error", err)
+			return
+		}
+
+		for _, export := range exports {
+			w.build(ctx, cfg, export)
+		}
+		if len(exports) < dataExportBatchSize {
+			return
+		}
+	}
+}
+
+func (w *DataExportWorker) build(ctx context.Context, cfg *config.Config, export models.DataExport) {
+	repo := w.storage.DataExportRepository()
+
+	path, size, err := w.writeArchive(ctx, cfg, export)
+	if err != nil {
+		if export.Attempts >= cfg.EXPORT_MAX_ATTEMPTS {
+			w.logger.Error("Data export failed", "error", err, "id", export.ID, "attempts", export.Attempts)
+			if _, err := repo.MarkExportDead(ctx, export.ID, err.Error()); err != nil {
+				w.logger.Error("MarkExportDead error", "error", err, "id", export.ID)
+			}
+			return
+		}
+
+		// Waits a minute after the first failure, two after the second, and so on.
+		delay := time.Duration(export.Attempts) * time.Minute
+		w.logger.Warn("Data export failed", "error", err, "id", export.ID, "attempts", export.Attempts, "retry_in", delay)
+		if _, err := repo.MarkExportFailed(ctx, export.ID, err.Error(), time.Now().Add(delay)); err != nil {
+			w.logger.Error("MarkExportFailed error", "error", err, "id", export.ID)
+		}
+		return
+	}
+
+	expiresAt := time.Now().Add(cfg.EXPORT_LINK_TTL)
+	_, err = repo.MarkExportReady(ctx, export.ID, path, size, expiresAt)
+	if err != nil {
+		if !errors.Is(err, postgres.ErrDataExportNotFound) {
+			w.logger.Error("MarkExportReady error", "error", err, "id", export.ID)
+		}
+		// The export is not ours to finish anymore; the next attempt, if
+		// any, writes a new archive.
+		_ = os.Remove(path)
+		return
+	}
+	w.logger.Info("Data export ready", "id", export.ID, "size_bytes", size)
+
+	// The link can also be fetched through the API, so a failure here only
+	// costs the notice.
+	if err := w.notify(ctx, export, expiresAt); err != nil {
+		w.logger.Error("Data export email error", "error", err, "id", export.ID)
+	}
+}
+
+func (w *DataExportWorker) notify(ctx context.Context, export models.DataExport, expiresAt time.Time) error {
+	link, err := dataExportURL(export.ID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	locale, err := w.storage.AuthRepository().GetUserLocale(ctx, export.Email)
+	if err != nil {
+		w.logger.Error("GetUserLocale error", "error", err)
+	}
+	msg, err := mail.DataExportEmail(export.Email, mail.ResolveLocale(locale, ""), link, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.storage.EmailOutboxRepository().EnqueueEmail(ctx, models.EmailMessage{
+		To:      msg.To,
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
+	})
+	return err
+}
+
+// writeArchive writes the export into EXPORT_DIR and returns the path and the
+// size of the archive. It is written under a temporary name first, so that a
+// partial archive is never served.
+func (w *DataExportWorker) writeArchive(ctx context.Context, cfg *config.Config, export models.DataExport) (string, int64, error) {
+	if err := os.MkdirAll(cfg.EXPORT_DIR, 0o700); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(cfg.EXPORT_DIR, export.ID+".zip")
+
+	f, err := os.CreateTemp(cfg.EXPORT_DIR, export.ID+"-*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	archive := dataexport.NewArchive(f)
+	if err := w.addLocalSections(ctx, archive, export.UserID); err != nil {
+		return "", 0, err
+	}
+	if err := w.sources.addRemoteSections(ctx, archive, export.UserID, cfg.EXPORT_SOURCE_TIMEOUT); err != nil {
+		return "", 0, err
+	}
+	if err := archive.Close(); err != nil {
+		return "", 0, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+// exportIdentity is a credential linked to the account: a passkey, an
+// authenticator app or a personal access token.
+type exportIdentity struct {
+	Type       string `json:"type"`
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+}
+
+// addLocalSections adds what this service holds about the user.
+func (w *DataExportWorker) addLocalSections(ctx context.Context, archive *dataexport.Archive, userID string) error {
+	profile, err := w.storage.UserRepository().GetUserProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = archive.Add("profile", profile,
+		[]string{"id", "email", "first_name", "last_name", "role", "status", "email_verified", "locale"},
+		[][]string{{profile.Id, dataexport.Cell(profile.Email), dataexport.Cell(profile.FirstName), dataexport.Cell(profile.LastName),
+			profile.Role, profile.Status, strconv.FormatBool(profile.EmailVerified), profile.Locale}})
+	if err != nil {
+		return err
+	}
+
+	sessions, err := w.storage.SessionRepository().GetUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(sessions))
+	for _, s := range sessions {
+		rows = append(rows, []string{s.ID, dataexport.Cell(s.DeviceName), dataexport.Cell(s.UserAgent),
+			dataexport.Cell(s.IPAddress), s.CreatedAt, s.LastUsedAt, s.ExpiresAt})
+	}
+	err = archive.Add("sessions", sessions,
+		[]string{"id", "device_name", "user_agent", "ip_address", "created_at", "last_used_at", "expires_at"}, rows)
+	if err != nil {
+		return err
+	}
+
+	events, err := w.auditEvents(ctx, userID)
+	if err != nil {
+		return err
+	}
+	rows = make([][]string, 0, len(events))
+	for _, event := range events {
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, []string{event.ID, event.CreatedAt, event.EventType, event.ActorID,
+			dataexport.Cell(event.IPAddress), dataexport.Cell(event.UserAgent), string(metadata)})
+	}
+	err = archive.Add("audit_events", events,
+		[]string{"id", "created_at", "event_type", "actor_id", "ip_address", "user_agent", "metadata"}, rows)
+	if err != nil {
+		return err
+	}
+
+	identities, err := w.identities(ctx, userID)
+	if err != nil {
+		return err
+	}
+	rows = make([][]string, 0, len(identities))
+	for _, identity := range identities {
+		rows = append(rows, []string{identity.Type, identity.ID, dataexport.Cell(identity.Name),
+			identity.CreatedAt, identity.LastUsedAt})
+	}
+	err = archive.Add("identities", identities, []string{"type", "id", "name", "created_at", "last_used_at"}, rows)
+	if err != nil {
+		return err
+	}
+
+	groups, err := w.storage.GroupRepository().GetUserGroups(ctx, userID)
+	if err != nil {
+		return err
+	}
+	rows = make([][]string, 0, len(groups))
+	for _, g := range groups {
+		rows = append(rows, []string{g.ID, dataexport.Cell(g.Name), g.Role, g.CreatedAt})
+	}
+	return archive.Add("groups", groups, []string{"id", "name", "role", "created_at"}, rows)
+}
+
+func (w *DataExportWorker) auditEvents(ctx context.Context, userID string) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	for {
+		page, err := w.storage.AuditRepository().ListEvents(ctx, models.AuditEventFilter{
+			UserID: userID,
+			Limit:  auditExportPageSize,
+			Offset: len(events),
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+		if len(page) < auditExportPageSize {
+			return events, nil
+		}
+	}
+}
+
+func (w *DataExportWorker) identities(ctx context.Context, userID string) ([]exportIdentity, error) {
+	identities := []exportIdentity{}
+
+	credentials, err := w.storage.WebAuthnRepository().GetUserCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range credentials {
+		identities = append(identities, exportIdentity{
+			Type:       "passkey",
+			ID:         c.ID,
+			Name:       c.Name,
+			CreatedAt:  c.CreatedAt,
+			LastUsedAt: c.LastUsedAt,
+		})
+	}
+
+	mfa, err := w.storage.MFARepository().GetTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, postgres.ErrMFANotFound) {
+		return nil, err
+	}
+	if err == nil && mfa.Enabled {
+		identities = append(identities, exportIdentity{Type: "totp", CreatedAt: mfa.EnabledAt})
+	}
+
+	tokens, err := w.storage.PersonalTokenRepository().GetUserTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		identities = append(identities, exportIdentity{
+			Type:       "personal_token",
+			ID:         t.ID,
+			Name:       t.Name,
+			CreatedAt:  t.CreatedAt,
+			LastUsedAt: t.LastUsedAt,
+		})
+	}
+
+	return identities, nil
+}
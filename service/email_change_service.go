@@ -0,0 +1,85 @@
+package service
+
+import (
+	"auth-service/api/token"
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/pkg/mail"
+	"auth-service/storage"
+	"auth-service/storage/postgres"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+var ErrInvalidEmailChangeToken = apperr.New(apperr.InvalidArgument, "invalid or expired email change token")
+
+const emailChangeTTL = 24 * time.Hour
+
+// requestEmailChange emails a confirmation link to the new address of the
+// user. The account keeps its current address until ConfirmEmailChange is
+// called with the token of that link.
+func requestEmailChange(ctx context.Context, st storage.IStorage, logger *slog.Logger, userID string, email string, locale string) error {
+	exists, err := st.AuthRepository().EmailExists(ctx, email)
+	if err != nil {
+		logger.Error("EmailExists error", "error", err)
+		return err
+	}
+	if exists {
+		return postgres.ErrEmailTaken
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	changeToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	_, err = st.RedisStore().StoreEmailChange(ctx, token.HashToken(changeToken), models.EmailChange{
+		UserID: userID,
+		Email:  email,
+	}, emailChangeTTL)
+	if err != nil {
+		logger.Error("StoreEmailChange error", "error", err)
+		return err
+	}
+
+	link := config.Load().ISSUER_URL + "/api/v1/auth/confirm-email-change?token=" + url.QueryEscape(changeToken)
+	msg, err := mail.EmailChangeEmail(email, locale, email, link)
+	if err != nil {
+		logger.Error("EmailChangeEmail error", "error", err)
+		return err
+	}
+	return enqueueEmail(ctx, st, logger, msg)
+}
+
+// ConfirmEmailChange switches the account to the address the token was sent
+// to, which proves the user owns it.
+func (s *authServiceImpl) ConfirmEmailChange(ctx context.Context, changeToken string) (*models.Response, error) {
+	change, err := s.storage.RedisStore().ConsumeEmailChange(ctx, token.HashToken(changeToken))
+	if err != nil {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	resp, err := s.storage.UserRepository().ChangeEmail(ctx, change.UserID, change.Email)
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	if errors.Is(err, postgres.ErrEmailTaken) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Error("ChangeEmail error", "error", err)
+		return nil, err
+	}
+
+	s.recordAuditEvent(ctx, change.UserID, postgres.AuditEmailChanged, map[string]string{
+		"email": change.Email,
+	})
+	return resp, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/mail"
+	"auth-service/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// EmailWorker delivers the messages in the email outbox. Several workers,
+// also in different processes, can run against the same database.
+type EmailWorker struct {
+	storage storage.IStorage
+	mailer  mail.Mailer
+	logger  *slog.Logger
+}
+
+func NewEmailWorker(storage storage.IStorage, mailer mail.Mailer, logger *slog.Logger) *EmailWorker {
+	return &EmailWorker{storage: storage, mailer: mailer, logger: logger}
+}
+
+// Run polls the outbox every MAIL_POLL_INTERVAL until ctx is cancelled.
+func (w *EmailWorker) Run(ctx context.Context) {
+	cfg := config.Load()
+
+	ticker := time.NewTicker(cfg.MAIL_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx, cfg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *EmailWorker) drain(ctx context.Context, cfg *config.Config) {
+	// A claimed message is leased for long enough to try it once; if this
+	// process dies meanwhile another worker picks it up after the lease.
+	lease := 2 * cfg.MAIL_SEND_TIMEOUT
+
+	for ctx.Err() == nil {
+		messages, err := w.storage.EmailOutboxRepository().ClaimDueEmails(ctx, cfg.MAIL_BATCH_SIZE, lease)
+		if err != nil {
+			w.logger.Error("ClaimDueEmails error", "error", err)
+			return
+		}
+
+		for _, msg := range messages {
+			w.deliver(ctx, cfg, msg)
+		}
+		if len(messages) < cfg.MAIL_BATCH_SIZE {
+			return
+		}
+	}
+}
+
+func (w *EmailWorker) deliver(ctx context.Context, cfg *config.Config, msg models.EmailMessage) {
+	sendCtx, cancel := context.WithTimeout(ctx, cfg.MAIL_SEND_TIMEOUT)
+	err := w.mailer.Send(sendCtx, mail.Message{To: msg.To, Subject: msg.Subject, HTML: msg.HTML, Text: msg.Text})
+	cancel()
+
+	repo := w.storage.EmailOutboxRepository()
+	if err == nil {
+		if _, err := repo.MarkEmailSent(ctx, msg.ID); err != nil {
+			w.logger.Error("MarkEmailSent error", "error", err, "id", msg.ID)
+		}
+		return
+	}
+
+	if msg.Attempts >= cfg.MAIL_MAX_ATTEMPTS {
+		w.logger.Error("Email dead-lettered", "error", err, "id", msg.ID, "attempts", msg.Attempts)
+		if _, err := repo.MarkEmailDead(ctx, msg.ID, err.Error()); err != nil {
+			w.logger.Error("MarkEmailDead error", "error", err, "id", msg.ID)
+		}
+		return
+	}
+
+	delay := emailRetryDelay(msg.Attempts, cfg)
+	w.logger.Warn("Email delivery failed", "error", err, "id", msg.ID, "attempts", msg.Attempts, "retry_in", delay)
+	if _, err := repo.MarkEmailFailed(ctx, msg.ID, err.Error(), time.Now().Add(delay)); err != nil {
+		w.logger.Error("MarkEmailFailed error", "error", err, "id", msg.ID)
+	}
+}
+
+// emailRetryDelay doubles MAIL_RETRY_BASE for every failed attempt, up to
+// MAIL_RETRY_MAX.
+func emailRetryDelay(attempts int, cfg *config.Config) time.Duration {
+	return retryDelay(attempts, cfg.MAIL_RETRY_BASE, cfg.MAIL_RETRY_MAX)
+}
+
+// retryDelay doubles base for every failed attempt after the first, up to
+// limit.
+func retryDelay(attempts int, base time.Duration, limit time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= limit {
+			return limit
+		}
+	}
+	return min(delay, limit)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/events"
+	"auth-service/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// EventRelay publishes the domain events in the outbox. Delivery is at least
+// once: an event whose publishing fails, or whose relay dies before recording
+// it as published, is published again later, with the same ID. Events are
+// published in the order they were written, except that one being retried
+// does not hold back the others. Several relays, also in different processes,
+// can run against the same database.
+type EventRelay struct {
+	storage   storage.IStorage
+	publisher events.EventPublisher
+	logger    *slog.Logger
+}
+
+func NewEventRelay(storage storage.IStorage, publisher events.EventPublisher, logger *slog.Logger) *EventRelay {
+	return &EventRelay{storage: storage, publisher: publisher, logger: logger}
+}
+
+// Run polls the outbox every EVENTS_POLL_INTERVAL until ctx is cancelled.
+func (r *EventRelay) Run(ctx context.Context) {
+	cfg := config.Load()
+
+	ticker := time.NewTicker(cfg.EVENTS_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		r.drain(ctx, cfg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanup.C:
+			r.cleanup(ctx, cfg)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *EventRelay) drain(ctx context.Context, cfg *config.Config) {
+	// A claimed event is leased for long enough to publish it once; if this
+	// process dies meanwhile another relay picks it up after the lease.
+	lease := 2 * cfg.EVENTS_PUBLISH_TIMEOUT
+
+	for ctx.Err() == nil {
+		claimed, err := r.storage.EventOutboxRepository().ClaimDueEvents(ctx, cfg.EVENTS_BATCH_SIZE, lease)
+		if err != nil {
+			r.logger.Error("ClaimDueEvents error", "error", err)
+			return
+		}
+
+		for _, event := range claimed {
+			r.publish(ctx, cfg, event)
+		}
+		if len(claimed) < cfg.EVENTS_BATCH_SIZE {
+			return
+		}
+	}
+}
+
+func (r *EventRelay) publish(ctx context.Context, cfg *config.Config, event models.Event) {
+	publishCtx, cancel := context.WithTimeout(ctx, cfg.EVENTS_PUBLISH_TIMEOUT)
+	err := r.publisher.Publish(publishCtx, events.Event{
+		ID:         event.ID,
+		Type:       event.Type,
+		UserID:     event.UserID,
+		Data:       event.Data,
+		OccurredAt: event.OccurredAt,
+	})
+	cancel()
+
+	repo := r.storage.EventOutboxRepository()
+	if err == nil {
+		if _, err := repo.MarkEventPublished(ctx, event.ID); err != nil {
+			r.logger.Error("MarkEventPublished error", "error", err, "id", event.ID)
+		}
+		return
+	}
+
+	delay := retryDelay(event.Attempts, cfg.EVENTS_RETRY_BASE, cfg.EVENTS_RETRY_MAX)
+	r.logger.Warn("Event publishing failed", "error", err, "id", event.ID, "type", event.Type,
+		"attempts", event.Attempts, "retry_in", delay)
+	if _, err := repo.MarkEventFailed(ctx, event.ID, err.Error(), time.Now().Add(delay)); err != nil {
+		r.logger.Error("MarkEventFailed error", "error", err, "id", event.ID)
+	}
+}
+
+func (r *EventRelay) cleanup(ctx context.Context, cfg *config.Config) {
+	deleted, err := r.storage.EventOutboxRepository().DeletePublishedEvents(ctx, time.Now().Add(-cfg.EVENTS_RETENTION))
+	if err != nil {
+		r.logger.Error("DeletePublishedEvents error", "error", err)
+		return
+	}
+	if deleted > 0 {
+		r.logger.Info("Published events removed", "count", deleted)
+	}
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/pkg/mail"
+	"auth-service/storage/postgres"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles a user can hold in a group. Owners manage the group and its members,
+// members share its data and viewers can only read it. What each role allows
+// in budgets and transactions is up to the services that own them; they read
+// the role from the access token or the GetGroupMembership RPC.
+const (
+	GroupOwner  = postgres.GroupOwner
+	GroupMember = postgres.GroupMember
+	GroupViewer = postgres.GroupViewer
+)
+
+const (
+	groupInvitationTTL = 7 * 24 * time.Hour
+	maxGroupNameLength = 100
+)
+
+var (
+	ErrGroupNotFound       = apperr.New(apperr.NotFound, "group not found")
+	ErrGroupForbidden      = apperr.New(apperr.PermissionDenied, "only group owners can do this")
+	ErrGroupMemberNotFound = apperr.New(apperr.NotFound, "group member not found")
+	ErrInvalidGroupName    = apperr.New(apperr.InvalidArgument, "group name must be between 1 and 100 characters")
+	ErrInvalidGroupRole    = apperr.New(apperr.InvalidArgument, "group role must be owner, member or viewer")
+	ErrAlreadyGroupMember  = apperr.New(apperr.Conflict, "user is already a member of the group")
+	ErrInvitationExists    = apperr.New(apperr.Conflict, "an invitation to this email is already pending")
+	ErrInvitationNotFound  = apperr.New(apperr.NotFound, "invitation not found")
+	ErrLastGroupOwner      = apperr.New(apperr.Conflict, "group must keep at least one owner, delete the group instead")
+)
+
+func isGroupRole(role string) bool {
+	return slices.Contains([]string{GroupOwner, GroupMember, GroupViewer}, role)
+}
+
+func (s *authServiceImpl) CreateGroup(ctx context.Context, userID string, name string) (*models.Group, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxGroupNameLength {
+		return nil, ErrInvalidGroupName
+	}
+
+	group, err := s.storage.GroupRepository().CreateGroup(ctx, name, userID)
+	if err != nil {
+		s.logger.Error("CreateGroup error", "error", err)
+		return nil, err
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditGroupCreated, map[string]string{
+		"group_id": group.ID,
+	})
+	return group, nil
+}
+
+func (s *authServiceImpl) ListGroups(ctx context.Context, userID string) (*models.GroupsList, error) {
+	groups, err := s.storage.GroupRepository().GetUserGroups(ctx, userID)
+	if err != nil {
+		s.logger.Error("GetUserGroups error", "error", err)
+		return nil, err
+	}
+	return &models.GroupsList{Groups: groups}, nil
+}
+
+// GetGroup returns the group with its members. Groups the user does not
+// belong to are reported as not found.
+func (s *authServiceImpl) GetGroup(ctx context.Context, userID string, groupID string) (*models.Group, error) {
+	role, err := s.groupRole(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.storage.GroupRepository().GetGroup(ctx, groupID)
+	if errors.Is(err, postgres.ErrGroupNotFound) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		s.logger.Error("GetGroup error", "error", err)
+		return nil, err
+	}
+	group.Role = role
+	return group, nil
+}
+
+func (s *authServiceImpl) DeleteGroup(ctx context.Context, userID string, groupID string) (*models.Response, error) {
+	if err := s.requireGroupOwner(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.storage.GroupRepository().DeleteGroup(ctx, groupID)
+	if errors.Is(err, postgres.ErrGroupNotFound) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		s.logger.Error("DeleteGroup error", "error", err)
+		return nil, err
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditGroupDeleted, map[string]string{
+		"group_id": groupID,
+	})
+	return resp, nil
+}
+
+// InviteGroupMember invites an email address to the group and emails the
+// invitee. The address does not need an account yet; the invitation is
+// answered after signing in with it.
+func (s *authServiceImpl) InviteGroupMember(ctx context.Context, userID string, inviterEmail string, groupID string, invite models.InviteGroupMemberReq, acceptLanguage string) (*models.GroupInvitation, error) {
+	if invite.Role == "" {
+		invite.Role = GroupMember
+	}
+	if !isGroupRole(invite.Role) {
+		return nil, ErrInvalidGroupRole
+	}
+	if err := s.requireGroupOwner(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(groupInvitationTTL)
+	invitation, err := s.storage.GroupRepository().CreateInvitation(ctx, groupID, invite.Email, invite.Role, userID, expiresAt)
+	if errors.Is(err, postgres.ErrAlreadyGroupMember) {
+		return nil, ErrAlreadyGroupMember
+	}
+	if errors.Is(err, postgres.ErrInvitationExists) {
+		return nil, ErrInvitationExists
+	}
+	if err != nil {
+		s.logger.Error("CreateInvitation error", "error", err)
+		return nil, err
+	}
+	invitation.InvitedBy = inviterEmail
+
+	msg, err := mail.GroupInvitationEmail(invitation.Email, s.emailLocale(ctx, invitation.Email, acceptLanguage),
+		inviterEmail, invitation.GroupName, invitation.Role, expiresAt)
+	if err != nil {
+		s.logger.Error("GroupInvitationEmail error", "error", err)
+	} else if err := s.sendEmail(ctx, msg); err != nil {
+		s.logger.Error("sendEmail error", "error", err)
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditGroupInvitation, map[string]string{
+		"group_id":      groupID,
+		"invitation_id": invitation.ID,
+		"email":         invitation.Email,
+		"role":          invitation.Role,
+	})
+	return invitation, nil
+}
+
+func (s *authServiceImpl) ListGroupInvitations(ctx context.Context, email string) (*models.GroupInvitationsList, error) {
+	invitations, err := s.storage.GroupRepository().GetPendingInvitations(ctx, email)
+	if err != nil {
+		s.logger.Error("GetPendingInvitations error", "error", err)
+		return nil, err
+	}
+	return &models.GroupInvitationsList{Invitations: invitations}, nil
+}
+
+// RespondToGroupInvitation accepts or declines an invitation addressed to the
+// user's email. Only invitations sent to that email can be answered.
+func (s *authServiceImpl) RespondToGroupInvitation(ctx context.Context, userID string, email string, invitationID string, accept bool) (*models.GroupInvitation, error) {
+	if _, err := uuid.Parse(invitationID); err != nil {
+		return nil, ErrInvitationNotFound
+	}
+
+	invitation, err := s.storage.GroupRepository().RespondToInvitation(ctx, invitationID, email, userID, accept)
+	if errors.Is(err, postgres.ErrInvitationNotFound) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		s.logger.Error("RespondToInvitation error", "error", err)
+		return nil, err
+	}
+
+	if accept {
+		s.recordAuditEvent(ctx, userID, postgres.AuditGroupJoined, map[string]string{
+			"group_id":      invitation.GroupID,
+			"invitation_id": invitation.ID,
+			"role":          invitation.Role,
+		})
+	}
+	return invitation, nil
+}
+
+// UpdateGroupMember changes the role of a member. Only owners can do it and
+// the last owner cannot be demoted.
+func (s *authServiceImpl) UpdateGroupMember(ctx context.Context, userID string, groupID string, memberID string, role string) (*models.Response, error) {
+	if !isGroupRole(role) {
+		return nil, ErrInvalidGroupRole
+	}
+	if _, err := uuid.Parse(memberID); err != nil {
+		return nil, ErrGroupMemberNotFound
+	}
+	if err := s.requireGroupOwner(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.storage.GroupRepository().UpdateMemberRole(ctx, groupID, memberID, role)
+	if err != nil {
+		return nil, s.groupMemberError("UpdateMemberRole", err)
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditGroupRoleChanged, map[string]string{
+		"group_id":  groupID,
+		"member_id": memberID,
+		"role":      role,
+	})
+	return resp, nil
+}
+
+// RemoveGroupMember removes a member from the group. Owners can remove
+// anyone; every member can remove themselves, which is how a group is left.
+func (s *authServiceImpl) RemoveGroupMember(ctx context.Context, userID string, groupID string, memberID string) (*models.Response, error) {
+	if _, err := uuid.Parse(memberID); err != nil {
+		return nil, ErrGroupMemberNotFound
+	}
+	if memberID == userID {
+		if _, err := s.groupRole(ctx, userID, groupID); err != nil {
+			return nil, err
+		}
+	} else if err := s.requireGroupOwner(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.storage.GroupRepository().RemoveMember(ctx, groupID, memberID)
+	if err != nil {
+		return nil, s.groupMemberError("RemoveMember", err)
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditGroupMemberRemoved, map[string]string{
+		"group_id":  groupID,
+		"member_id": memberID,
+	})
+	return resp, nil
+}
+
+// groupRole returns the user's role in the group. Non-members get
+// ErrGroupNotFound so that group IDs cannot be probed.
+func (s *authServiceImpl) groupRole(ctx context.Context, userID string, groupID string) (string, error) {
+	if _, err := uuid.Parse(groupID); err != nil {
+		return "", ErrGroupNotFound
+	}
+
+	role, err := s.storage.GroupRepository().GetMemberRole(ctx, groupID, userID)
+	if errors.Is(err, postgres.ErrNotGroupMember) {
+		return "", ErrGroupNotFound
+	}
+	if err != nil {
+		s.logger.Error("GetMemberRole error", "error", err)
+		return "", err
+	}
+	return role, nil
+}
+
+func (s *authServiceImpl) requireGroupOwner(ctx context.Context, userID string, groupID string) error {
+	role, err := s.groupRole(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+	if role != GroupOwner {
+		return ErrGroupForbidden
+	}
+	return nil
+}
+
+func (s *authServiceImpl) groupMemberError(op string, err error) error {
+	switch {
+	case errors.Is(err, postgres.ErrGroupNotFound):
+		return ErrGroupNotFound
+	case errors.Is(err, postgres.ErrNotGroupMember):
+		return ErrGroupMemberNotFound
+	case errors.Is(err, postgres.ErrLastGroupOwner):
+		return ErrLastGroupOwner
+	}
+	s.logger.Error(op+" error", "error", err)
+	return err
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/pkg/mail"
+	"auth-service/storage"
+	"auth-service/storage/postgres"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrEmailNotFound = apperr.New(apperr.NotFound, "email message not found")
+
+// SendPasswordResetEmail stores the reset code email in the outbox. The
+// EmailWorker delivers it in the background, so a nil error does not mean the
+// email has arrived.
+func (s *authServiceImpl) SendPasswordResetEmail(ctx context.Context, email string, code string, acceptLanguage string) error {
+	msg, err := mail.PasswordResetEmail(email, s.emailLocale(ctx, email, acceptLanguage), code)
+	if err != nil {
+		s.logger.Error("PasswordResetEmail error", "error", err)
+		return err
+	}
+	return s.sendEmail(ctx, msg)
+}
+
+func (s *authServiceImpl) SendVerificationEmail(ctx context.Context, email string, link string, acceptLanguage string) error {
+	msg, err := mail.VerificationEmail(email, s.emailLocale(ctx, email, acceptLanguage), link)
+	if err != nil {
+		s.logger.Error("VerificationEmail error", "error", err)
+		return err
+	}
+	return s.sendEmail(ctx, msg)
+}
+
+// emailLocale prefers the locale saved for the account over the
+// Accept-Language header of the request that triggered the email.
+func (s *authServiceImpl) emailLocale(ctx context.Context, email string, acceptLanguage string) string {
+	locale, err := s.storage.AuthRepository().GetUserLocale(ctx, email)
+	if err != nil {
+		s.logger.Error("GetUserLocale error", "error", err)
+	}
+	return mail.ResolveLocale(locale, acceptLanguage)
+}
+
+// sendNewDeviceAlert tells the user about a sign-in from a device the
+// account has not been used on before. The sign-in stands even if the alert
+// cannot be queued.
+func (s *authServiceImpl) sendNewDeviceAlert(ctx context.Context, email string, client models.ClientInfo) {
+	device := client.DeviceName
+	if device == "" {
+		device = client.UserAgent
+	}
+
+	msg, err := mail.NewDeviceLoginEmail(email, s.emailLocale(ctx, email, ""), device, client.IPAddress, time.Now())
+	if err != nil {
+		s.logger.Error("NewDeviceLoginEmail error", "error", err)
+		return
+	}
+	_ = s.sendEmail(ctx, msg)
+}
+
+func (s *authServiceImpl) sendEmail(ctx context.Context, msg mail.Message) error {
+	return enqueueEmail(ctx, s.storage, s.logger, msg)
+}
+
+// enqueueEmail stores msg in the outbox for the EmailWorker to deliver.
+func enqueueEmail(ctx context.Context, st storage.IStorage, logger *slog.Logger, msg mail.Message) error {
+	id, err := st.EmailOutboxRepository().EnqueueEmail(ctx, models.EmailMessage{
+		To:      msg.To,
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
+	})
+	if err != nil {
+		logger.Error("EnqueueEmail error", "error", err)
+		return err
+	}
+
+	logger.Info("Email queued", "id", id, "subject", msg.Subject)
+	return nil
+}
+
+func (s *authServiceImpl) GetEmailMessage(ctx context.Context, id string) (*models.EmailMessage, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrEmailNotFound
+	}
+
+	msg, err := s.storage.EmailOutboxRepository().GetEmail(ctx, id)
+	if errors.Is(err, postgres.ErrEmailNotFound) {
+		return nil, ErrEmailNotFound
+	}
+	if err != nil {
+		s.logger.Error("GetEmail error", "error", err)
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *authServiceImpl) ListEmailMessages(ctx context.Context, status string, limit int, offset int) (*models.EmailMessagesList, error) {
+	messages, err := s.storage.EmailOutboxRepository().ListEmails(ctx, status, limit, offset)
+	if err != nil {
+		s.logger.Error("ListEmails error", "error", err)
+		return nil, err
+	}
+	return &models.EmailMessagesList{Messages: messages}, nil
+}
+
+// RedriveEmailMessage queues a dead-lettered message for delivery again.
+func (s *authServiceImpl) RedriveEmailMessage(ctx context.Context, id string) (*models.Response, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrEmailNotFound
+	}
+
+	resp, err := s.storage.EmailOutboxRepository().RedriveEmail(ctx, id)
+	if errors.Is(err, postgres.ErrEmailNotFound) {
+		return nil, ErrEmailNotFound
+	}
+	if err != nil {
+		s.logger.Error("RedriveEmail error", "error", err)
+		return nil, err
+	}
+	return resp, nil
+}
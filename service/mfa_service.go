@@ -0,0 +1,272 @@
+package service
+
+import (
+	"auth-service/api/token"
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/storage"
+	"auth-service/storage/postgres"
+	"context"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = apperr.New(apperr.Conflict, "two-factor authentication is already enabled")
+	ErrMFANotEnabled       = apperr.New(apperr.Conflict, "two-factor authentication is not enabled")
+	ErrMFARequired         = apperr.New(apperr.Unauthenticated, "two-factor authentication is required")
+	ErrInvalidMFAToken     = apperr.New(apperr.Unauthenticated, "invalid or expired mfa token")
+	ErrInvalidMFACode      = apperr.New(apperr.InvalidArgument, "invalid verification code")
+	ErrTooManyMFAAttempts  = apperr.New(apperr.RateLimited, "too many verification attempts, sign in again")
+	ErrNoPendingEnrollment = apperr.New(apperr.NotFound, "no pending two-factor enrollment")
+)
+
+// maxMFAAttempts is the number of codes that can be tried with a single
+// mfa_pending token before the password has to be entered again.
+const maxMFAAttempts = 5
+
+var totpCodePattern = regexp.MustCompile(`^[0-9]{6}$`)
+
+func (s *authServiceImpl) IsMFAEnabled(ctx context.Context, userID string) (bool, error) {
+	mfa, err := s.storage.MFARepository().GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrMFANotFound) {
+			return false, nil
+		}
+		s.logger.Error("GetTOTP error", "error", err)
+		return false, err
+	}
+	return mfa.Enabled, nil
+}
+
+// StartMFAChallenge is called instead of StartSession after a correct password
+// when the user has a second factor.
+func (s *authServiceImpl) StartMFAChallenge(userID string) (*models.MFAPendingResp, error) {
+	mfaToken, err := token.GeneratedMFAPendingToken(userID)
+	if err != nil {
+		s.logger.Error("GeneratedMFAPendingToken error", "error", err)
+		return nil, err
+	}
+
+	return &models.MFAPendingResp{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		ExpiresIn:   int64(token.MFAPendingTokenTTL.Seconds()),
+	}, nil
+}
+
+// CompleteMFA checks a TOTP or recovery code against the user of an
+// mfa_pending token and returns that user, ready for StartSession.
+func (s *authServiceImpl) CompleteMFA(ctx context.Context, mfaToken string, code string) (*models.User, error) {
+	claims, err := token.ExtractMFAPendingClaims(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	attempts, err := s.storage.RedisStore().IncrementMFAAttempts(ctx, claims.Id, token.MFAPendingTokenTTL)
+	if err != nil {
+		s.logger.Error("IncrementMFAAttempts error", "error", err)
+		return nil, err
+	}
+	if attempts > maxMFAAttempts {
+		return nil, ErrTooManyMFAAttempts
+	}
+
+	if err := s.verifyMFACode(ctx, claims.ID, code); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.storage.UserRepository().GetUserProfile(ctx, claims.ID)
+	if err != nil {
+		s.logger.Error("GetUserProfile error", "error", err)
+		return nil, err
+	}
+
+	return &models.User{
+		ID:    profile.Id,
+		Email: profile.Email,
+		Role:  profile.Role,
+	}, nil
+}
+
+func (s *authServiceImpl) GetMFAStatus(ctx context.Context, userID string) (*models.MFAStatus, error) {
+	enabled, err := s.IsMFAEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return &models.MFAStatus{}, nil
+	}
+
+	left, err := s.storage.MFARepository().CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		s.logger.Error("CountRecoveryCodes error", "error", err)
+		return nil, err
+	}
+	return &models.MFAStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// EnrollTOTP generates a new secret for the user. It only takes effect once
+// ConfirmTOTP has seen a valid code for it.
+func (s *authServiceImpl) EnrollTOTP(ctx context.Context, userID string, email string) (*models.TOTPEnrollResp, error) {
+	enabled, err := s.IsMFAEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	key, err := token.GenerateTOTPKey(email)
+	if err != nil {
+		s.logger.Error("GenerateTOTPKey error", "error", err)
+		return nil, err
+	}
+
+	encrypted, err := token.EncryptSecret(key.Secret)
+	if err != nil {
+		s.logger.Error("EncryptSecret error", "error", err)
+		return nil, err
+	}
+
+	_, err = s.storage.MFARepository().SaveTOTPSecret(ctx, userID, encrypted)
+	if err != nil {
+		s.logger.Error("SaveTOTPSecret error", "error", err)
+		return nil, err
+	}
+
+	return &models.TOTPEnrollResp{
+		Secret:     key.Secret,
+		OtpauthURL: key.URL,
+		QRCode:     base64.StdEncoding.EncodeToString(key.QRCode),
+	}, nil
+}
+
+func (s *authServiceImpl) ConfirmTOTP(ctx context.Context, userID string, code string) (*models.RecoveryCodesResp, error) {
+	mfa, err := s.storage.MFARepository().GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, ErrNoPendingEnrollment
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := s.verifyTOTP(ctx, userID, mfa.SecretEncrypted, code); err != nil {
+		return nil, err
+	}
+
+	// The second factor is only enabled along with the recovery codes, so a
+	// user is never left without a way back in.
+	var resp *models.RecoveryCodesResp
+	err = s.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if _, err := tx.MFARepository().EnableTOTP(ctx, userID); err != nil {
+			s.logger.Error("EnableTOTP error", "error", err)
+			return err
+		}
+		var err error
+		resp, err = s.issueRecoveryCodes(ctx, tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditMFAEnabled, nil)
+	return resp, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the user. A current
+// TOTP code is required so a stolen access token alone cannot do it.
+func (s *authServiceImpl) RegenerateRecoveryCodes(ctx context.Context, userID string, code string) (*models.RecoveryCodesResp, error) {
+	mfa, err := s.storage.MFARepository().GetTOTP(ctx, userID)
+	if err != nil || !mfa.Enabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	if err := s.verifyTOTP(ctx, userID, mfa.SecretEncrypted, code); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(ctx, s.storage, userID)
+}
+
+// ResetMFA removes the second factor of a user who lost access to it.
+func (s *authServiceImpl) ResetMFA(ctx context.Context, userID string, adminID string) (*models.Response, error) {
+	resp, err := s.storage.MFARepository().DeleteMFA(ctx, userID)
+	if err != nil {
+		s.logger.Error("DeleteMFA error", "error", err)
+		return nil, err
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditMFAReset, map[string]string{"admin_id": adminID})
+	return resp, nil
+}
+
+func (s *authServiceImpl) verifyMFACode(ctx context.Context, userID string, code string) error {
+	mfa, err := s.storage.MFARepository().GetTOTP(ctx, userID)
+	if err != nil || !mfa.Enabled {
+		return ErrMFANotEnabled
+	}
+
+	if totpCodePattern.MatchString(code) {
+		return s.verifyTOTP(ctx, userID, mfa.SecretEncrypted, code)
+	}
+
+	used, err := s.storage.MFARepository().UseRecoveryCode(ctx, userID, token.HashToken(token.NormalizeRecoveryCode(code)))
+	if err != nil {
+		s.logger.Error("UseRecoveryCode error", "error", err)
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditRecoveryCodeUsed, nil)
+	return nil
+}
+
+func (s *authServiceImpl) verifyTOTP(ctx context.Context, userID string, encryptedSecret string, code string) error {
+	secret, err := token.DecryptSecret(encryptedSecret)
+	if err != nil {
+		s.logger.Error("DecryptSecret error", "error", err)
+		return err
+	}
+
+	step, ok := token.ValidateTOTP(secret, code)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	fresh, err := s.storage.RedisStore().MarkTOTPUsed(ctx, userID, step, 2*time.Minute)
+	if err != nil {
+		s.logger.Error("MarkTOTPUsed error", "error", err)
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *authServiceImpl) issueRecoveryCodes(ctx context.Context, st storage.IStorage, userID string) (*models.RecoveryCodesResp, error) {
+	codes, err := token.GenerateRecoveryCodes()
+	if err != nil {
+		s.logger.Error("GenerateRecoveryCodes error", "error", err)
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = token.HashToken(code)
+	}
+
+	_, err = st.MFARepository().ReplaceRecoveryCodes(ctx, userID, hashes)
+	if err != nil {
+		s.logger.Error("ReplaceRecoveryCodes error", "error", err)
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResp{RecoveryCodes: codes}, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"auth-service/api/token"
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/storage"
+	"auth-service/storage/postgres"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+const maxPersonalTokenNameLength = 100
+
+var (
+	ErrInvalidTokenName      = apperr.New(apperr.InvalidArgument, "token name must be between 1 and 100 characters")
+	ErrInvalidTokenExpiry    = apperr.New(apperr.InvalidArgument, "token expiry is out of range")
+	ErrPersonalTokenNotFound = apperr.New(apperr.NotFound, "personal access token not found")
+)
+
+// CreatePersonalToken issues a personal access token limited to scopes, which
+// must be permissions the user currently holds. The token is returned once
+// and only its hash is stored.
+func (s *authServiceImpl) CreatePersonalToken(ctx context.Context, userID string, req models.CreatePersonalTokenReq) (*models.PersonalTokenCreated, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > maxPersonalTokenNameLength {
+		return nil, ErrInvalidTokenName
+	}
+
+	cfg := config.Load()
+	ttl := cfg.PAT_DEFAULT_TTL
+	if req.ExpiresInDays != 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	if ttl <= 0 || ttl > cfg.PAT_MAX_TTL {
+		return nil, ErrInvalidTokenExpiry
+	}
+
+	permissions, err := s.storage.RoleRepository().GetUserPermissions(ctx, userID)
+	if err != nil {
+		s.logger.Error("GetUserPermissions error", "error", err)
+		return nil, err
+	}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(permissions, scope) {
+			return nil, ErrInvalidScope
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	raw, prefix, err := token.GeneratePersonalToken()
+	if err != nil {
+		s.logger.Error("GeneratePersonalToken error", "error", err)
+		return nil, err
+	}
+
+	pat, err := s.storage.PersonalTokenRepository().CreateToken(ctx, models.PersonalToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		TokenHash: token.HashToken(raw),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(ttl).Format(time.RFC3339),
+	})
+	if err != nil {
+		s.logger.Error("CreateToken error", "error", err)
+		return nil, err
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditPersonalTokenCreated, map[string]string{
+		"token_id": pat.ID,
+		"name":     pat.Name,
+		"scopes":   strings.Join(scopes, ","),
+	})
+	return &models.PersonalTokenCreated{PersonalToken: *pat, Token: raw}, nil
+}
+
+func (s *authServiceImpl) ListPersonalTokens(ctx context.Context, userID string) (*models.PersonalTokensList, error) {
+	tokens, err := s.storage.PersonalTokenRepository().GetUserTokens(ctx, userID)
+	if err != nil {
+		s.logger.Error("GetUserTokens error", "error", err)
+		return nil, err
+	}
+	return &models.PersonalTokensList{Tokens: tokens}, nil
+}
+
+func (s *authServiceImpl) RevokePersonalToken(ctx context.Context, userID string, id string) (*models.Response, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPersonalTokenNotFound
+	}
+
+	resp, err := s.storage.PersonalTokenRepository().RevokeToken(ctx, userID, id)
+	if errors.Is(err, postgres.ErrPersonalTokenNotFound) {
+		return nil, ErrPersonalTokenNotFound
+	}
+	if err != nil {
+		s.logger.Error("RevokeToken error", "error", err)
+		return nil, err
+	}
+
+	s.recordAuditEvent(ctx, userID, postgres.AuditPersonalTokenRevoked, map[string]string{
+		"token_id": id,
+	})
+	return resp, nil
+}
+
+func (s *authServiceImpl) AuthenticatePersonalToken(ctx context.Context, raw string) (*token.Claims, error) {
+	claims, err := personalTokenClaims(ctx, s.storage, raw)
+	if err != nil && !errors.Is(err, ErrPersonalTokenNotFound) {
+		s.logger.Error("AuthenticatePersonalToken error", "error", err)
+	}
+	return claims, err
+}
+
+// personalTokenClaims resolves a personal access token to the claims an access
+// token of its user would carry, with the permissions narrowed to the token's
+// scopes. Permissions are intersected with the user's current ones, so losing
+// a role also takes it away from the user's tokens.
+func personalTokenClaims(ctx context.Context, st storage.IStorage, raw string) (*token.Claims, error) {
+	pat, err := st.PersonalTokenRepository().GetTokenByHash(ctx, token.HashToken(raw))
+	if errors.Is(err, postgres.ErrPersonalTokenNotFound) {
+		return nil, ErrPersonalTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := st.RoleRepository().GetUserPermissions(ctx, pat.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var granted []string
+	for _, scope := range pat.Scopes {
+		if slices.Contains(permissions, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	groups, err := st.GroupRepository().GetUserMemberships(ctx, pat.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := st.PersonalTokenRepository().TouchToken(ctx, pat.ID); err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, pat.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token.Claims{
+		ID:          pat.UserID,
+		Email:       pat.Email,
+		Role:        pat.Role,
+		Type:        token.TypePersonal,
+		Scope:       strings.Join(pat.Scopes, " "),
+		Permissions: granted,
+		Groups:      groups,
+		StandardClaims: jwt.StandardClaims{
+			Id:        pat.ID,
+			Subject:   pat.UserID,
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/storage/postgres"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Permissions checked by this service. Other services define their own, such
+// as budgets:write, and check them through the CheckPermission RPC.
+const (
+	PermUsersRead      = "users:read"
+	PermUsersWrite     = "users:write"
+	PermRolesRead      = "roles:read"
+	PermRolesWrite     = "roles:write"
+	PermAccountsUnlock = "accounts:unlock"
+	PermMFAReset       = "mfa:reset"
+	PermEmailsRead     = "emails:read"
+	PermEmailsWrite    = "emails:write"
+	PermAuditRead      = "audit:read"
+)
+
+var (
+	ErrUnknownRole  = apperr.New(apperr.InvalidArgument, "unknown role")
+	ErrUserNotFound = apperr.New(apperr.NotFound, "user not found")
+)
+
+// UpdateUserRoles replaces the roles of a user. The change reaches the user's
+// access tokens the next time they are refreshed.
+func (s *authServiceImpl) UpdateUserRoles(ctx context.Context, manage models.ManageUserRoles, adminID string, client models.ClientInfo) (*models.Response, error) {
+	roles := manage.Roles
+	if len(roles) == 0 && manage.Role != "" {
+		roles = []string{manage.Role}
+	}
+
+	var unique []string
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if role != "" && !slices.Contains(unique, role) {
+			unique = append(unique, role)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, ErrUnknownRole
+	}
+
+	resp, err := s.storage.RoleRepository().SetUserRoles(ctx, manage.Email, unique)
+	if errors.Is(err, postgres.ErrUnknownRole) {
+		return nil, ErrUnknownRole
+	}
+	if errors.Is(err, postgres.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		s.logger.Error("SetUserRoles error", "error", err)
+		return nil, err
+	}
+
+	recordAudit(ctx, s.storage, s.logger, models.AuditEvent{
+		ActorID:   adminID,
+		EventType: postgres.AuditRolesChanged,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Metadata: map[string]string{
+			"email":    manage.Email,
+			"roles":    strings.Join(unique, ","),
+			"admin_id": adminID,
+		},
+	})
+	return resp, nil
+}
+
+func (s *authServiceImpl) ListRoles(ctx context.Context) (*models.RolesList, error) {
+	roles, err := s.storage.RoleRepository().ListRoles(ctx)
+	if err != nil {
+		s.logger.Error("ListRoles error", "error", err)
+		return nil, err
+	}
+	return &models.RolesList{Roles: roles}, nil
+}
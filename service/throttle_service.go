@@ -0,0 +1,176 @@
+package service
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/storage/postgres"
+	"context"
+	"strings"
+	"time"
+)
+
+var ErrTooManyAttempts = apperr.New(apperr.RateLimited, "too many attempts, try again later")
+
+// Actions that are throttled independently of each other.
+const (
+	ThrottleLogin          = "login"
+	ThrottleForgotPassword = "forgot_password"
+	ThrottleResetPassword  = "reset_password"
+	ThrottleDeleteAccount  = "delete_account"
+)
+
+// Failures are counted per email, per IP and per email+IP pair. The pair
+// gets an exponential backoff so a single client slows down quickly, the
+// email gets a lockout so a distributed attack on one account stops, and the
+// IP gets a much higher lockout threshold so one client cannot spray many
+// accounts.
+type throttleKeys struct {
+	email   string
+	ip      string
+	emailIP string
+}
+
+func newThrottleKeys(action, email, ip string) throttleKeys {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	keys := throttleKeys{ip: action + ":ip:" + ip}
+	if email != "" {
+		keys.email = action + ":email:" + email
+		keys.emailIP = action + ":email_ip:" + email + "|" + ip
+	}
+	return keys
+}
+
+func (k throttleKeys) all() []string {
+	if k.email == "" {
+		return []string{k.ip}
+	}
+	return []string{k.ip, k.email, k.emailIP}
+}
+
+// CheckThrottle returns how long the caller has to wait before the next
+// attempt, or zero when the attempt may go ahead.
+func (s *authServiceImpl) CheckThrottle(ctx context.Context, action string, email string, ip string) (time.Duration, error) {
+	wait, err := s.storage.RedisStore().LockedFor(ctx, newThrottleKeys(action, email, ip).all()...)
+	if err != nil {
+		s.logger.Error("LockedFor error", "error", err)
+		return 0, err
+	}
+	return wait, nil
+}
+
+// RecordFailure counts a failed attempt and returns how long the caller now
+// has to wait.
+func (s *authServiceImpl) RecordFailure(ctx context.Context, action string, email string, ip string) (time.Duration, error) {
+	cfg := config.Load()
+	keys := newThrottleKeys(action, email, ip)
+	store := s.storage.RedisStore()
+
+	ipFailures, err := store.RecordFailure(ctx, keys.ip, cfg.THROTTLE_FAILURE_WINDOW)
+	if err != nil {
+		s.logger.Error("RecordFailure error", "error", err)
+		return 0, err
+	}
+	if ipFailures >= int64(cfg.THROTTLE_IP_MAX_FAILURES) {
+		if err := store.Lock(ctx, keys.ip, cfg.THROTTLE_LOCKOUT); err != nil {
+			s.logger.Error("Lock error", "error", err)
+		}
+	}
+
+	if keys.email != "" {
+		emailFailures, err := store.RecordFailure(ctx, keys.email, cfg.THROTTLE_FAILURE_WINDOW)
+		if err != nil {
+			s.logger.Error("RecordFailure error", "error", err)
+			return 0, err
+		}
+		if emailFailures == int64(cfg.THROTTLE_MAX_FAILURES) {
+			s.logger.Warn("Account locked after repeated failures", "action", action, "email", email)
+			s.recordAuditEvent(ctx, "", postgres.AuditAccountLocked, map[string]string{
+				"action": action,
+				"email":  email,
+				"ip":     ip,
+			})
+		}
+		if emailFailures >= int64(cfg.THROTTLE_MAX_FAILURES) {
+			if err := store.Lock(ctx, keys.email, cfg.THROTTLE_LOCKOUT); err != nil {
+				s.logger.Error("Lock error", "error", err)
+			}
+		}
+
+		pairFailures, err := store.RecordFailure(ctx, keys.emailIP, cfg.THROTTLE_FAILURE_WINDOW)
+		if err != nil {
+			s.logger.Error("RecordFailure error", "error", err)
+			return 0, err
+		}
+		if wait := backoffDuration(pairFailures, cfg); wait > 0 {
+			if err := store.Lock(ctx, keys.emailIP, wait); err != nil {
+				s.logger.Error("Lock error", "error", err)
+			}
+		}
+	}
+
+	return s.CheckThrottle(ctx, action, email, ip)
+}
+
+// ClearFailures resets the email and email+IP counters after a successful
+// attempt. The IP counter is kept so that a valid login in between does not
+// hide credential spraying.
+func (s *authServiceImpl) ClearFailures(ctx context.Context, action string, email string, ip string) {
+	keys := newThrottleKeys(action, email, ip)
+	if keys.email == "" {
+		return
+	}
+
+	if err := s.storage.RedisStore().ClearFailures(ctx, keys.email, keys.emailIP); err != nil {
+		s.logger.Error("ClearFailures error", "error", err)
+	}
+}
+
+// UnlockAccount lifts every lockout and backoff on email, for all actions.
+func (s *authServiceImpl) UnlockAccount(ctx context.Context, email string, adminID string) (*models.Response, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	escaped := escapeGlob(email)
+
+	var removed int64
+	for _, pattern := range []string{"*:email:" + escaped, "*:email_ip:" + escaped + "|*"} {
+		n, err := s.storage.RedisStore().Unlock(ctx, pattern)
+		if err != nil {
+			s.logger.Error("Unlock error", "error", err)
+			return nil, err
+		}
+		removed += n
+	}
+
+	s.recordAuditEvent(ctx, "", postgres.AuditAccountUnlocked, map[string]string{
+		"email":    email,
+		"admin_id": adminID,
+	})
+
+	if removed == 0 {
+		return &models.Response{Status: "success", Message: "Account was not locked"}, nil
+	}
+	return &models.Response{Status: "success", Message: "Account unlocked successfully"}, nil
+}
+
+// backoffDuration doubles the wait for every failure after the first
+// THROTTLE_BACKOFF_AFTER ones, up to THROTTLE_BACKOFF_MAX.
+func backoffDuration(failures int64, cfg *config.Config) time.Duration {
+	over := failures - int64(cfg.THROTTLE_BACKOFF_AFTER)
+	if over <= 0 {
+		return 0
+	}
+
+	wait := cfg.THROTTLE_BACKOFF_BASE
+	for i := int64(1); i < over; i++ {
+		wait *= 2
+		if wait >= cfg.THROTTLE_BACKOFF_MAX {
+			return cfg.THROTTLE_BACKOFF_MAX
+		}
+	}
+	return min(wait, cfg.THROTTLE_BACKOFF_MAX)
+}
+
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
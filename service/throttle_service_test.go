@@ -0,0 +1,79 @@
+package service
+
+import (
+	"auth-service/config"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDuration(t *testing.T) {
+	cfg := &config.Config{
+		THROTTLE_BACKOFF_AFTER: 3,
+		THROTTLE_BACKOFF_BASE:  time.Second,
+		THROTTLE_BACKOFF_MAX:   10 * time.Second,
+	}
+
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{1000, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, backoffDuration(tt.failures, cfg), "%d failures", tt.failures)
+	}
+
+	cfg.THROTTLE_BACKOFF_BASE = time.Minute
+	assert.Equal(t, 10*time.Second, backoffDuration(4, cfg))
+}
+
+func TestNewThrottleKeys(t *testing.T) {
+	keys := newThrottleKeys(ThrottleLogin, "  Test_Email@Test.com ", "10.0.0.1")
+	assert.Equal(t, throttleKeys{
+		email:   "login:email:test_email@test.com",
+		ip:      "login:ip:10.0.0.1",
+		emailIP: "login:email_ip:test_email@test.com|10.0.0.1",
+	}, keys)
+	assert.Equal(t, []string{keys.ip, keys.email, keys.emailIP}, keys.all())
+
+	keys = newThrottleKeys(ThrottleForgotPassword, " ", "10.0.0.1")
+	assert.Equal(t, throttleKeys{ip: "forgot_password:ip:10.0.0.1"}, keys)
+	assert.Equal(t, []string{"forgot_password:ip:10.0.0.1"}, keys.all())
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, "plain@test.com", escapeGlob("plain@test.com"))
+	assert.Equal(t, `a\*b\?c\[d\]e\\f`, escapeGlob(`a*b?c[d]e\f`))
+
+	// The escaped email only matches itself in UnlockAccount's patterns, which
+	// follow the same rules as path.Match.
+	email := "*@test.com"
+	keys := newThrottleKeys(ThrottleLogin, email, "10.0.0.1")
+	other := newThrottleKeys(ThrottleLogin, "victim@test.com", "10.0.0.1")
+	for _, tt := range []struct {
+		pattern string
+		key     string
+		other   string
+	}{
+		{"*:email:" + escapeGlob(email), keys.email, other.email},
+		{"*:email_ip:" + escapeGlob(email) + "|*", keys.emailIP, other.emailIP},
+	} {
+		matched, err := path.Match(tt.pattern, tt.key)
+		assert.NoError(t, err)
+		assert.True(t, matched, tt.pattern)
+
+		matched, err = path.Match(tt.pattern, tt.other)
+		assert.NoError(t, err)
+		assert.False(t, matched, tt.pattern)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"auth-service/api/token"
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrEmailNotVerified         = apperr.New(apperr.PermissionDenied, "email address is not verified")
+	ErrInvalidVerificationToken = apperr.New(apperr.InvalidArgument, "invalid or expired verification token")
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+
+	// verificationResendInterval limits how often a verification email can be
+	// sent to the same address.
+	verificationResendInterval = time.Minute
+)
+
+// CreateEmailVerification issues a single-use token for email and returns the
+// link that has to be sent to it.
+func (s *authServiceImpl) CreateEmailVerification(ctx context.Context, email string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	verificationToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	_, err := s.storage.RedisStore().StoreEmailVerification(ctx, token.HashToken(verificationToken), email, emailVerificationTTL)
+	if err != nil {
+		s.logger.Error("StoreEmailVerification error", "error", err)
+		return "", err
+	}
+
+	_, err = s.storage.RedisStore().MarkVerificationSent(ctx, email, verificationResendInterval)
+	if err != nil {
+		s.logger.Error("MarkVerificationSent error", "error", err)
+	}
+
+	return config.Load().ISSUER_URL + "/api/v1/auth/verify-email?token=" + url.QueryEscape(verificationToken), nil
+}
+
+func (s *authServiceImpl) VerifyEmail(ctx context.Context, verificationToken string) (*models.Response, error) {
+	email, err := s.storage.RedisStore().ConsumeEmailVerification(ctx, token.HashToken(verificationToken))
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	resp, err := s.storage.AuthRepository().VerifyEmail(ctx, email)
+	if err != nil {
+		s.logger.Error("VerifyEmail error", "error", err)
+		return nil, ErrInvalidVerificationToken
+	}
+	return resp, nil
+}
+
+// ResendVerification returns a new verification link for a pending account,
+// or an empty link when there is nothing to send: the address is unknown,
+// already verified, or was sent a link less than a minute ago. Callers must
+// not reveal which of these happened.
+func (s *authServiceImpl) ResendVerification(ctx context.Context, email string) (string, error) {
+	pending, err := s.storage.AuthRepository().IsEmailPending(ctx, email)
+	if err != nil {
+		s.logger.Error("IsEmailPending error", "error", err)
+		return "", err
+	}
+	if !pending {
+		return "", nil
+	}
+
+	fresh, err := s.storage.RedisStore().MarkVerificationSent(ctx, email, verificationResendInterval)
+	if err != nil {
+		s.logger.Error("MarkVerificationSent error", "error", err)
+		return "", err
+	}
+	if !fresh {
+		return "", nil
+	}
+
+	return s.CreateEmailVerification(ctx, email)
+}
+
+func withinVerificationGracePeriod(createdAt string) bool {
+	grace := config.Load().EMAIL_VERIFICATION_GRACE_PERIOD
+	if grace <= 0 {
+		return false
+	}
+
+	created, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return false
+	}
+	return time.Since(created) < grace
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"auth-service/storage/postgres"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidWebAuthnSession = apperr.New(apperr.InvalidArgument, "webauthn ceremony not found or expired")
+	ErrWebAuthnFailed         = apperr.New(apperr.Unauthenticated, "webauthn verification failed")
+	ErrPasskeyNotRegistered   = apperr.New(apperr.InvalidArgument, "passkey could not be registered")
+)
+
+const webAuthnSessionTTL = 5 * time.Minute
+
+// webAuthnUser adapts a user and their stored credentials to webauthn.User.
+// The user handle is the user ID, so a discoverable login can find the user
+// from the assertion alone.
+type webAuthnUser struct {
+	id          string
+	email       string
+	displayName string
+	credentials []models.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(u.id)
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.displayName
+}
+
+func (u *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, len(c.Transports))
+		for j, t := range c.Transports {
+			transports[j] = protocol.AuthenticatorTransport(t)
+		}
+
+		credentials[i] = webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		}
+	}
+	return credentials
+}
+
+func newWebAuthn() (*webauthn.WebAuthn, error) {
+	cfg := config.Load()
+
+	var origins []string
+	for _, origin := range strings.Split(cfg.WEBAUTHN_RP_ORIGINS, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.WEBAUTHN_RP_ID,
+		RPDisplayName: cfg.WEBAUTHN_RP_NAME,
+		RPOrigins:     origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationRequired,
+		},
+	})
+}
+
+func (s *authServiceImpl) loadWebAuthnUser(ctx context.Context, userID string) (*webAuthnUser, error) {
+	profile, err := s.storage.UserRepository().GetUserProfile(ctx, userID)
+	if err != nil {
+		s.logger.Error("GetUserProfile error", "error", err)
+		return nil, err
+	}
+
+	credentials, err := s.storage.WebAuthnRepository().GetUserCredentials(ctx, userID)
+	if err != nil {
+		s.logger.Error("GetUserCredentials error", "error", err)
+		return nil, err
+	}
+
+	return &webAuthnUser{
+		id:          profile.Id,
+		email:       profile.Email,
+		displayName: strings.TrimSpace(profile.FirstName + " " + profile.LastName),
+		credentials: credentials,
+	}, nil
+}
+
+func (s *authServiceImpl) storeWebAuthnSession(ctx context.Context, key string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.storage.RedisStore().StoreWebAuthnSession(ctx, key, data, webAuthnSessionTTL)
+	if err != nil {
+		s.logger.Error("StoreWebAuthnSession error", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s *authServiceImpl) consumeWebAuthnSession(ctx context.Context, key string) (*webauthn.SessionData, error) {
+	data, err := s.storage.RedisStore().ConsumeWebAuthnSession(ctx, key)
+	if err != nil {
+		return nil, ErrInvalidWebAuthnSession
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *authServiceImpl) BeginWebAuthnRegistration(ctx context.Context, userID string) (*models.WebAuthnBeginResp, error) {
+	wa, err := newWebAuthn()
+	if err != nil {
+		s.logger.Error("WebAuthn config error", "error", err)
+		return nil, err
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := make([]protocol.CredentialDescriptor, 0, len(user.credentials))
+	for _, c := range user.WebAuthnCredentials() {
+		exclude = append(exclude, c.Descriptor())
+	}
+
+	creation, session, err := wa.BeginRegistration(user, webauthn.WithExclusions(exclude))
+	if err != nil {
+		s.logger.Error("BeginRegistration error", "error", err)
+		return nil, err
+	}
+
+	sessionID := uuid.NewString()
+	if err := s.storeWebAuthnSession(ctx, "register:"+userID+":"+sessionID, session); err != nil {
+		return nil, err
+	}
+
+	return &models.WebAuthnBeginResp{SessionID: sessionID, Options: creation}, nil
+}
+
+func (s *authServiceImpl) FinishWebAuthnRegistration(ctx context.Context, userID string, sessionID string, name string, body io.Reader) (*models.WebAuthnCredential, error) {
+	session, err := s.consumeWebAuthnSession(ctx, "register:"+userID+":"+sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	wa, err := newWebAuthn()
+	if err != nil {
+		s.logger.Error("WebAuthn config error", "error", err)
+		return nil, err
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(body)
+	if err != nil {
+		s.logger.Error("ParseCredentialCreationResponseBody error", "error", err)
+		return nil, ErrPasskeyNotRegistered
+	}
+
+	credential, err := wa.CreateCredential(user, *session, parsed)
+	if err != nil {
+		s.logger.Error("CreateCredential error", "error", err)
+		return nil, ErrPasskeyNotRegistered
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+
+	stored := models.WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+
+	_, err = s.storage.WebAuthnRepository().CreateCredential(ctx, stored)
+	if err != nil {
+		s.logger.Error("CreateCredential error", "error", err)
+		return nil, err
+	}
+
+	return &stored, nil
+}
+
+// BeginWebAuthnLogin starts a passkey login. Without an email the ceremony
+// is discoverable: the authenticator picks the credential and tells us the
+// user through its user handle.
+func (s *authServiceImpl) BeginWebAuthnLogin(ctx context.Context, email string) (*models.WebAuthnBeginResp, error) {
+	wa, err := newWebAuthn()
+	if err != nil {
+		s.logger.Error("WebAuthn config error", "error", err)
+		return nil, err
+	}
+
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+	)
+	if email == "" {
+		assertion, session, err = wa.BeginDiscoverableLogin()
+	} else {
+		var user *webAuthnUser
+		user, err = s.webAuthnUserByEmail(ctx, email)
+		if err != nil {
+			return nil, ErrWebAuthnFailed
+		}
+		assertion, session, err = wa.BeginLogin(user)
+	}
+	if err != nil {
+		s.logger.Error("BeginLogin error", "error", err)
+		return nil, ErrWebAuthnFailed
+	}
+
+	sessionID := uuid.NewString()
+	if err := s.storeWebAuthnSession(ctx, "login:"+sessionID, session); err != nil {
+		return nil, err
+	}
+
+	return &models.WebAuthnBeginResp{SessionID: sessionID, Options: assertion}, nil
+}
+
+// FinishWebAuthnLogin verifies the assertion and returns the user, ready for
+// StartSession.
+func (s *authServiceImpl) FinishWebAuthnLogin(ctx context.Context, sessionID string, body io.Reader) (*models.User, error) {
+	session, err := s.consumeWebAuthnSession(ctx, "login:"+sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	wa, err := newWebAuthn()
+	if err != nil {
+		s.logger.Error("WebAuthn config error", "error", err)
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(body)
+	if err != nil {
+		s.logger.Error("ParseCredentialRequestResponseBody error", "error", err)
+		return nil, ErrWebAuthnFailed
+	}
+
+	var user *webAuthnUser
+	var credential *webauthn.Credential
+	if session.UserID == nil {
+		credential, err = wa.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			user, err = s.loadWebAuthnUser(ctx, string(userHandle))
+			return user, err
+		}, *session, parsed)
+	} else {
+		user, err = s.loadWebAuthnUser(ctx, string(session.UserID))
+		if err != nil {
+			return nil, ErrWebAuthnFailed
+		}
+		credential, err = wa.ValidateLogin(user, *session, parsed)
+	}
+	if err != nil {
+		s.logger.Error("ValidateLogin error", "error", err)
+		return nil, ErrWebAuthnFailed
+	}
+
+	if credential.Authenticator.CloneWarning {
+		s.logger.Warn("WebAuthn sign counter went backwards", "user_id", user.id)
+		s.recordAuditEvent(ctx, user.id, postgres.AuditWebAuthnCloneWarning, nil)
+		return nil, ErrWebAuthnFailed
+	}
+
+	_, err = s.storage.WebAuthnRepository().UpdateSignCount(ctx, credential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState)
+	if err != nil {
+		s.logger.Error("UpdateSignCount error", "error", err)
+		return nil, err
+	}
+
+	profile, err := s.storage.UserRepository().GetUserProfile(ctx, user.id)
+	if err != nil {
+		s.logger.Error("GetUserProfile error", "error", err)
+		return nil, err
+	}
+
+	return &models.User{
+		ID:    profile.Id,
+		Email: profile.Email,
+		Role:  profile.Role,
+	}, nil
+}
+
+func (s *authServiceImpl) webAuthnUserByEmail(ctx context.Context, email string) (*webAuthnUser, error) {
+	user, err := s.storage.AuthRepository().LoginUser(ctx, models.LoginUserReq{Email: email})
+	if err != nil {
+		s.logger.Error("LoginUser error", "error", err)
+		return nil, err
+	}
+
+	return s.loadWebAuthnUser(ctx, user.ID)
+}
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AccountDeletionRepository interface {
+	ScheduleDeletion(ctx context.Context, userID string, deleteAt time.Time) (*models.Response, error)
+	CancelDeletion(ctx context.Context, userID string) (bool, error)
+	GetDueDeletions(ctx context.Context, limit int) ([]string, error)
+	AnonymizeUser(ctx context.Context, userID string) (bool, error)
+}
+
+type accountDeletionRepositoryImpl struct {
+	db DBTX
+}
+
+func NewAccountDeletionRepository(db DBTX) AccountDeletionRepository {
+	return &accountDeletionRepositoryImpl{db: db}
+}
+
+// ScheduleDeletion marks the account for deletion at deleteAt, replacing an
+// earlier date.
+func (a *accountDeletionRepositoryImpl) ScheduleDeletion(ctx context.Context, userID string, deleteAt time.Time) (*models.Response, error) {
+	res, err := a.db.ExecContext(ctx, `
+		UPDATE users
+		SET deletion_scheduled_at = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`, userID, deleteAt)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if affected == 0 {
+		return &models.Response{Status: "error", Message: "User not found"}, ErrUserNotFound
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Account deletion scheduled",
+	}, nil
+}
+
+// CancelDeletion clears a pending deletion and reports whether there was one.
+// An account that has already been anonymized cannot be brought back.
+func (a *accountDeletionRepositoryImpl) CancelDeletion(ctx context.Context, userID string) (bool, error) {
+	res, err := a.db.ExecContext(ctx, `
+		UPDATE users
+		SET deletion_scheduled_at = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL AND deletion_scheduled_at IS NOT NULL
+	`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// GetDueDeletions returns up to limit accounts whose deletion date has
+// passed, oldest first.
+func (a *accountDeletionRepositoryImpl) GetDueDeletions(ctx context.Context, limit int) ([]string, error) {
+	rows, err := a.db.QueryContext(ctx, `
+		SELECT id
+		FROM users
+		WHERE deletion_scheduled_at <= CURRENT_TIMESTAMP
+		ORDER BY deletion_scheduled_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// AnonymizeUser erases the personal data of an account whose deletion is due,
+// completes the deletion and emits user.deleted. It reports false when the
+// deletion was cancelled meanwhile. The users row is kept, with a placeholder
+// email and no name or password, so that references to the ID stay valid;
+// sessions, tokens, second factors, data exports and queued emails are
+// removed.
+// Groups the user owned alone pass to their longest-standing member, or are
+// removed when the user was their only member. The audit log is append-only
+// and keeps its entries.
+func (a *accountDeletionRepositoryImpl) AnonymizeUser(ctx context.Context, userID string) (bool, error) {
+	tx, err := beginTx(ctx, a.db)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var email string
+	err = tx.QueryRowContext(ctx, `
+		SELECT email
+		FROM users
+		WHERE id = $1 AND deletion_scheduled_at <= CURRENT_TIMESTAMP
+		FOR UPDATE
+	`, userID).Scan(&email)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if err := anonymizeUser(ctx, tx, userID, email); err != nil {
+		return false, err
+	}
+
+	err = enqueueEvent(ctx, tx, models.Event{
+		// Derived from the user, so the event is written only once.
+		ID:     uuid.NewSHA1(uuid.NameSpaceOID, []byte(EventUserDeleted+":"+userID)).String(),
+		Type:   EventUserDeleted,
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE users SET deletion_scheduled_at = NULL WHERE id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func anonymizeUser(ctx context.Context, tx DBTX, userID string, email string) error {
+	for _, query := range []string{
+		`DELETE FROM sessions WHERE user_id = $1`,
+		`DELETE FROM personal_access_tokens WHERE user_id = $1`,
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+		`DELETE FROM webauthn_credentials WHERE user_id = $1`,
+		// Archives of ready exports are removed by the export worker once
+		// they have expired.
+		`UPDATE data_exports SET expires_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND status = 'ready'`,
+		`DELETE FROM data_exports WHERE user_id = $1 AND status <> 'ready'`,
+		`UPDATE group_members m
+		SET role = 'owner'
+		FROM (
+			SELECT DISTINCT ON (h.group_id) h.group_id, h.user_id
+			FROM group_members h
+			JOIN group_members o ON o.group_id = h.group_id AND o.user_id = $1 AND o.role = 'owner'
+			WHERE h.user_id <> $1
+				AND NOT EXISTS (
+					SELECT 1 FROM group_members x
+					WHERE x.group_id = h.group_id AND x.role = 'owner' AND x.user_id <> $1
+				)
+			ORDER BY h.group_id, h.joined_at
+		) heir
+		WHERE m.group_id = heir.group_id AND m.user_id = heir.user_id`,
+		`DELETE FROM groups g
+		WHERE g.id IN (SELECT group_id FROM group_members WHERE user_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM group_members m WHERE m.group_id = g.id AND m.user_id <> $1)`,
+		`DELETE FROM group_members WHERE user_id = $1`,
+		`UPDATE users
+		SET email = 'deleted-' || id || '@deleted.invalid',
+			first_name = '',
+			last_name = '',
+			password_hash = '',
+			locale = '',
+			deleted_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+
+	for _, query := range []string{
+		`DELETE FROM group_invitations WHERE email = $1`,
+		`DELETE FROM email_outbox WHERE recipient = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
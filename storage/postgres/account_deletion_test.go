@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleAndCancelDeletion(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewAccountDeletionRepository(db)
+	userID := "d70789c8-37e0-4de6-8195-d900abc0afb5"
+
+	resp, err := repo.ScheduleDeletion(context.Background(), userID, time.Now().Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp.Status)
+
+	due, err := repo.GetDueDeletions(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.NotContains(t, due, userID)
+
+	anonymized, err := repo.AnonymizeUser(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.False(t, anonymized)
+
+	cancelled, err := repo.CancelDeletion(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+
+	cancelled, err = repo.CancelDeletion(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+
+	_, err = repo.ScheduleDeletion(context.Background(), "00000000-0000-0000-0000-000000000000", time.Now())
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"context"
+	"encoding/json"
+)
+
+const (
+	AuditRefreshTokenReuse = "refresh_token_reuse"
+	AuditMFAEnabled        = "mfa_enabled"
+	AuditMFAReset          = "mfa_reset"
+	AuditRecoveryCodeUsed  = "mfa_recovery_code_used"
+
+	AuditWebAuthnCloneWarning = "webauthn_clone_warning"
+
+	AuditAccountLocked   = "account_locked"
+	AuditAccountUnlocked = "account_unlocked"
+
+	AuditRolesChanged = "roles_changed"
+
+	AuditGroupCreated       = "group_created"
+	AuditGroupDeleted       = "group_deleted"
+	AuditGroupInvitation    = "group_invitation_sent"
+	AuditGroupJoined        = "group_joined"
+	AuditGroupRoleChanged   = "group_role_changed"
+	AuditGroupMemberRemoved = "group_member_removed"
+
+	AuditPersonalTokenCreated = "personal_token_created"
+	AuditPersonalTokenRevoked = "personal_token_revoked"
+
+	AuditLoginSucceeded  = "login_succeeded"
+	AuditLoginFailed     = "login_failed"
+	AuditLogout          = "logout"
+	AuditTokenRefreshed  = "token_refreshed"
+	AuditPasswordChanged = "password_changed"
+	AuditPasswordReset   = "password_reset"
+	AuditProfileUpdated  = "profile_updated"
+	AuditAccountDeleted  = "account_deleted"
+
+	AuditEmailChangeRequested = "email_change_requested"
+	AuditEmailChanged         = "email_changed"
+
+	AuditAccountDeletionRequested = "account_deletion_requested"
+	AuditAccountDeletionCancelled = "account_deletion_cancelled"
+
+	AuditDataExportRequested  = "data_export_requested"
+	AuditDataExportDownloaded = "data_export_downloaded"
+)
+
+type AuditRepository interface {
+	RecordEvent(ctx context.Context, event models.AuditEvent) (*models.Response, error)
+	ListEvents(ctx context.Context, filter models.AuditEventFilter) ([]models.AuditEvent, error)
+	CountEvents(ctx context.Context, filter models.AuditEventFilter) (int, error)
+}
+
+type auditRepositoryImpl struct {
+	db DBTX
+}
+
+func NewAuditRepository(db DBTX) AuditRepository {
+	return &auditRepositoryImpl{db: db}
+}
+
+// RecordEvent appends event to the audit log. Events that only know the
+// account by the email in their metadata, such as a lockout, are attributed
+// to the account with that email.
+func (a *auditRepositoryImpl) RecordEvent(ctx context.Context, event models.AuditEvent) (*models.Response, error) {
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if event.Metadata == nil {
+		metadata = []byte("{}")
+	}
+
+	_, err = a.db.ExecContext(ctx, `
+		INSERT INTO audit_events (
+			user_id,
+			actor_id,
+			event_type,
+			ip_address,
+			user_agent,
+			metadata
+		)
+			VALUES (
+				COALESCE(NULLIF($1, '')::UUID, (SELECT id FROM users WHERE email = $6::JSONB->>'email')),
+				NULLIF($2, '')::UUID,
+				$3, $4, $5, $6
+			)
+	`, event.UserID, event.ActorID, event.EventType, event.IPAddress, event.UserAgent, metadata)
+
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	return &models.Response{
+		Status:  "success",
+		Message: "Audit event recorded successfully",
+	}, nil
+}
+
+// auditEventsWhere filters audit_events by the fields of
+// models.AuditEventFilter, passed as $1 to $6 in auditFilterArgs order.
+const auditEventsWhere = `
+		WHERE
+			($1 = '' OR user_id = NULLIF($1, '')::UUID) AND
+			($2 = '' OR actor_id = NULLIF($2, '')::UUID) AND
+			($3 = '' OR event_type = $3) AND
+			($4 = '' OR ip_address = $4) AND
+			($5 = '' OR created_at >= NULLIF($5, '')::TIMESTAMPTZ) AND
+			($6 = '' OR created_at < NULLIF($6, '')::TIMESTAMPTZ)
+`
+
+func auditFilterArgs(filter models.AuditEventFilter) []interface{} {
+	return []interface{}{filter.UserID, filter.ActorID, filter.EventType, filter.IPAddress, filter.From, filter.To}
+}
+
+// ListEvents returns a page of the events matching filter, newest first.
+func (a *auditRepositoryImpl) ListEvents(ctx context.Context, filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	rows, err := a.db.QueryContext(ctx, `
+		SELECT
+			id,
+			COALESCE(user_id::TEXT, ''),
+			COALESCE(actor_id::TEXT, ''),
+			event_type,
+			ip_address,
+			user_agent,
+			metadata,
+			created_at
+		FROM
+			audit_events`+auditEventsWhere+`
+		ORDER BY
+			created_at DESC, id
+		LIMIT $7 OFFSET $8
+	`, append(auditFilterArgs(filter), filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var (
+			event    models.AuditEvent
+			metadata []byte
+		)
+		err := rows.Scan(&event.ID, &event.UserID, &event.ActorID, &event.EventType,
+			&event.IPAddress, &event.UserAgent, &metadata, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(metadata, &event.Metadata); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// CountEvents returns how many events match filter, ignoring its limit and
+// offset.
+func (a *auditRepositoryImpl) CountEvents(ctx context.Context, filter models.AuditEventFilter) (int, error) {
+	var count int
+	err := a.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*)
+		FROM
+			audit_events`+auditEventsWhere, auditFilterArgs(filter)...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
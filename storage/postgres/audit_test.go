@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordEvent(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewAuditRepository(db)
+
+	resp, err := repo.RecordEvent(context.Background(), models.AuditEvent{
+		UserID:    "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		EventType: AuditRefreshTokenReuse,
+		IPAddress: "127.0.0.1",
+		UserAgent: "go-test",
+		Metadata:  map[string]string{"session_id": "0f7c7b1e-6d1c-4f55-9c1e-3b2a7d9e8f10"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.Status, "success")
+}
+
+func TestListEvents(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewAuditRepository(db)
+
+	_, err = repo.RecordEvent(context.Background(), models.AuditEvent{
+		UserID:    "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		EventType: AuditLoginFailed,
+		IPAddress: "127.0.0.1",
+		UserAgent: "go-test",
+		Metadata:  map[string]string{"reason": "invalid_password"},
+	})
+	assert.NoError(t, err)
+
+	filter := models.AuditEventFilter{
+		UserID:    "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		EventType: AuditLoginFailed,
+		Limit:     10,
+	}
+	events, err := repo.ListEvents(context.Background(), filter)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, events) {
+		assert.Equal(t, AuditLoginFailed, events[0].EventType)
+		assert.Equal(t, "invalid_password", events[0].Metadata["reason"])
+	}
+
+	total, err := repo.CountEvents(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, total, len(events))
+}
@@ -2,33 +2,43 @@ package postgres
 
 import (
 	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
 	"database/sql"
-	"fmt"
+)
+
+var ErrPendingAccountNotFound = apperr.New(apperr.NotFound, "pending account not found")
+
+const (
+	StatusPending = "pending"
+	StatusActive  = "active"
+
+	// DefaultRole is the role of a newly registered user.
+	DefaultRole = "user"
 )
 
 type AuthenticationRepository interface {
-	EmailExists(email string) (bool, error)
-	RegisterUser(user models.RegisterUser) (*models.Response, error)
-	LoginUser(login models.LoginUserReq) (*models.User, error)
-	LogOutUser(id string) (*models.Response, error)
-	ResetPassword(email string, newPassword string) (*models.Response, error)
-	SaveRefreshToken(refreshToken models.RefreshToken) (*models.Response, error)
-	InvalidateRefreshToken(email string) (*models.Response, error)
-	IsRefreshTokenValid(email string) (bool, error)
-	ManageUserRoles(email string, role string) (*models.Response, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	RegisterUser(ctx context.Context, user models.RegisterUser) (*models.Response, error)
+	LoginUser(ctx context.Context, login models.LoginUserReq) (*models.User, error)
+	ResetPassword(ctx context.Context, email string, newPassword string) (string, error)
+	UpdatePasswordHash(ctx context.Context, id string, passwordHash string) (*models.Response, error)
+	VerifyEmail(ctx context.Context, email string) (*models.Response, error)
+	IsEmailPending(ctx context.Context, email string) (bool, error)
+	GetUserLocale(ctx context.Context, email string) (string, error)
 }
 
 type authenticationRepositoryImpl struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewAuthenticationRepository(db *sql.DB) AuthenticationRepository {
+func NewAuthenticationRepository(db DBTX) AuthenticationRepository {
 	return &authenticationRepositoryImpl{db: db}
 }
 
-func (a *authenticationRepositoryImpl) EmailExists(email string) (bool, error) {
+func (a *authenticationRepositoryImpl) EmailExists(ctx context.Context, email string) (bool, error) {
 	var exists bool
-	err := a.db.QueryRow(`
+	err := a.db.QueryRowContext(ctx, `
         SELECT 
 			EXISTS (SELECT 1 FROM users WHERE email = $1)
 		
@@ -41,43 +51,75 @@ func (a *authenticationRepositoryImpl) EmailExists(email string) (bool, error) {
 	return exists, nil
 }
 
-func (a *authenticationRepositoryImpl) RegisterUser(user models.RegisterUser) (*models.Response, error) {
-	_, err := a.db.Exec(`
-		INSERT INTO users (
-			email, 
-			first_name, 
-			last_name, 
-			password_hash,
-			role
+// RegisterUser creates a pending account with the default role and emits
+// user.registered.
+func (a *authenticationRepositoryImpl) RegisterUser(ctx context.Context, user models.RegisterUser) (*models.Response, error) {
+	tx, err := beginTx(ctx, a.db)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	err = tx.QueryRowContext(ctx, `
+		WITH inserted AS (
+			INSERT INTO users (
+				email, 
+				first_name, 
+				last_name, 
+				password_hash,
+				role,
+				status,
+				locale
+			)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, role
 		)
-			VALUES ($1, $2, $3, $4, $5)
-    `, user.Email, user.FirstName, user.LastName, user.Password, "user")
+		INSERT INTO user_roles (user_id, role)
+			SELECT id, role FROM inserted
+		RETURNING user_id
+    `, user.Email, user.FirstName, user.LastName, user.Password, DefaultRole, StatusPending, user.Locale).Scan(&userID)
 
 	if err != nil {
 		return &models.Response{Status: "error", Message: err.Error()}, err
 	}
+
+	err = enqueueEvent(ctx, tx, models.Event{
+		Type:   EventUserRegistered,
+		UserID: userID,
+		Data:   map[string]string{"email": user.Email, "role": DefaultRole},
+	})
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
 	return &models.Response{
 		Status:  "success",
 		Message: "User registered successfully",
 	}, nil
 }
 
-func (a *authenticationRepositoryImpl) LoginUser(login models.LoginUserReq) (*models.User, error) {
+func (a *authenticationRepositoryImpl) LoginUser(ctx context.Context, login models.LoginUserReq) (*models.User, error) {
 	var user models.User
-	err := a.db.QueryRow(`
+	err := a.db.QueryRowContext(ctx, `
 		SELECT
 			id,
 			email,
             password_hash,
-			role
+			role,
+			status,
+			locale,
+			created_at
 		FROM
 			users
 		WHERE 
 			deleted_at IS NULL AND email = $1
-	`, login.Email).Scan(&user.ID, &user.Email, &user.Password, &user.Role)
+	`, login.Email).Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.Status, &user.Locale, &user.CreatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found %s", login.Email)
+		return nil, ErrUserNotFound
 	} else if err != nil {
 		return nil, err
 	}
@@ -85,125 +127,99 @@ func (a *authenticationRepositoryImpl) LoginUser(login models.LoginUserReq) (*mo
 	return &user, nil
 }
 
-func (a *authenticationRepositoryImpl) LogOutUser(id string) (*models.Response, error) {
-	_, err := a.db.Exec(`
+// ResetPassword sets the password of the account with email and returns its
+// ID, or an empty ID when there is no such account.
+func (a *authenticationRepositoryImpl) ResetPassword(ctx context.Context, email string, newPassword string) (string, error) {
+	var id string
+	err := a.db.QueryRowContext(ctx, `
         UPDATE users
-        SET deleted_at = CURRENT_TIMESTAMP
-        WHERE id = $1
-    `, id)
+        SET password_hash = $1
+        WHERE email = $2 AND deleted_at IS NULL
+        RETURNING id
+    `, newPassword, email).Scan(&id)
 
-	if err != nil {
-		return &models.Response{Status: "error", Message: err.Error()}, err
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", err
 	}
-	return &models.Response{
-		Status:  "success",
-		Message: "User logged out successfully",
-	}, nil
+	return id, nil
 }
 
-func (a *authenticationRepositoryImpl) ResetPassword(email string, newPassword string) (*models.Response, error) {
-	_, err := a.db.Exec(`
+func (a *authenticationRepositoryImpl) UpdatePasswordHash(ctx context.Context, id string, passwordHash string) (*models.Response, error) {
+	_, err := a.db.ExecContext(ctx, `
         UPDATE users
-        SET password_hash = $1
-        WHERE email = $2
-    `, newPassword, email)
+        SET password_hash = $1,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2
+    `, passwordHash, id)
 
 	if err != nil {
 		return &models.Response{Status: "error", Message: err.Error()}, err
 	}
 	return &models.Response{
 		Status:  "success",
-		Message: "Password reset successfully",
+		Message: "Password hash updated successfully",
 	}, nil
 }
 
-func (a *authenticationRepositoryImpl) SaveRefreshToken(refreshToken models.RefreshToken) (*models.Response, error) {
-	_, err := a.db.Exec(`
-		DELETE FROM 
-			refresh_tokens
-		WHERE user_email = $1
-	`, refreshToken.Email)
+// VerifyEmail activates a pending account.
+func (a *authenticationRepositoryImpl) VerifyEmail(ctx context.Context, email string) (*models.Response, error) {
+	res, err := a.db.ExecContext(ctx, `
+		UPDATE users
+		SET status = $2,
+			email_verified_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE email = $1 AND status = $3 AND deleted_at IS NULL
+	`, email, StatusActive, StatusPending)
 	if err != nil {
-		return &models.Response{
-			Status:  "error",
-			Message: err.Error(),
-		}, err
+		return &models.Response{Status: "error", Message: err.Error()}, err
 	}
-	_, err = a.db.Exec(`
-		INSERT INTO refresh_tokens (
-			user_email,
-			token,
-			expires_at
-		)
-		    VALUES ($1, $2, $3)
-	`, refreshToken.Email, refreshToken.RefreshToken, refreshToken.ExpiresAt)
 
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return &models.Response{
-			Status:  "error",
-			Message: err.Error(),
-		}, err
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if affected == 0 {
+		return &models.Response{Status: "error", Message: "Pending account not found"}, ErrPendingAccountNotFound
 	}
 
 	return &models.Response{
 		Status:  "success",
-		Message: "Refresh token saved successfully",
+		Message: "Email verified successfully",
 	}, nil
 }
 
-func (a *authenticationRepositoryImpl) InvalidateRefreshToken(email string) (*models.Response, error) {
-	_, err := a.db.Exec(`
-		DELETE FROM 
-			refresh_tokens
-		WHERE
-			user_email = $1
-	`, email)
+func (a *authenticationRepositoryImpl) IsEmailPending(ctx context.Context, email string) (bool, error) {
+	var pending bool
+	err := a.db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM users WHERE email = $1 AND status = $2 AND deleted_at IS NULL)
+	`, email, StatusPending).Scan(&pending)
 
 	if err != nil {
-		return &models.Response{
-			Status:  "error",
-			Message: err.Error(),
-		}, err
+		return false, err
 	}
-
-	return &models.Response{
-		Status:  "success",
-		Message: "Refresh token invalidated successfully",
-	}, nil
+	return pending, nil
 }
 
-func (a *authenticationRepositoryImpl) IsRefreshTokenValid(email string) (bool, error) {
-	var count int
-	err := a.db.QueryRow(`
-		SELECT	
-			count(*)
-		FROM 
-			refresh_tokens
-		WHERE 
-			user_email = $1 AND expires_at > CURRENT_TIMESTAMP
-	`, email).Scan(&count)
+// GetUserLocale returns the locale saved for the account with email. It is
+// empty when the user has not picked one or there is no such account.
+func (a *authenticationRepositoryImpl) GetUserLocale(ctx context.Context, email string) (string, error) {
+	var locale string
+	err := a.db.QueryRowContext(ctx, `
+		SELECT
+			locale
+		FROM
+			users
+		WHERE
+			email = $1 AND deleted_at IS NULL
+	`, email).Scan(&locale)
 
 	if err == sql.ErrNoRows {
-		return false, nil
+		return "", nil
 	} else if err != nil {
-		return false, err
-	}
-
-	return count > 0, nil
-}
-
-func (a *authenticationRepositoryImpl) ManageUserRoles(email string, role string) (*models.Response, error) {
-	_, err := a.db.Exec(`
-        UPDATE users
-        SET role = $2
-        WHERE email = $1
-    `, email, role)
-
-	if err != nil {
-		return &models.Response{Status: "error", Message: err.Error()}, err
+		return "", err
 	}
-	return &models.Response{
-		Status:  "success",
-		Message: "User role updated successfully",
-	}, nil
+	return locale, nil
 }
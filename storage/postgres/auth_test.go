@@ -3,8 +3,8 @@ package postgres
 import (
 	"auth-service/config"
 	"auth-service/models"
+	"context"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -38,9 +38,10 @@ func TestRegisterUser(t *testing.T) {
 		FirstName: "Test",
 		LastName:  "User",
 		Password:  "test_password",
+		Locale:    "uz",
 	}
 
-	resp, err := repo.RegisterUser(user)
+	resp, err := repo.RegisterUser(context.Background(), user)
 	if err != nil {
         t.Fatal(err)
     }
@@ -60,7 +61,7 @@ func TestLoginUser(t *testing.T) {
         Password: "test_password",
     }
 
-    resp, err := repo.LoginUser(user)
+    resp, err := repo.LoginUser(context.Background(), user)
     if err != nil {
         t.Fatal(err)
     }
@@ -77,13 +78,13 @@ func TestEmailExists(t *testing.T) {
 
 	repo := NewAuthenticationRepository(db)
 	
-	exists, err := repo.EmailExists("test_email@test.com")
+	exists, err := repo.EmailExists(context.Background(), "test_email@test.com")
 	assert.NoError(t, err)
 
 	assert.Equal(t, exists, true)
 }
 
-func TestLogOut(t *testing.T) {
+func TestResetPassword(t *testing.T) {
 	cfg := config.Load()
 	db, err := ConnectDB(cfg)
 	if err != nil {
@@ -92,13 +93,13 @@ func TestLogOut(t *testing.T) {
 
 	repo := NewAuthenticationRepository(db)
 
-	resp, err := repo.LogOutUser("d70789c8-37e0-4de6-8195-d900abc0afb5")
+	id, err := repo.ResetPassword(context.Background(), "test_email@test.com", "update_password")
 	assert.NoError(t, err)
 
-	assert.Equal(t, "success", resp.Status)
+	assert.Equal(t, id, "d70789c8-37e0-4de6-8195-d900abc0afb5")
 }
 
-func TestResetPassword(t *testing.T) {
+func TestUpdatePasswordHash(t *testing.T) {
 	cfg := config.Load()
 	db, err := ConnectDB(cfg)
 	if err != nil {
@@ -107,13 +108,13 @@ func TestResetPassword(t *testing.T) {
 
 	repo := NewAuthenticationRepository(db)
 
-	resp, err := repo.ResetPassword("test_email@test.com", "update_password")
+	resp, err := repo.UpdatePasswordHash(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "$2a$12$7iVw1p0zq6hN8bZ0Y3y0Le0mI3n2oQ6m5f3l5b1qk2Yw8rj3y6H1S")
 	assert.NoError(t, err)
 
 	assert.Equal(t, resp.Status, "success")
 }
 
-func TestManageUserRoles(t *testing.T) {
+func TestVerifyEmail(t *testing.T) {
 	cfg := config.Load()
 	db, err := ConnectDB(cfg)
 	if err != nil {
@@ -122,48 +123,22 @@ func TestManageUserRoles(t *testing.T) {
 
 	repo := NewAuthenticationRepository(db)
 
-	resp, err := repo.ManageUserRoles("test_email@test.com", "admin")
+	pending, err := repo.IsEmailPending(context.Background(), "test_email@test.com")
 	assert.NoError(t, err)
-
-	assert.Equal(t, resp.Status, "success")
-}
-
-func TestSaveRefreshToken(t *testing.T) {
-	cfg := config.Load()
-	db, err := ConnectDB(cfg)
-	if err != nil {
-		t.Fatal(err)
+	if !pending {
+		t.Skip("test user is already verified")
 	}
 
-	repo := NewAuthenticationRepository(db)
-
-	resp, err := repo.SaveRefreshToken(models.RefreshToken{
-		Email: "test_email@test.com",
-		RefreshToken: "test_token",
-		ExpiresAt: time.Now().Add(1*24*time.Hour).Format("2006-01-02 15:04:05"),
-	})
-
+	resp, err := repo.VerifyEmail(context.Background(), "test_email@test.com")
 	assert.NoError(t, err)
-
 	assert.Equal(t, resp.Status, "success")
-}
-
-func TestInvalidateRefreshToken(t *testing.T) {
-	cfg := config.Load()
-	db, err := ConnectDB(cfg)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	repo := NewAuthenticationRepository(db)
 
-	resp, err := repo.InvalidateRefreshToken("test_email@test.com")
+	pending, err = repo.IsEmailPending(context.Background(), "test_email@test.com")
 	assert.NoError(t, err)
-
-	assert.Equal(t, resp.Status, "success")
+	assert.False(t, pending)
 }
 
-func TestIsRefreshTokenValid(t *testing.T) {
+func TestGetUserLocale(t *testing.T) {
 	cfg := config.Load()
 	db, err := ConnectDB(cfg)
 	if err != nil {
@@ -172,8 +147,11 @@ func TestIsRefreshTokenValid(t *testing.T) {
 
 	repo := NewAuthenticationRepository(db)
 
-	resp, err := repo.IsRefreshTokenValid("test_email@test.com")
+	locale, err := repo.GetUserLocale(context.Background(), "test_email@test.com")
 	assert.NoError(t, err)
+	assert.Equal(t, "uz", locale)
 
-	assert.Equal(t, resp, true)
-}
\ No newline at end of file
+	locale, err = repo.GetUserLocale(context.Background(), "missing_email@test.com")
+	assert.NoError(t, err)
+	assert.Empty(t, locale)
+}
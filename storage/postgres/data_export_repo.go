@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"database/sql"
+	"time"
+)
+
+var (
+	ErrDataExportNotFound   = apperr.New(apperr.NotFound, "data export not found")
+	ErrDataExportInProgress = apperr.New(apperr.Conflict, "a data export is already in progress")
+)
+
+const (
+	ExportPending  = "pending"
+	ExportBuilding = "building"
+	ExportReady    = "ready"
+	ExportFailed   = "failed"
+	ExportExpired  = "expired"
+)
+
+type DataExportRepository interface {
+	CreateExport(ctx context.Context, userID string) (*models.DataExport, error)
+	GetExport(ctx context.Context, id string) (*models.DataExport, error)
+	ClaimDueExports(ctx context.Context, limit int, lease time.Duration) ([]models.DataExport, error)
+	MarkExportReady(ctx context.Context, id string, filePath string, sizeBytes int64, expiresAt time.Time) (*models.Response, error)
+	MarkExportFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) (*models.Response, error)
+	MarkExportDead(ctx context.Context, id string, lastError string) (*models.Response, error)
+	ExpireExports(ctx context.Context) ([]string, error)
+}
+
+type dataExportRepositoryImpl struct {
+	db DBTX
+}
+
+func NewDataExportRepository(db DBTX) DataExportRepository {
+	return &dataExportRepositoryImpl{db: db}
+}
+
+// CreateExport queues a new export for the user. Only one export of a user
+// can be pending or building at a time.
+func (d *dataExportRepositoryImpl) CreateExport(ctx context.Context, userID string) (*models.DataExport, error) {
+	export := models.DataExport{UserID: userID, Status: ExportPending}
+	err := d.db.QueryRowContext(ctx, `
+		INSERT INTO data_exports (user_id)
+			VALUES ($1)
+		RETURNING id, created_at
+	`, userID).Scan(&export.ID, &export.CreatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrDataExportInProgress
+	} else if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (d *dataExportRepositoryImpl) GetExport(ctx context.Context, id string) (*models.DataExport, error) {
+	var (
+		export      models.DataExport
+		completedAt sql.NullString
+		expiresAt   sql.NullString
+	)
+	err := d.db.QueryRowContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			status,
+			attempts,
+			last_error,
+			file_path,
+			size_bytes,
+			created_at,
+			completed_at,
+			expires_at
+		FROM
+			data_exports
+		WHERE
+			id = $1
+	`, id).Scan(&export.ID, &export.UserID, &export.Status, &export.Attempts, &export.LastError,
+		&export.FilePath, &export.SizeBytes, &export.CreatedAt, &completedAt, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrDataExportNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	export.CompletedAt = completedAt.String
+	export.ExpiresAt = expiresAt.String
+
+	return &export, nil
+}
+
+// ClaimDueExports marks up to limit pending exports as building and returns
+// them with the owner's email. An export stays claimed for lease; if the
+// worker dies meanwhile another one picks it up afterwards.
+func (d *dataExportRepositoryImpl) ClaimDueExports(ctx context.Context, limit int, lease time.Duration) ([]models.DataExport, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		UPDATE data_exports e
+		SET status = 'building',
+			attempts = attempts + 1,
+			next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second'
+		FROM users u
+		WHERE u.id = e.user_id AND e.id IN (
+			SELECT id
+			FROM data_exports
+			WHERE status IN ('pending', 'building') AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			e.id,
+			e.user_id,
+			u.email,
+			e.status,
+			e.attempts,
+			e.created_at
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []models.DataExport
+	for rows.Next() {
+		var export models.DataExport
+		err := rows.Scan(&export.ID, &export.UserID, &export.Email, &export.Status, &export.Attempts, &export.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// MarkExportReady records the finished archive of an export that is still
+// being built. ErrDataExportNotFound means it no longer is, for example
+// because the account was deleted meanwhile, and the archive should go.
+func (d *dataExportRepositoryImpl) MarkExportReady(ctx context.Context, id string, filePath string, sizeBytes int64, expiresAt time.Time) (*models.Response, error) {
+	res, err := d.db.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = 'ready',
+			file_path = $2,
+			size_bytes = $3,
+			last_error = '',
+			completed_at = CURRENT_TIMESTAMP,
+			expires_at = $4
+		WHERE id = $1 AND status = 'building'
+	`, id, filePath, sizeBytes, expiresAt)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if affected == 0 {
+		return &models.Response{Status: "error", Message: "Data export not found"}, ErrDataExportNotFound
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Data export is ready",
+	}, nil
+}
+
+// MarkExportFailed schedules another attempt at nextAttemptAt.
+func (d *dataExportRepositoryImpl) MarkExportFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) (*models.Response, error) {
+	_, err := d.db.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = 'pending',
+			last_error = $2,
+			next_attempt_at = $3
+		WHERE id = $1 AND status = 'building'
+	`, id, lastError, nextAttemptAt)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Data export will be retried",
+	}, nil
+}
+
+// MarkExportDead gives up on an export; the user can request a new one.
+func (d *dataExportRepositoryImpl) MarkExportDead(ctx context.Context, id string, lastError string) (*models.Response, error) {
+	_, err := d.db.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = 'failed',
+			last_error = $2,
+			completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'building'
+	`, id, lastError)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Data export failed",
+	}, nil
+}
+
+// ExpireExports marks the ready exports whose link has expired as expired and
+// returns the paths of their archives, which the caller removes.
+func (d *dataExportRepositoryImpl) ExpireExports(ctx context.Context) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		UPDATE data_exports e
+		SET status = 'expired',
+			file_path = ''
+		FROM (
+			SELECT id, file_path
+			FROM data_exports
+			WHERE status = 'ready' AND expires_at <= CURRENT_TIMESTAMP
+			FOR UPDATE SKIP LOCKED
+		) expired
+		WHERE e.id = expired.id
+		RETURNING expired.file_path
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataExportLifecycle(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewDataExportRepository(db)
+	userID := "d70789c8-37e0-4de6-8195-d900abc0afb5"
+
+	export, err := repo.CreateExport(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, ExportPending, export.Status)
+
+	_, err = repo.CreateExport(context.Background(), userID)
+	assert.ErrorIs(t, err, ErrDataExportInProgress)
+
+	claimed, err := repo.ClaimDueExports(context.Background(), 100, time.Minute)
+	assert.NoError(t, err)
+	var found bool
+	for _, c := range claimed {
+		if c.ID == export.ID {
+			found = true
+			assert.Equal(t, "test_email@test.com", c.Email)
+			assert.Equal(t, 1, c.Attempts)
+		}
+	}
+	assert.True(t, found)
+
+	resp, err := repo.MarkExportReady(context.Background(), export.ID, "exports/test.zip", 42, time.Now().Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp.Status)
+
+	got, err := repo.GetExport(context.Background(), export.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ExportReady, got.Status)
+	assert.Equal(t, int64(42), got.SizeBytes)
+
+	paths, err := repo.ExpireExports(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, paths, "exports/test.zip")
+
+	got, err = repo.GetExport(context.Background(), export.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ExportExpired, got.Status)
+
+	_, err = repo.MarkExportReady(context.Background(), export.ID, "exports/test.zip", 42, time.Now())
+	assert.ErrorIs(t, err, ErrDataExportNotFound)
+
+	_, err = repo.GetExport(context.Background(), "00000000-0000-0000-0000-000000000000")
+	assert.ErrorIs(t, err, ErrDataExportNotFound)
+}
@@ -0,0 +1,260 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Delivery states of an outbox message. A message is "sending" while a worker
+// holds it; if the worker dies the lease runs out and it is picked up again.
+const (
+	EmailPending = "pending"
+	EmailSending = "sending"
+	EmailSent    = "sent"
+	EmailDead    = "dead"
+)
+
+var ErrEmailNotFound = apperr.New(apperr.NotFound, "email message not found")
+
+type EmailOutboxRepository interface {
+	EnqueueEmail(ctx context.Context, msg models.EmailMessage) (string, error)
+	ClaimDueEmails(ctx context.Context, limit int, lease time.Duration) ([]models.EmailMessage, error)
+	MarkEmailSent(ctx context.Context, id string) (*models.Response, error)
+	MarkEmailFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) (*models.Response, error)
+	MarkEmailDead(ctx context.Context, id string, lastError string) (*models.Response, error)
+	GetEmail(ctx context.Context, id string) (*models.EmailMessage, error)
+	ListEmails(ctx context.Context, status string, limit int, offset int) ([]models.EmailMessage, error)
+	RedriveEmail(ctx context.Context, id string) (*models.Response, error)
+}
+
+type emailOutboxRepositoryImpl struct {
+	db DBTX
+}
+
+func NewEmailOutboxRepository(db DBTX) EmailOutboxRepository {
+	return &emailOutboxRepositoryImpl{db: db}
+}
+
+func (e *emailOutboxRepositoryImpl) EnqueueEmail(ctx context.Context, msg models.EmailMessage) (string, error) {
+	var id string
+	err := e.db.QueryRowContext(ctx, `
+		INSERT INTO email_outbox (
+			recipient,
+			subject,
+			html,
+			text
+		)
+			VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, msg.To, msg.Subject, msg.HTML, msg.Text).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ClaimDueEmails marks up to limit due messages as sending for lease and
+// returns them. Concurrent workers never claim the same message.
+func (e *emailOutboxRepositoryImpl) ClaimDueEmails(ctx context.Context, limit int, lease time.Duration) ([]models.EmailMessage, error) {
+	rows, err := e.db.QueryContext(ctx, `
+		UPDATE email_outbox
+		SET status = 'sending',
+			attempts = attempts + 1,
+			next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second',
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id
+			FROM email_outbox
+			WHERE status IN ('pending', 'sending') AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			id,
+			recipient,
+			subject,
+			html,
+			text,
+			status,
+			attempts,
+			last_error,
+			next_attempt_at,
+			created_at
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.EmailMessage
+	for rows.Next() {
+		var msg models.EmailMessage
+		err := rows.Scan(&msg.ID, &msg.To, &msg.Subject, &msg.HTML, &msg.Text, &msg.Status,
+			&msg.Attempts, &msg.LastError, &msg.NextAttemptAt, &msg.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (e *emailOutboxRepositoryImpl) MarkEmailSent(ctx context.Context, id string) (*models.Response, error) {
+	return e.update(ctx, `
+		UPDATE email_outbox
+		SET status = 'sent',
+			last_error = '',
+			sent_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, id)
+}
+
+// MarkEmailFailed puts the message back in the queue for another attempt at
+// nextAttemptAt.
+func (e *emailOutboxRepositoryImpl) MarkEmailFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) (*models.Response, error) {
+	return e.update(ctx, `
+		UPDATE email_outbox
+		SET status = 'pending',
+			last_error = $2,
+			next_attempt_at = $3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, id, lastError, nextAttemptAt)
+}
+
+func (e *emailOutboxRepositoryImpl) MarkEmailDead(ctx context.Context, id string, lastError string) (*models.Response, error) {
+	return e.update(ctx, `
+		UPDATE email_outbox
+		SET status = 'dead',
+			last_error = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, id, lastError)
+}
+
+func (e *emailOutboxRepositoryImpl) GetEmail(ctx context.Context, id string) (*models.EmailMessage, error) {
+	var (
+		msg    models.EmailMessage
+		sentAt sql.NullString
+	)
+	err := e.db.QueryRowContext(ctx, `
+		SELECT
+			id,
+			recipient,
+			subject,
+			status,
+			attempts,
+			last_error,
+			next_attempt_at,
+			created_at,
+			sent_at
+		FROM
+			email_outbox
+		WHERE
+			id = $1
+	`, id).Scan(&msg.ID, &msg.To, &msg.Subject, &msg.Status, &msg.Attempts,
+		&msg.LastError, &msg.NextAttemptAt, &msg.CreatedAt, &sentAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrEmailNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	msg.SentAt = sentAt.String
+	return &msg, nil
+}
+
+// ListEmails returns messages newest first. An empty status lists all of them.
+func (e *emailOutboxRepositoryImpl) ListEmails(ctx context.Context, status string, limit int, offset int) ([]models.EmailMessage, error) {
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT
+			id,
+			recipient,
+			subject,
+			status,
+			attempts,
+			last_error,
+			next_attempt_at,
+			created_at,
+			sent_at
+		FROM
+			email_outbox
+		WHERE
+			$1 = '' OR status = $1
+		ORDER BY
+			created_at DESC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.EmailMessage
+	for rows.Next() {
+		var (
+			msg    models.EmailMessage
+			sentAt sql.NullString
+		)
+		err := rows.Scan(&msg.ID, &msg.To, &msg.Subject, &msg.Status, &msg.Attempts,
+			&msg.LastError, &msg.NextAttemptAt, &msg.CreatedAt, &sentAt)
+		if err != nil {
+			return nil, err
+		}
+		msg.SentAt = sentAt.String
+		messages = append(messages, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// RedriveEmail moves a dead message back to the queue with a fresh attempt
+// budget.
+func (e *emailOutboxRepositoryImpl) RedriveEmail(ctx context.Context, id string) (*models.Response, error) {
+	res, err := e.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = 'pending',
+			attempts = 0,
+			next_attempt_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'dead'
+	`, id)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if affected == 0 {
+		return &models.Response{Status: "error", Message: "Dead email message not found"}, ErrEmailNotFound
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Email message queued for delivery",
+	}, nil
+}
+
+func (e *emailOutboxRepositoryImpl) update(ctx context.Context, query string, args ...interface{}) (*models.Response, error) {
+	if _, err := e.db.ExecContext(ctx, query, args...); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	return &models.Response{
+		Status:  "success",
+		Message: "Email message updated successfully",
+	}, nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailOutbox(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewEmailOutboxRepository(db)
+
+	id, err := repo.EnqueueEmail(context.Background(), models.EmailMessage{
+		To:      "test_email@test.com",
+		Subject: "Test subject",
+		HTML:    "<p>Test</p>",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	msg, err := repo.GetEmail(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, EmailPending, msg.Status)
+	assert.Equal(t, 0, msg.Attempts)
+
+	_, err = repo.MarkEmailDead(context.Background(), id, "connection refused")
+	assert.NoError(t, err)
+
+	dead, err := repo.ListEmails(context.Background(), EmailDead, 10, 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, dead)
+
+	resp, err := repo.RedriveEmail(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, resp.Status, "success")
+
+	_, err = repo.RedriveEmail(context.Background(), id)
+	assert.ErrorIs(t, err, ErrEmailNotFound)
+
+	_, err = repo.MarkEmailFailed(context.Background(), id, "connection refused", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+
+	_, err = repo.MarkEmailSent(context.Background(), id)
+	assert.NoError(t, err)
+
+	msg, err = repo.GetEmail(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, EmailSent, msg.Status)
+	assert.NotEmpty(t, msg.SentAt)
+}
+
+func TestClaimDueEmails(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewEmailOutboxRepository(db)
+
+	_, err = repo.EnqueueEmail(context.Background(), models.EmailMessage{
+		To:      "test_email@test.com",
+		Subject: "Test subject",
+		HTML:    "<p>Test</p>",
+	})
+	assert.NoError(t, err)
+
+	messages, err := repo.ClaimDueEmails(context.Background(), 100, time.Minute)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, messages)
+	for _, msg := range messages {
+		assert.Equal(t, EmailSending, msg.Status)
+		assert.Greater(t, msg.Attempts, 0)
+	}
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Domain events. The repositories write them to the outbox in the same
+// transaction as the users change they describe.
+const (
+	EventUserRegistered   = "user.registered"
+	EventUserEmailChanged = "user.email_changed"
+	EventUserRoleChanged  = "user.role_changed"
+	// EventUserDeleted follows the anonymization of a deleted account, so
+	// that other services purge what they hold for the user.
+	EventUserDeleted = "user.deleted"
+)
+
+type EventOutboxRepository interface {
+	ClaimDueEvents(ctx context.Context, limit int, lease time.Duration) ([]models.Event, error)
+	MarkEventPublished(ctx context.Context, id string) (*models.Response, error)
+	MarkEventFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) (*models.Response, error)
+	DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+type eventOutboxRepositoryImpl struct {
+	db DBTX
+}
+
+func NewEventOutboxRepository(db DBTX) EventOutboxRepository {
+	return &eventOutboxRepositoryImpl{db: db}
+}
+
+// enqueueEvent writes event to the outbox within tx, so that it is published
+// if and only if tx commits. Without an ID the event gets a random one; an
+// event whose ID is already in the outbox is skipped, which makes writing it
+// again harmless.
+func enqueueEvent(ctx context.Context, tx DBTX, event models.Event) error {
+	data := []byte(`{}`)
+	if event.Data != nil {
+		var err error
+		if data, err = json.Marshal(event.Data); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (
+			id,
+			event_type,
+			user_id,
+			data
+		)
+			VALUES (COALESCE(NULLIF($1, '')::UUID, GEN_RANDOM_UUID()), $2, $3, $4)
+		ON CONFLICT (id) DO NOTHING
+	`, event.ID, event.Type, event.UserID, data)
+	return err
+}
+
+// ClaimDueEvents leases up to limit unpublished events for lease and returns
+// them, oldest first. Concurrent relays never claim the same event.
+func (e *eventOutboxRepositoryImpl) ClaimDueEvents(ctx context.Context, limit int, lease time.Duration) ([]models.Event, error) {
+	rows, err := e.db.QueryContext(ctx, `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+			next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE published_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			id,
+			event_type,
+			user_id,
+			data,
+			attempts,
+			created_at
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var (
+			event models.Event
+			data  []byte
+		)
+		err := rows.Scan(&event.ID, &event.Type, &event.UserID, &data, &event.Attempts, &event.OccurredAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &event.Data); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (e *eventOutboxRepositoryImpl) MarkEventPublished(ctx context.Context, id string) (*models.Response, error) {
+	_, err := e.db.ExecContext(ctx, `
+		UPDATE outbox
+		SET published_at = CURRENT_TIMESTAMP,
+			last_error = ''
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Event marked as published",
+	}, nil
+}
+
+// MarkEventFailed schedules another attempt at nextAttemptAt. Events are
+// never given up on: consumers rely on seeing every one of them.
+func (e *eventOutboxRepositoryImpl) MarkEventFailed(ctx context.Context, id string, lastError string, nextAttemptAt time.Time) (*models.Response, error) {
+	_, err := e.db.ExecContext(ctx, `
+		UPDATE outbox
+		SET last_error = $2,
+			next_attempt_at = $3
+		WHERE id = $1 AND published_at IS NULL
+	`, id, lastError, nextAttemptAt)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Event will be retried",
+	}, nil
+}
+
+// DeletePublishedEvents removes the events published before before and
+// returns how many there were.
+func (e *eventOutboxRepositoryImpl) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := e.db.ExecContext(ctx, `
+		DELETE FROM outbox
+		WHERE published_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventOutbox(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewEventOutboxRepository(db)
+	event := models.Event{
+		ID:     uuid.NewString(),
+		Type:   EventUserEmailChanged,
+		UserID: "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		Data:   map[string]string{"email": "test_email@test.com"},
+	}
+
+	// Writing the same event twice keeps one copy.
+	for i := 0; i < 2; i++ {
+		tx, err := db.Begin()
+		assert.NoError(t, err)
+		assert.NoError(t, enqueueEvent(context.Background(), tx, event))
+		assert.NoError(t, tx.Commit())
+	}
+
+	claimed, err := repo.ClaimDueEvents(context.Background(), 1000, time.Minute)
+	assert.NoError(t, err)
+	var found []models.Event
+	for _, c := range claimed {
+		if c.ID == event.ID {
+			found = append(found, c)
+		}
+	}
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, event.Data, found[0].Data)
+		assert.Equal(t, 1, found[0].Attempts)
+	}
+
+	resp, err := repo.MarkEventFailed(context.Background(), event.ID, "broker down", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp.Status)
+
+	resp, err = repo.MarkEventPublished(context.Background(), event.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp.Status)
+
+	deleted, err := repo.DeletePublishedEvents(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, deleted, int64(1))
+}
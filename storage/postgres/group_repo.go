@@ -0,0 +1,480 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	GroupOwner  = "owner"
+	GroupMember = "member"
+	GroupViewer = "viewer"
+
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+)
+
+var (
+	ErrGroupNotFound      = apperr.New(apperr.NotFound, "group not found")
+	ErrNotGroupMember     = apperr.New(apperr.NotFound, "user is not a member of the group")
+	ErrAlreadyGroupMember = apperr.New(apperr.Conflict, "user is already a member of the group")
+	ErrInvitationExists   = apperr.New(apperr.Conflict, "invitation is already pending")
+	ErrInvitationNotFound = apperr.New(apperr.NotFound, "invitation not found")
+	ErrLastGroupOwner     = apperr.New(apperr.Conflict, "group must keep at least one owner")
+)
+
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, name string, ownerID string) (*models.Group, error)
+	GetGroup(ctx context.Context, groupID string) (*models.Group, error)
+	GetUserGroups(ctx context.Context, userID string) ([]models.Group, error)
+	GetUserMemberships(ctx context.Context, userID string) ([]models.GroupMembership, error)
+	GetMemberRole(ctx context.Context, groupID string, userID string) (string, error)
+	DeleteGroup(ctx context.Context, groupID string) (*models.Response, error)
+	UpdateMemberRole(ctx context.Context, groupID string, userID string, role string) (*models.Response, error)
+	RemoveMember(ctx context.Context, groupID string, userID string) (*models.Response, error)
+
+	CreateInvitation(ctx context.Context, groupID string, email string, role string, invitedBy string, expiresAt time.Time) (*models.GroupInvitation, error)
+	GetPendingInvitations(ctx context.Context, email string) ([]models.GroupInvitation, error)
+	RespondToInvitation(ctx context.Context, invitationID string, email string, userID string, accept bool) (*models.GroupInvitation, error)
+}
+
+type groupRepositoryImpl struct {
+	db DBTX
+}
+
+func NewGroupRepository(db DBTX) GroupRepository {
+	return &groupRepositoryImpl{db: db}
+}
+
+// CreateGroup creates a group with ownerID as its only member and owner.
+func (r *groupRepositoryImpl) CreateGroup(ctx context.Context, name string, ownerID string) (*models.Group, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	group := models.Group{Name: name, Role: GroupOwner}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO groups (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, name, ownerID).Scan(&group.ID, &group.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO group_members (group_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, group.ID, ownerID, GroupOwner)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// GetGroup returns the group with its members, owners first.
+func (r *groupRepositoryImpl) GetGroup(ctx context.Context, groupID string) (*models.Group, error) {
+	var group models.Group
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, created_at FROM groups WHERE id = $1
+	`, groupID).Scan(&group.ID, &group.Name, &group.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrGroupNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			gm.user_id,
+			u.email,
+			gm.role,
+			gm.joined_at
+		FROM
+			group_members gm
+			JOIN users u ON u.id = gm.user_id
+		WHERE
+			gm.group_id = $1 AND u.deleted_at IS NULL
+		ORDER BY
+			gm.role = 'owner' DESC, gm.joined_at
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member models.GroupMember
+		if err := rows.Scan(&member.UserID, &member.Email, &member.Role, &member.JoinedAt); err != nil {
+			return nil, err
+		}
+		group.Members = append(group.Members, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// GetUserGroups returns the groups userID belongs to together with the
+// user's role in each.
+func (r *groupRepositoryImpl) GetUserGroups(ctx context.Context, userID string) ([]models.Group, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			g.id,
+			g.name,
+			gm.role,
+			g.created_at
+		FROM
+			group_members gm
+			JOIN groups g ON g.id = gm.group_id
+		WHERE
+			gm.user_id = $1
+		ORDER BY
+			g.created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []models.Group
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.Name, &group.Role, &group.CreatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (r *groupRepositoryImpl) GetUserMemberships(ctx context.Context, userID string) ([]models.GroupMembership, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT group_id, role FROM group_members WHERE user_id = $1 ORDER BY joined_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memberships []models.GroupMembership
+	for rows.Next() {
+		var membership models.GroupMembership
+		if err := rows.Scan(&membership.GroupID, &membership.Role); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+// GetMemberRole returns the role of userID in the group, or ErrNotGroupMember.
+func (r *groupRepositoryImpl) GetMemberRole(ctx context.Context, groupID string, userID string) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT role FROM group_members WHERE group_id = $1 AND user_id = $2
+	`, groupID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotGroupMember
+	} else if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *groupRepositoryImpl) DeleteGroup(ctx context.Context, groupID string) (*models.Response, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM groups WHERE id = $1`, groupID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return &models.Response{Status: "error", Message: "Group not found"}, ErrGroupNotFound
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Group deleted successfully",
+	}, nil
+}
+
+// UpdateMemberRole changes the role of a member. Demoting the last owner is
+// refused with ErrLastGroupOwner.
+func (r *groupRepositoryImpl) UpdateMemberRole(ctx context.Context, groupID string, userID string, role string) (*models.Response, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockMember(ctx, tx, groupID, userID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if current == GroupOwner && role != GroupOwner {
+		if err := ensureAnotherOwner(ctx, tx, groupID); err != nil {
+			return &models.Response{Status: "error", Message: err.Error()}, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE group_members SET role = $3 WHERE group_id = $1 AND user_id = $2
+	`, groupID, userID, role)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Member role updated successfully",
+	}, nil
+}
+
+// RemoveMember removes userID from the group. Removing the last owner is
+// refused with ErrLastGroupOwner; the group has to be deleted instead.
+func (r *groupRepositoryImpl) RemoveMember(ctx context.Context, groupID string, userID string) (*models.Response, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockMember(ctx, tx, groupID, userID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if current == GroupOwner {
+		if err := ensureAnotherOwner(ctx, tx, groupID); err != nil {
+			return &models.Response{Status: "error", Message: err.Error()}, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM group_members WHERE group_id = $1 AND user_id = $2
+	`, groupID, userID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Member removed successfully",
+	}, nil
+}
+
+// lockMember locks the group row, so that concurrent membership changes are
+// serialised and the owner count stays accurate, and returns the member's role.
+func lockMember(ctx context.Context, tx DBTX, groupID string, userID string) (string, error) {
+	var id string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM groups WHERE id = $1 FOR UPDATE`, groupID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", ErrGroupNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	var role string
+	err = tx.QueryRowContext(ctx, `
+		SELECT role FROM group_members WHERE group_id = $1 AND user_id = $2
+	`, groupID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotGroupMember
+	} else if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func ensureAnotherOwner(ctx context.Context, tx DBTX, groupID string) error {
+	var owners int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM group_members WHERE group_id = $1 AND role = $2
+	`, groupID, GroupOwner).Scan(&owners)
+	if err != nil {
+		return err
+	}
+	if owners < 2 {
+		return ErrLastGroupOwner
+	}
+	return nil
+}
+
+// CreateInvitation invites email to the group. Emails are stored lower-cased
+// so that the invitee is matched regardless of how the address was typed.
+func (r *groupRepositoryImpl) CreateInvitation(ctx context.Context, groupID string, email string, role string, invitedBy string, expiresAt time.Time) (*models.GroupInvitation, error) {
+	email = strings.ToLower(email)
+
+	var member bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (
+				SELECT 1
+				FROM group_members gm
+					JOIN users u ON u.id = gm.user_id
+				WHERE gm.group_id = $1 AND LOWER(u.email) = $2
+			)
+	`, groupID, email).Scan(&member)
+	if err != nil {
+		return nil, err
+	}
+	if member {
+		return nil, ErrAlreadyGroupMember
+	}
+
+	// An expired invitation no longer blocks a new one.
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE group_invitations
+		SET status = 'expired'
+		WHERE group_id = $1 AND email = $2 AND status = $3 AND expires_at <= CURRENT_TIMESTAMP
+	`, groupID, email, InvitationPending)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := models.GroupInvitation{
+		GroupID:   groupID,
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		Status:    InvitationPending,
+	}
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO group_invitations (group_id, email, role, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, expires_at, (SELECT name FROM groups WHERE id = $1)
+	`, groupID, email, role, invitedBy, expiresAt).Scan(&invitation.ID, &invitation.CreatedAt,
+		&invitation.ExpiresAt, &invitation.GroupName)
+	if isUniqueViolation(err) {
+		return nil, ErrInvitationExists
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// GetPendingInvitations returns the unexpired invitations sent to email.
+func (r *groupRepositoryImpl) GetPendingInvitations(ctx context.Context, email string) ([]models.GroupInvitation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			i.id,
+			i.group_id,
+			g.name,
+			i.email,
+			i.role,
+			COALESCE(u.email, ''),
+			i.status,
+			i.created_at,
+			i.expires_at
+		FROM
+			group_invitations i
+			JOIN groups g ON g.id = i.group_id
+			LEFT JOIN users u ON u.id = i.invited_by
+		WHERE
+			i.email = $1 AND i.status = $2 AND i.expires_at > CURRENT_TIMESTAMP
+		ORDER BY
+			i.created_at DESC
+	`, strings.ToLower(email), InvitationPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []models.GroupInvitation
+	for rows.Next() {
+		var invitation models.GroupInvitation
+		err := rows.Scan(&invitation.ID, &invitation.GroupID, &invitation.GroupName, &invitation.Email,
+			&invitation.Role, &invitation.InvitedBy, &invitation.Status, &invitation.CreatedAt, &invitation.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// RespondToInvitation accepts or declines a pending invitation sent to email.
+// Accepting adds userID to the group with the invited role in the same
+// transaction.
+func (r *groupRepositoryImpl) RespondToInvitation(ctx context.Context, invitationID string, email string, userID string, accept bool) (*models.GroupInvitation, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status := InvitationDeclined
+	if accept {
+		status = InvitationAccepted
+	}
+
+	invitation := models.GroupInvitation{ID: invitationID, Status: status}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE group_invitations
+		SET status = $3,
+			responded_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND email = $2 AND status = $4 AND expires_at > CURRENT_TIMESTAMP
+		RETURNING group_id, (SELECT name FROM groups WHERE id = group_id), email, role,
+			COALESCE(invited_by::TEXT, ''), created_at, expires_at
+	`, invitationID, strings.ToLower(email), status, InvitationPending).Scan(&invitation.GroupID, &invitation.GroupName,
+		&invitation.Email, &invitation.Role, &invitation.InvitedBy, &invitation.CreatedAt, &invitation.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvitationNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if accept {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO group_members (group_id, user_id, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (group_id, user_id) DO NOTHING
+		`, invitation.GroupID, userID, invitation.Role)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
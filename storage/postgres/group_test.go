@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupLifecycle(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewGroupRepository(db)
+	userID := "d70789c8-37e0-4de6-8195-d900abc0afb5"
+
+	group, err := repo.CreateGroup(context.Background(), "Test household", userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.DeleteGroup(context.Background(), group.ID)
+
+	role, err := repo.GetMemberRole(context.Background(), group.ID, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, GroupOwner, role)
+
+	memberships, err := repo.GetUserMemberships(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Contains(t, memberships, models.GroupMembership{GroupID: group.ID, Role: GroupOwner})
+
+	_, err = repo.UpdateMemberRole(context.Background(), group.ID, userID, GroupViewer)
+	assert.ErrorIs(t, err, ErrLastGroupOwner)
+	_, err = repo.RemoveMember(context.Background(), group.ID, userID)
+	assert.ErrorIs(t, err, ErrLastGroupOwner)
+
+	_, err = repo.CreateInvitation(context.Background(), group.ID, "test_email@test.com", GroupMember, userID, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrAlreadyGroupMember)
+
+	invitation, err := repo.CreateInvitation(context.Background(), group.ID, "Invitee@test.com", GroupViewer, userID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "invitee@test.com", invitation.Email)
+	assert.Equal(t, "Test household", invitation.GroupName)
+
+	_, err = repo.CreateInvitation(context.Background(), group.ID, "invitee@test.com", GroupMember, userID, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrInvitationExists)
+
+	pending, err := repo.GetPendingInvitations(context.Background(), "INVITEE@test.com")
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	_, err = repo.RespondToInvitation(context.Background(), invitation.ID, "someone_else@test.com", userID, false)
+	assert.ErrorIs(t, err, ErrInvitationNotFound)
+
+	declined, err := repo.RespondToInvitation(context.Background(), invitation.ID, "invitee@test.com", userID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, InvitationDeclined, declined.Status)
+
+	_, err = repo.RespondToInvitation(context.Background(), invitation.ID, "invitee@test.com", userID, true)
+	assert.ErrorIs(t, err, ErrInvitationNotFound)
+
+	resp, err := repo.DeleteGroup(context.Background(), group.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp.Status)
+
+	_, err = repo.GetGroup(context.Background(), group.ID)
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+}
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrMFANotFound               = apperr.New(apperr.NotFound, "mfa not found")
+	ErrMFAAlreadyEnabled         = apperr.New(apperr.Conflict, "mfa already enabled")
+	ErrPendingEnrollmentNotFound = apperr.New(apperr.NotFound, "pending enrollment not found")
+)
+
+type MFARepository interface {
+	SaveTOTPSecret(ctx context.Context, userID string, encryptedSecret string) (*models.Response, error)
+	GetTOTP(ctx context.Context, userID string) (*models.UserMFA, error)
+	EnableTOTP(ctx context.Context, userID string) (*models.Response, error)
+	DeleteMFA(ctx context.Context, userID string) (*models.Response, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) (*models.Response, error)
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+type mfaRepositoryImpl struct {
+	db DBTX
+}
+
+func NewMFARepository(db DBTX) MFARepository {
+	return &mfaRepositoryImpl{db: db}
+}
+
+// SaveTOTPSecret stores a new, not yet confirmed secret. An earlier
+// unconfirmed enrollment is replaced; a confirmed one is left untouched.
+func (m *mfaRepositoryImpl) SaveTOTPSecret(ctx context.Context, userID string, encryptedSecret string) (*models.Response, error) {
+	res, err := m.db.ExecContext(ctx, `
+		INSERT INTO user_mfa (
+			user_id,
+			totp_secret_encrypted
+		)
+			VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret_encrypted = EXCLUDED.totp_secret_encrypted,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_mfa.enabled_at IS NULL
+	`, userID, encryptedSecret)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if affected == 0 {
+		return &models.Response{Status: "error", Message: "MFA is already enabled"}, ErrMFAAlreadyEnabled
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "TOTP secret saved successfully",
+	}, nil
+}
+
+func (m *mfaRepositoryImpl) GetTOTP(ctx context.Context, userID string) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	var enabledAt sql.NullString
+	err := m.db.QueryRowContext(ctx, `
+		SELECT
+			user_id,
+			totp_secret_encrypted,
+			enabled_at
+		FROM
+			user_mfa
+		WHERE
+			user_id = $1
+	`, userID).Scan(&mfa.UserID, &mfa.SecretEncrypted, &enabledAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrMFANotFound
+	} else if err != nil {
+		return nil, err
+	}
+	mfa.EnabledAt = enabledAt.String
+	mfa.Enabled = enabledAt.Valid
+
+	return &mfa, nil
+}
+
+func (m *mfaRepositoryImpl) EnableTOTP(ctx context.Context, userID string) (*models.Response, error) {
+	res, err := m.db.ExecContext(ctx, `
+		UPDATE user_mfa
+		SET enabled_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if affected == 0 {
+		return &models.Response{Status: "error", Message: "Pending enrollment not found"}, ErrPendingEnrollmentNotFound
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "TOTP enabled successfully",
+	}, nil
+}
+
+// DeleteMFA removes the TOTP secret and every recovery code of the user.
+func (m *mfaRepositoryImpl) DeleteMFA(ctx context.Context, userID string) (*models.Response, error) {
+	tx, err := beginTx(ctx, m.db)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "MFA reset successfully",
+	}, nil
+}
+
+// ReplaceRecoveryCodes invalidates the previous recovery codes of the user
+// and stores the new hashes.
+func (m *mfaRepositoryImpl) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) (*models.Response, error) {
+	tx, err := beginTx(ctx, m.db)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash)
+		SELECT $1, UNNEST($2::VARCHAR[])
+	`, userID, pq.Array(codeHashes))
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Recovery codes generated successfully",
+	}, nil
+}
+
+// UseRecoveryCode marks the matching unused code as used and reports whether
+// there was one.
+func (m *mfaRepositoryImpl) UseRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error) {
+	res, err := m.db.ExecContext(ctx, `
+		UPDATE mfa_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (m *mfaRepositoryImpl) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
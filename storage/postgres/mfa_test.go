@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveTOTPSecret(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewMFARepository(db)
+
+	resp, err := repo.SaveTOTPSecret(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "test_encrypted_secret")
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.Status, "success")
+}
+
+func TestEnableTOTP(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewMFARepository(db)
+
+	resp, err := repo.EnableTOTP(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.NoError(t, err)
+	assert.Equal(t, resp.Status, "success")
+
+	mfa, err := repo.GetTOTP(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.NoError(t, err)
+	assert.True(t, mfa.Enabled)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewMFARepository(db)
+
+	_, err = repo.ReplaceRecoveryCodes(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", []string{"test_hash_1", "test_hash_2"})
+	assert.NoError(t, err)
+
+	used, err := repo.UseRecoveryCode(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "test_hash_1")
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = repo.UseRecoveryCode(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "test_hash_1")
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
+
+func TestDeleteMFA(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewMFARepository(db)
+
+	resp, err := repo.DeleteMFA(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.Status, "success")
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+var ErrClientNotFound = apperr.New(apperr.NotFound, "client not found")
+
+type OAuthClientRepository interface {
+	GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error)
+}
+
+type oauthClientRepositoryImpl struct {
+	db DBTX
+}
+
+func NewOAuthClientRepository(db DBTX) OAuthClientRepository {
+	return &oauthClientRepositoryImpl{db: db}
+}
+
+func (o *oauthClientRepositoryImpl) GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := o.db.QueryRowContext(ctx, `
+		SELECT
+			id,
+			client_id,
+			client_secret_hash,
+			name,
+			grant_types,
+			scopes,
+			redirect_uris
+		FROM
+			oauth_clients
+		WHERE
+			deleted_at IS NULL AND client_id = $1
+	`, clientID).Scan(&client.ID, &client.ClientID, &client.ClientSecretHash, &client.Name,
+		pq.Array(&client.GrantTypes), pq.Array(&client.Scopes), pq.Array(&client.RedirectURIs))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrClientNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetClient(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewOAuthClientRepository(db)
+
+	resp, err := repo.GetClient(context.Background(), "test_client")
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.ClientID, "test_client")
+}
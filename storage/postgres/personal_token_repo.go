@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrPersonalTokenNotFound = apperr.New(apperr.NotFound, "personal access token not found")
+
+// personalTokenTouchInterval limits how often last_used_at is written for a
+// token that is used in a tight loop.
+const personalTokenTouchInterval = time.Minute
+
+type PersonalTokenRepository interface {
+	CreateToken(ctx context.Context, pat models.PersonalToken) (*models.PersonalToken, error)
+	GetUserTokens(ctx context.Context, userID string) ([]models.PersonalToken, error)
+	GetTokenByHash(ctx context.Context, tokenHash string) (*models.PersonalToken, error)
+	TouchToken(ctx context.Context, id string) error
+	RevokeToken(ctx context.Context, userID string, id string) (*models.Response, error)
+	RevokeUserTokens(ctx context.Context, userID string) (*models.Response, error)
+}
+
+type personalTokenRepositoryImpl struct {
+	db DBTX
+}
+
+func NewPersonalTokenRepository(db DBTX) PersonalTokenRepository {
+	return &personalTokenRepositoryImpl{db: db}
+}
+
+func (r *personalTokenRepositoryImpl) CreateToken(ctx context.Context, pat models.PersonalToken) (*models.PersonalToken, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, expires_at
+	`, pat.UserID, pat.Name, pat.Prefix, pat.TokenHash, pq.Array(pat.Scopes), pat.ExpiresAt).Scan(&pat.ID,
+		&pat.CreatedAt, &pat.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &pat, nil
+}
+
+// GetUserTokens lists the user's tokens that have not been revoked, expired
+// ones included so that they can be recognised and cleaned up.
+func (r *personalTokenRepositoryImpl) GetUserTokens(ctx context.Context, userID string) ([]models.PersonalToken, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			name,
+			token_prefix,
+			scopes,
+			created_at,
+			expires_at,
+			last_used_at
+		FROM
+			personal_access_tokens
+		WHERE
+			user_id = $1 AND revoked_at IS NULL
+		ORDER BY
+			created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.PersonalToken
+	for rows.Next() {
+		var (
+			pat      models.PersonalToken
+			lastUsed sql.NullString
+		)
+		err := rows.Scan(&pat.ID, &pat.UserID, &pat.Name, &pat.Prefix, pq.Array(&pat.Scopes),
+			&pat.CreatedAt, &pat.ExpiresAt, &lastUsed)
+		if err != nil {
+			return nil, err
+		}
+		pat.LastUsedAt = lastUsed.String
+		tokens = append(tokens, pat)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetTokenByHash returns the live token with tokenHash together with the
+// owner's email and role. Revoked and expired tokens and tokens of deleted
+// users are reported as ErrPersonalTokenNotFound.
+func (r *personalTokenRepositoryImpl) GetTokenByHash(ctx context.Context, tokenHash string) (*models.PersonalToken, error) {
+	var pat models.PersonalToken
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			t.id,
+			t.user_id,
+			u.email,
+			u.role,
+			t.name,
+			t.token_prefix,
+			t.scopes,
+			t.created_at,
+			t.expires_at
+		FROM
+			personal_access_tokens t
+			JOIN users u ON u.id = t.user_id
+		WHERE
+			t.token_hash = $1
+			AND t.revoked_at IS NULL
+			AND t.expires_at > CURRENT_TIMESTAMP
+			AND u.deleted_at IS NULL
+	`, tokenHash).Scan(&pat.ID, &pat.UserID, &pat.Email, &pat.Role, &pat.Name, &pat.Prefix,
+		pq.Array(&pat.Scopes), &pat.CreatedAt, &pat.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPersonalTokenNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	pat.TokenHash = tokenHash
+	return &pat, nil
+}
+
+func (r *personalTokenRepositoryImpl) TouchToken(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE personal_access_tokens
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - $2::INTERVAL)
+	`, id, personalTokenTouchInterval.String())
+	return err
+}
+
+func (r *personalTokenRepositoryImpl) RevokeToken(ctx context.Context, userID string, id string) (*models.Response, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE personal_access_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return &models.Response{Status: "error", Message: "Token not found"}, ErrPersonalTokenNotFound
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Token revoked successfully",
+	}, nil
+}
+
+// RevokeUserTokens revokes every active token of the user.
+func (r *personalTokenRepositoryImpl) RevokeUserTokens(ctx context.Context, userID string) (*models.Response, error) {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE personal_access_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Tokens revoked successfully",
+	}, nil
+}
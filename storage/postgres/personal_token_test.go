@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersonalTokenLifecycle(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewPersonalTokenRepository(db)
+	userID := "d70789c8-37e0-4de6-8195-d900abc0afb5"
+	tokenHash := "0f3a9c2e7d5b1f4a8c6e2d0b9a7f5e3c1d8b6a4f2e0c9d7b5a3f1e8c6d4b2a0f"
+
+	pat, err := repo.CreateToken(context.Background(), models.PersonalToken{
+		UserID:    userID,
+		Name:      "Spreadsheet export",
+		Prefix:    "pat_abcdefgh",
+		TokenHash: tokenHash,
+		Scopes:    []string{"transactions:read"},
+		ExpiresAt: time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := repo.GetTokenByHash(context.Background(), tokenHash)
+	assert.NoError(t, err)
+	assert.Equal(t, pat.ID, found.ID)
+	assert.Equal(t, "test_email@test.com", found.Email)
+	assert.Equal(t, []string{"transactions:read"}, found.Scopes)
+
+	assert.NoError(t, repo.TouchToken(context.Background(), pat.ID))
+
+	tokens, err := repo.GetUserTokens(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens)
+	assert.NotEmpty(t, tokens[0].LastUsedAt)
+
+	resp, err := repo.RevokeToken(context.Background(), userID, pat.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp.Status)
+
+	_, err = repo.GetTokenByHash(context.Background(), tokenHash)
+	assert.ErrorIs(t, err, ErrPersonalTokenNotFound)
+
+	_, err = repo.RevokeToken(context.Background(), userID, pat.ID)
+	assert.ErrorIs(t, err, ErrPersonalTokenNotFound)
+}
@@ -0,0 +1,217 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrUnknownRole  = apperr.New(apperr.InvalidArgument, "unknown role")
+	ErrUserNotFound = apperr.New(apperr.NotFound, "user not found")
+)
+
+type RoleRepository interface {
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	GetUserPermissions(ctx context.Context, userID string) ([]string, error)
+	HasPermission(ctx context.Context, userID string, permission string) (bool, error)
+	SetUserRoles(ctx context.Context, email string, roles []string) (*models.Response, error)
+}
+
+type roleRepositoryImpl struct {
+	db DBTX
+}
+
+func NewRoleRepository(db DBTX) RoleRepository {
+	return &roleRepositoryImpl{db: db}
+}
+
+func (r *roleRepositoryImpl) ListRoles(ctx context.Context) ([]models.Role, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			r.name,
+			r.description,
+			COALESCE(ARRAY_AGG(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM
+			roles r
+			LEFT JOIN role_permissions rp ON rp.role = r.name
+		GROUP BY
+			r.name, r.description
+		ORDER BY
+			r.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.Name, &role.Description, pq.Array(&role.Permissions)); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *roleRepositoryImpl) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	var roles []string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(ARRAY_AGG(role ORDER BY role), '{}')
+		FROM
+			user_roles
+		WHERE
+			user_id = $1
+	`, userID).Scan(pq.Array(&roles))
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetUserPermissions returns the union of the permissions of every role the
+// user holds.
+func (r *roleRepositoryImpl) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	var permissions []string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(ARRAY_AGG(DISTINCT rp.permission ORDER BY rp.permission), '{}')
+		FROM
+			user_roles ur
+			JOIN role_permissions rp ON rp.role = ur.role
+		WHERE
+			ur.user_id = $1
+	`, userID).Scan(pq.Array(&permissions))
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *roleRepositoryImpl) HasPermission(ctx context.Context, userID string, permission string) (bool, error) {
+	var allowed bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (
+				SELECT 1
+				FROM user_roles ur
+					JOIN role_permissions rp ON rp.role = ur.role
+					JOIN users u ON u.id = ur.user_id
+				WHERE ur.user_id = $1 AND rp.permission = $2 AND u.deleted_at IS NULL
+			)
+	`, userID, permission).Scan(&allowed)
+	if err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+// SetUserRoles replaces the roles of the user with email. The first role
+// becomes users.role, which is what tokens and profiles report as the role.
+func (r *roleRepositoryImpl) SetUserRoles(ctx context.Context, email string, roles []string) (*models.Response, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID        string
+		previousRole  string
+		previousRoles []string
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, role FROM users WHERE email = $1 AND deleted_at IS NULL FOR UPDATE
+	`, email).Scan(&userID, &previousRole)
+	if err == sql.ErrNoRows {
+		return &models.Response{Status: "error", Message: "User not found"}, ErrUserNotFound
+	} else if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	var known int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM roles WHERE name = ANY($1)`, pq.Array(roles)).Scan(&known)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if known != len(roles) {
+		return &models.Response{Status: "error", Message: "Unknown role"}, ErrUnknownRole
+	}
+
+	rows, err := tx.QueryContext(ctx, `DELETE FROM user_roles WHERE user_id = $1 RETURNING role`, userID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			rows.Close()
+			return &models.Response{Status: "error", Message: err.Error()}, err
+		}
+		previousRoles = append(previousRoles, role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_roles (user_id, role)
+		SELECT $1, UNNEST($2::VARCHAR[])
+	`, userID, pq.Array(roles))
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET role = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, userID, roles[0])
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	if previousRole != roles[0] || !sameRoles(previousRoles, roles) {
+		err = enqueueEvent(ctx, tx, models.Event{
+			Type:   EventUserRoleChanged,
+			UserID: userID,
+			Data: map[string]string{
+				"role":           roles[0],
+				"roles":          strings.Join(roles, ","),
+				"previous_role":  previousRole,
+				"previous_roles": strings.Join(previousRoles, ","),
+			},
+		})
+		if err != nil {
+			return &models.Response{Status: "error", Message: err.Error()}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "User roles updated successfully",
+	}, nil
+}
+
+func sameRoles(a []string, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUserRoles(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRoleRepository(db)
+
+	resp, err := repo.SetUserRoles(context.Background(), "test_email@test.com", []string{"admin", "user"})
+	assert.NoError(t, err)
+	assert.Equal(t, resp.Status, "success")
+
+	_, err = repo.SetUserRoles(context.Background(), "test_email@test.com", []string{"superhacker"})
+	assert.ErrorIs(t, err, ErrUnknownRole)
+
+	_, err = repo.SetUserRoles(context.Background(), "missing_email@test.com", []string{"user"})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestListRoles(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRoleRepository(db)
+
+	roles, err := repo.ListRoles(context.Background())
+	assert.NoError(t, err)
+
+	var names []string
+	for _, role := range roles {
+		names = append(names, role.Name)
+		if role.Name == "admin" {
+			assert.Contains(t, role.Permissions, "roles:write")
+		}
+	}
+	assert.Contains(t, names, "admin")
+	assert.Contains(t, names, "user")
+}
+
+func TestGetUserPermissions(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRoleRepository(db)
+
+	permissions, err := repo.GetUserPermissions(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.NoError(t, err)
+	assert.NotNil(t, permissions)
+
+	allowed, err := repo.HasPermission(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "no:such-permission")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
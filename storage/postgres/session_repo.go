@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+	"database/sql"
+)
+
+var ErrSessionNotFound = apperr.New(apperr.NotFound, "session not found")
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session models.Session) (*models.Response, error)
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	GetUserSessions(ctx context.Context, userID string) ([]models.Session, error)
+	RotateRefreshToken(ctx context.Context, id string, oldHash string, newHash string, expiresAt string) (bool, error)
+	RevokeSession(ctx context.Context, userID string, id string) (*models.Response, error)
+	RevokeOtherSessions(ctx context.Context, userID string, currentID string) ([]string, error)
+	RevokeUserSessions(ctx context.Context, userID string) ([]string, error)
+	IsNewDevice(ctx context.Context, userID string, userAgent string) (bool, error)
+}
+
+type sessionRepositoryImpl struct {
+	db DBTX
+}
+
+func NewSessionRepository(db DBTX) SessionRepository {
+	return &sessionRepositoryImpl{db: db}
+}
+
+func (s *sessionRepositoryImpl) CreateSession(ctx context.Context, session models.Session) (*models.Response, error) {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sessions (
+			id,
+			user_id,
+			device_name,
+			user_agent,
+			ip_address,
+			client_id,
+			refresh_token_hash,
+			expires_at
+		)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, session.ID, session.UserID, session.DeviceName, session.UserAgent,
+		session.IPAddress, session.ClientID, session.RefreshTokenHash, session.ExpiresAt)
+
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	return &models.Response{
+		Status:  "success",
+		Message: "Session created successfully",
+	}, nil
+}
+
+// GetSession returns a session that has been neither revoked nor expired.
+func (s *sessionRepositoryImpl) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			device_name,
+			user_agent,
+			ip_address,
+			client_id,
+			refresh_token_hash,
+			created_at,
+			last_used_at,
+			expires_at
+		FROM
+			sessions
+		WHERE
+			id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	`, id).Scan(&session.ID, &session.UserID, &session.DeviceName, &session.UserAgent,
+		&session.IPAddress, &session.ClientID, &session.RefreshTokenHash, &session.CreatedAt,
+		&session.LastUsedAt, &session.ExpiresAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *sessionRepositoryImpl) GetUserSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			device_name,
+			user_agent,
+			ip_address,
+			created_at,
+			last_used_at,
+			expires_at
+		FROM
+			sessions
+		WHERE
+			user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY
+			last_used_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(&session.ID, &session.UserID, &session.DeviceName, &session.UserAgent,
+			&session.IPAddress, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RotateRefreshToken swaps the session's refresh token hash from oldHash to
+// newHash. It reports false when oldHash is no longer the current hash, which
+// means the presented token has already been used.
+func (s *sessionRepositoryImpl) RotateRefreshToken(ctx context.Context, id string, oldHash string, newHash string, expiresAt string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET refresh_token_hash = $3,
+			expires_at = $4,
+			last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL
+	`, id, oldHash, newHash, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// RevokeSession ends a session and forgets its refresh token, so that the
+// token can no longer be rotated even if the revocation is overlooked.
+func (s *sessionRepositoryImpl) RevokeSession(ctx context.Context, userID string, id string) (*models.Response, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP,
+			refresh_token_hash = ''
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if affected == 0 {
+		return &models.Response{Status: "error", Message: "Session not found"}, ErrSessionNotFound
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Session revoked successfully",
+	}, nil
+}
+
+// RevokeOtherSessions revokes every active session of the user except
+// currentID and returns the IDs of the sessions it revoked.
+func (s *sessionRepositoryImpl) RevokeOtherSessions(ctx context.Context, userID string, currentID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP,
+			refresh_token_hash = ''
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+		RETURNING id
+	`, userID, currentID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSessionIDs(rows)
+}
+
+// RevokeUserSessions revokes every active session of the user and returns the
+// IDs of the sessions it revoked.
+func (s *sessionRepositoryImpl) RevokeUserSessions(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP,
+			refresh_token_hash = ''
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSessionIDs(rows)
+}
+
+// IsNewDevice reports whether the user has signed in before, but never with
+// userAgent. The very first sign-in of an account is not a new device.
+func (s *sessionRepositoryImpl) IsNewDevice(ctx context.Context, userID string, userAgent string) (bool, error) {
+	var isNew bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 AND COUNT(*) FILTER (WHERE user_agent = $2) = 0
+		FROM sessions
+		WHERE user_id = $1
+	`, userID, userAgent).Scan(&isNew)
+	if err != nil {
+		return false, err
+	}
+	return isNew, nil
+}
+
+func scanSessionIDs(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
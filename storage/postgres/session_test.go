@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSession(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSessionRepository(db)
+
+	resp, err := repo.CreateSession(context.Background(), models.Session{
+		ID:               "0f7c7b1e-6d1c-4f55-9c1e-3b2a7d9e8f10",
+		UserID:           "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		DeviceName:       "Test device",
+		UserAgent:        "go-test",
+		IPAddress:        "127.0.0.1",
+		ClientID:         "test-client",
+		RefreshTokenHash: "test_hash",
+		ExpiresAt:        time.Now().Add(7 * 24 * time.Hour).Format("2006-01-02 15:04:05"),
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.Status, "success")
+}
+
+func TestGetSession(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSessionRepository(db)
+
+	resp, err := repo.GetSession(context.Background(), "0f7c7b1e-6d1c-4f55-9c1e-3b2a7d9e8f10")
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.UserID, "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.Equal(t, "test-client", resp.ClientID)
+}
+
+func TestGetUserSessions(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSessionRepository(db)
+
+	resp, err := repo.GetUserSessions(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, resp)
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSessionRepository(db)
+
+	ok, err := repo.RotateRefreshToken(context.Background(), "0f7c7b1e-6d1c-4f55-9c1e-3b2a7d9e8f10", "test_hash", "test_hash_rotated",
+		time.Now().Add(7*24*time.Hour).Format("2006-01-02 15:04:05"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.RotateRefreshToken(context.Background(), "0f7c7b1e-6d1c-4f55-9c1e-3b2a7d9e8f10", "test_hash", "test_hash_reused",
+		time.Now().Add(7*24*time.Hour).Format("2006-01-02 15:04:05"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSessionRepository(db)
+
+	_, err = repo.RevokeOtherSessions(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "0f7c7b1e-6d1c-4f55-9c1e-3b2a7d9e8f10")
+	assert.NoError(t, err)
+}
+
+func TestRevokeSession(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSessionRepository(db)
+
+	resp, err := repo.RevokeSession(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "0f7c7b1e-6d1c-4f55-9c1e-3b2a7d9e8f10")
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.Status, "success")
+}
+
+func TestIsNewDevice(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSessionRepository(db)
+
+	isNew, err := repo.IsNewDevice(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "go-test")
+	assert.NoError(t, err)
+	assert.False(t, isNew)
+
+	isNew, err = repo.IsNewDevice(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5", "unknown-agent")
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+}
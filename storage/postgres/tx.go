@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// DBTX is what the repositories run their queries on: a *sql.DB, or a *sql.Tx
+// when they take part in a transaction started by the caller. It only has the
+// context variants so that every query is cancelled with its request.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ DBTX = (*sql.DB)(nil)
+	_ DBTX = (*sql.Tx)(nil)
+)
+
+// txn is a transaction a repository runs several statements in. Within a
+// transaction of the caller it is that transaction, and committing or rolling
+// it back is left to the caller.
+type txn struct {
+	*sql.Tx
+	owned bool
+}
+
+// beginTx starts a transaction on db, or joins db when it is one already.
+func beginTx(ctx context.Context, db DBTX) (*txn, error) {
+	switch db := db.(type) {
+	case *sql.Tx:
+		return &txn{Tx: db}, nil
+	case interface {
+		BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+	}:
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &txn{Tx: tx, owned: true}, nil
+	default:
+		return nil, errors.New("postgres: cannot begin a transaction")
+	}
+}
+
+func (t *txn) Commit() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *txn) Rollback() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// RunInTx runs fn in a transaction on db and commits it if fn succeeds. The
+// transaction is rolled back when fn fails or ctx is cancelled first. When db
+// is a transaction already fn simply runs in it, so that calls nest.
+//
+// A statement that fails aborts the whole transaction in Postgres, so fn
+// should give up on the first error rather than carry on.
+func RunInTx(ctx context.Context, db DBTX, fn func(tx DBTX) error) error {
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx.Tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
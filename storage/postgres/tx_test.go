@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"auth-service/generated/user"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunInTx(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	userID := "d70789c8-37e0-4de6-8195-d900abc0afb5"
+	before, err := NewUserRepository(db).GetUserProfile(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A repository running its own transaction joins the caller's one, and
+	// nothing is kept when the caller fails afterwards.
+	errFailed := errors.New("failed")
+	err = RunInTx(ctx, db, func(tx DBTX) error {
+		_, err := NewUserRepository(tx).UpdateUserProfile(ctx, &user.UpdateUserProfileReq{
+			Id:        userID,
+			Email:     before.Email,
+			FirstName: "rolled_back",
+			LastName:  before.LastName,
+		})
+		assert.NoError(t, err)
+
+		profile, err := NewUserRepository(tx).GetUserProfile(ctx, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, "rolled_back", profile.FirstName)
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	after, err := NewUserRepository(db).GetUserProfile(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, before.FirstName, after.FirstName)
+
+	// Nested calls run in the outer transaction.
+	err = RunInTx(ctx, db, func(tx DBTX) error {
+		return RunInTx(ctx, tx, func(inner DBTX) error {
+			assert.Same(t, tx, inner)
+			return nil
+		})
+	})
+	assert.NoError(t, err)
+}
+
+func TestRunInTxCancelled(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err = RunInTx(ctx, db, func(tx DBTX) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, called)
+
+	_, err = NewAuthenticationRepository(db).EmailExists(ctx, "test_email@test.com")
+	assert.ErrorIs(t, err, context.Canceled)
+}
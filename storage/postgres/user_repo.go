@@ -2,80 +2,187 @@ package postgres
 
 import (
 	pb "auth-service/generated/user"
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
 	"database/sql"
 	"fmt"
 )
 
+var ErrEmailTaken = apperr.New(apperr.Conflict, "email already exists")
+
 type UserRepository interface {
-	GetUserProfile(id string) (*pb.UserProfile, error)
-	UpdateUserProfile(userProfile *pb.UpdateUserProfileReq) (*pb.UpdateUserProfileResp, error)
-	GetUsersList(fUser *pb.GetUsersListReq) (*pb.GetUsersListResp, error)
-	ChangePassword(change *pb.ChangePasswordReq) (*pb.ChangePasswordResp, error)
+	GetUserProfile(ctx context.Context, id string) (*pb.UserProfile, error)
+	UpdateUserProfile(ctx context.Context, userProfile *pb.UpdateUserProfileReq) (*pb.UpdateUserProfileResp, error)
+	GetUsersList(ctx context.Context, fUser *pb.GetUsersListReq) (*pb.GetUsersListResp, error)
+	GetPasswordHash(ctx context.Context, id string) (string, error)
+	ChangePassword(ctx context.Context, change *pb.ChangePasswordReq) (*pb.ChangePasswordResp, error)
+	ChangeEmail(ctx context.Context, id string, email string) (*models.Response, error)
 }
 
 type userRepositoryImpl struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewUserRepository(db *sql.DB) UserRepository {
+func NewUserRepository(db DBTX) UserRepository {
 	return &userRepositoryImpl{db: db}
 }
 
-func (u *userRepositoryImpl) GetUserProfile(id string) (*pb.UserProfile, error) {
+func (u *userRepositoryImpl) GetUserProfile(ctx context.Context, id string) (*pb.UserProfile, error) {
 	var userProfile pb.UserProfile
-	err := u.db.QueryRow(`
+	err := u.db.QueryRowContext(ctx, `
 		SELECT 
 			id, 
 			email, 
 			first_name, 
 			last_name, 
-			role 
+			role,
+			status,
+			email_verified_at IS NOT NULL,
+			locale
 		FROM 
 			users 
 		WHERE id = $1
-	`, id).Scan(&userProfile.Id, &userProfile.Email, &userProfile.FirstName, &userProfile.LastName, &userProfile.Role)
+	`, id).Scan(&userProfile.Id, &userProfile.Email, &userProfile.FirstName, &userProfile.LastName, &userProfile.Role,
+		&userProfile.Status, &userProfile.EmailVerified, &userProfile.Locale)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	} else if err != nil {
 		return nil, err
 	}
 	return &userProfile, nil
 }
 
-func (u *userRepositoryImpl) UpdateUserProfile(userProfile *pb.UpdateUserProfileReq) (*pb.UpdateUserProfileResp, error) {
-	_, err := u.db.Exec(`
+// UpdateUserProfile saves the name and locale of the user. The email is only
+// changed by ChangeEmail, once the user has confirmed the new address.
+func (u *userRepositoryImpl) UpdateUserProfile(ctx context.Context, userProfile *pb.UpdateUserProfileReq) (*pb.UpdateUserProfileResp, error) {
+	res, err := u.db.ExecContext(ctx, `
         UPDATE 
             users 
         SET 
-            email = $1, 
-            first_name = $2, 
-            last_name = $3 
+            first_name = $1, 
+            last_name = $2,
+            locale = COALESCE(NULLIF($4, ''), locale)
         WHERE 
-            id = $4
-    `, userProfile.Email, userProfile.FirstName, userProfile.LastName, userProfile.Id)
+            id = $3
+    `, userProfile.FirstName, userProfile.LastName, userProfile.Id, userProfile.Locale)
+	if err != nil {
+		return &pb.UpdateUserProfileResp{
+			Status:  "error",
+			Message: err.Error(),
+		}, err
+	}
 
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return &pb.UpdateUserProfileResp{
 			Status:  "error",
 			Message: err.Error(),
 		}, err
 	}
+	if affected == 0 {
+		return &pb.UpdateUserProfileResp{
+			Status:  "error",
+			Message: "User not found",
+		}, ErrUserNotFound
+	}
+
 	return &pb.UpdateUserProfileResp{
 		Status:  "success",
 		Message: "User profile updated successfully",
 	}, nil
 }
 
-func (u *userRepositoryImpl) ChangePassword(change *pb.ChangePasswordReq) (*pb.ChangePasswordResp, error) {
-	_, err := u.db.Exec(`
+// ChangeEmail replaces the email of the user with an address they have
+// confirmed, which counts as verifying it, and emits user.email_changed.
+func (u *userRepositoryImpl) ChangeEmail(ctx context.Context, id string, email string) (*models.Response, error) {
+	tx, err := beginTx(ctx, u.db)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	defer tx.Rollback()
+
+	var previousEmail string
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			email
+		FROM
+			users
+		WHERE
+			id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id).Scan(&previousEmail)
+	if err == sql.ErrNoRows {
+		return &models.Response{Status: "error", Message: "User not found"}, ErrUserNotFound
+	}
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users
+		SET email = $1,
+			email_verified_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, email, id)
+	if isUniqueViolation(err) {
+		return &models.Response{Status: "error", Message: "Email already exists"}, ErrEmailTaken
+	}
+	if err == nil && email != previousEmail {
+		err = enqueueEvent(ctx, tx, models.Event{
+			Type:   EventUserEmailChanged,
+			UserID: id,
+			Data:   map[string]string{"email": email, "previous_email": previousEmail},
+		})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	return &models.Response{
+		Status:  "success",
+		Message: "Email changed successfully",
+	}, nil
+}
+
+// GetPasswordHash returns the user's password hash. In a transaction the row
+// stays locked until it ends, so that the hash cannot change in between.
+func (u *userRepositoryImpl) GetPasswordHash(ctx context.Context, id string) (string, error) {
+	var passwordHash string
+	err := u.db.QueryRowContext(ctx, `
+		SELECT
+			password_hash
+		FROM
+			users
+		WHERE
+			deleted_at IS NULL AND id = $1
+		FOR UPDATE
+	`, id).Scan(&passwordHash)
+
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return passwordHash, nil
+}
+
+// ChangePassword stores change.NewPassword as the user's password hash; the
+// caller is expected to have verified the current password and hashed the new one.
+func (u *userRepositoryImpl) ChangePassword(ctx context.Context, change *pb.ChangePasswordReq) (*pb.ChangePasswordResp, error) {
+	_, err := u.db.ExecContext(ctx, `
         UPDATE 
             users 
         SET 
-            password_hash = $1 
+            password_hash = $1,
+            updated_at = CURRENT_TIMESTAMP
         WHERE 
-            password_hash = $2 AND id = $3
-    `, change.NewPassword, change.CurrentPassword, change.Id)
+            id = $2
+    `, change.NewPassword, change.Id)
 
 	if err != nil {
 		return &pb.ChangePasswordResp{
@@ -89,7 +196,7 @@ func (u *userRepositoryImpl) ChangePassword(change *pb.ChangePasswordReq) (*pb.C
 	}, nil
 }
 
-func (u *userRepositoryImpl) GetUsersList(fUser *pb.GetUsersListReq) (*pb.GetUsersListResp, error) {
+func (u *userRepositoryImpl) GetUsersList(ctx context.Context, fUser *pb.GetUsersListReq) (*pb.GetUsersListResp, error) {
 	var (
 		args   []interface{}
 		filter string
@@ -101,7 +208,10 @@ func (u *userRepositoryImpl) GetUsersList(fUser *pb.GetUsersListReq) (*pb.GetUse
 			email, 
 			first_name, 
 			last_name, 
-			role 
+			role,
+			status,
+			email_verified_at IS NOT NULL,
+			locale
 		FROM 
 			users
 		WHERE
@@ -119,25 +229,33 @@ func (u *userRepositoryImpl) GetUsersList(fUser *pb.GetUsersListReq) (*pb.GetUse
 		filter += fmt.Sprintf(" AND role = $%d", len(args)+1)
 		args = append(args, fUser.Role)
 	}
+	switch fUser.VerificationStatus {
+	case "verified":
+		filter += " AND email_verified_at IS NOT NULL"
+	case "unverified":
+		filter += " AND email_verified_at IS NULL"
+	}
 
 	var totalCount int32
 	q := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL` + filter
-	err := u.db.QueryRow(q, args...).Scan(&totalCount)
+	err := u.db.QueryRowContext(ctx, q, args...).Scan(&totalCount)
 	if err != nil {
 		return nil, err
 	}
 	query += filter
 	query += fmt.Sprintf(" LIMIT %d OFFSET %d", fUser.Limit, (fUser.Page-1)*fUser.Limit)
 
-	rows, err := u.db.Query(query, args...)
+	rows, err := u.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var users []*pb.UserProfile
 	for rows.Next() {
 		var user pb.UserProfile
-		err := rows.Scan(&user.Id, &user.Email, &user.FirstName, &user.LastName, &user.Role)
+		err := rows.Scan(&user.Id, &user.Email, &user.FirstName, &user.LastName, &user.Role,
+			&user.Status, &user.EmailVerified, &user.Locale)
 		if err != nil {
 			return nil, err
 		}
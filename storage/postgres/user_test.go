@@ -3,6 +3,7 @@ package postgres
 import (
 	"auth-service/config"
 	"auth-service/generated/user"
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,7 +18,7 @@ func TestGetUserProfile(t *testing.T) {
 
 	repo := NewUserRepository(db)
 
-	resp, err := repo.GetUserProfile("d70789c8-37e0-4de6-8195-d900abc0afb5")
+	resp, err := repo.GetUserProfile(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5")
 	assert.NoError(t, err)
 
 	assert.Equal(t, resp.Email, "test_email@test.com")
@@ -32,7 +33,7 @@ func TestUpdateUserProfile(t *testing.T) {
 
 	repo := NewUserRepository(db)
 
-	resp, err := repo.UpdateUserProfile(&user.UpdateUserProfileReq{
+	resp, err := repo.UpdateUserProfile(context.Background(), &user.UpdateUserProfileReq{
 		Id: "d70789c8-37e0-4de6-8195-d900abc0afb5",
 		Email: "test_update_email@test.com",
 		FirstName: "UpdateName",
@@ -53,7 +54,7 @@ func TestChangePassword(t *testing.T) {
 
 	repo := NewUserRepository(db)
 
-	resp, err := repo.ChangePassword(&user.ChangePasswordReq{
+	resp, err := repo.ChangePassword(context.Background(), &user.ChangePasswordReq{
 		Id: "d70789c8-37e0-4de6-8195-d900abc0afb5",
 		CurrentPassword: "test_password",
 		NewPassword: "update_password",
@@ -73,7 +74,7 @@ func TestGetUserList(t *testing.T) {
 
 	repo := NewUserRepository(db)
 
-	resp, err := repo.GetUsersList(&user.GetUsersListReq{
+	resp, err := repo.GetUsersList(context.Background(), &user.GetUsersListReq{
 		Page: 1,
 		Limit: 10,
 		FirstName: "Test",
@@ -83,3 +84,68 @@ func TestGetUserList(t *testing.T) {
 
 	assert.Equal(t, resp.TotalCount,  int32(0))
 }
+
+func TestGetPasswordHash(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewUserRepository(db)
+
+	hash, err := repo.GetPasswordHash(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, hash)
+}
+
+func TestGetUsersListByVerificationStatus(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewUserRepository(db)
+
+	resp, err := repo.GetUsersList(context.Background(), &user.GetUsersListReq{
+		Page:               1,
+		Limit:              10,
+		VerificationStatus: "unverified",
+	})
+	assert.NoError(t, err)
+
+	for _, u := range resp.Users {
+		assert.False(t, u.EmailVerified)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+	userID := "d70789c8-37e0-4de6-8195-d900abc0afb5"
+
+	before, err := repo.GetUserProfile(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := repo.ChangeEmail(ctx, userID, "test_changed_email@test.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp.Status)
+
+	profile, err := repo.GetUserProfile(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_changed_email@test.com", profile.Email)
+	assert.True(t, profile.EmailVerified)
+
+	_, err = repo.ChangeEmail(ctx, userID, before.Email)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"auth-service/models"
+	"auth-service/pkg/apperr"
+	"context"
+
+	"github.com/lib/pq"
+)
+
+var ErrCredentialNotFound = apperr.New(apperr.NotFound, "credential not found")
+
+type WebAuthnRepository interface {
+	CreateCredential(ctx context.Context, credential models.WebAuthnCredential) (*models.Response, error)
+	GetUserCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error)
+	UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32, backupState bool) (*models.Response, error)
+}
+
+type webAuthnRepositoryImpl struct {
+	db DBTX
+}
+
+func NewWebAuthnRepository(db DBTX) WebAuthnRepository {
+	return &webAuthnRepositoryImpl{db: db}
+}
+
+func (w *webAuthnRepositoryImpl) CreateCredential(ctx context.Context, credential models.WebAuthnCredential) (*models.Response, error) {
+	_, err := w.db.ExecContext(ctx, `
+		INSERT INTO webauthn_credentials (
+			user_id,
+			name,
+			credential_id,
+			public_key,
+			attestation_type,
+			transports,
+			aaguid,
+			sign_count,
+			backup_eligible,
+			backup_state
+		)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, credential.UserID, credential.Name, credential.CredentialID, credential.PublicKey,
+		credential.AttestationType, pq.Array(credential.Transports), credential.AAGUID,
+		int64(credential.SignCount), credential.BackupEligible, credential.BackupState)
+
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	return &models.Response{
+		Status:  "success",
+		Message: "Credential registered successfully",
+	}, nil
+}
+
+func (w *webAuthnRepositoryImpl) GetUserCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error) {
+	rows, err := w.db.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			name,
+			credential_id,
+			public_key,
+			attestation_type,
+			transports,
+			COALESCE(aaguid, ''::BYTEA),
+			sign_count,
+			backup_eligible,
+			backup_state,
+			created_at,
+			COALESCE(last_used_at::TEXT, '')
+		FROM
+			webauthn_credentials
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []models.WebAuthnCredential
+	for rows.Next() {
+		var credential models.WebAuthnCredential
+		var signCount int64
+		err := rows.Scan(&credential.ID, &credential.UserID, &credential.Name, &credential.CredentialID,
+			&credential.PublicKey, &credential.AttestationType, pq.Array(&credential.Transports),
+			&credential.AAGUID, &signCount, &credential.BackupEligible, &credential.BackupState,
+			&credential.CreatedAt, &credential.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		credential.SignCount = uint32(signCount)
+		credentials = append(credentials, credential)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+func (w *webAuthnRepositoryImpl) UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32, backupState bool) (*models.Response, error) {
+	res, err := w.db.ExecContext(ctx, `
+		UPDATE webauthn_credentials
+		SET sign_count = $2,
+			backup_state = $3,
+			last_used_at = CURRENT_TIMESTAMP
+		WHERE credential_id = $1
+	`, credentialID, int64(signCount), backupState)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+	if affected == 0 {
+		return &models.Response{Status: "error", Message: "Credential not found"}, ErrCredentialNotFound
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Credential updated successfully",
+	}, nil
+}
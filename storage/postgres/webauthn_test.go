@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCredential(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewWebAuthnRepository(db)
+
+	resp, err := repo.CreateCredential(context.Background(), models.WebAuthnCredential{
+		UserID:          "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		Name:            "Test passkey",
+		CredentialID:    []byte("test_credential_id"),
+		PublicKey:       []byte("test_public_key"),
+		AttestationType: "none",
+		Transports:      []string{"internal"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.Status, "success")
+}
+
+func TestGetUserCredentials(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewWebAuthnRepository(db)
+
+	resp, err := repo.GetUserCredentials(context.Background(), "d70789c8-37e0-4de6-8195-d900abc0afb5")
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, resp)
+}
+
+func TestUpdateSignCount(t *testing.T) {
+	cfg := config.Load()
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewWebAuthnRepository(db)
+
+	resp, err := repo.UpdateSignCount(context.Background(), []byte("test_credential_id"), 1, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, resp.Status, "success")
+}
@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RecordFailure counts a failed attempt under key. The counter expires window
+// after the first failure it holds.
+func (rdb *redisStoreImpl) RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := rdb.client.TxPipeline()
+	incr := pipe.Incr(ctx, "throttle:fail:"+key)
+	pipe.ExpireNX(ctx, "throttle:fail:"+key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// Lock blocks key for duration unless it is already blocked for longer.
+func (rdb *redisStoreImpl) Lock(ctx context.Context, key string, duration time.Duration) error {
+	lockKey := "throttle:lock:" + key
+
+	ttl, err := rdb.client.PTTL(ctx, lockKey).Result()
+	if err != nil {
+		return err
+	}
+	if ttl >= duration {
+		return nil
+	}
+	return rdb.client.Set(ctx, lockKey, "locked", duration).Err()
+}
+
+// LockedFor returns the longest remaining lock among keys, or zero when none
+// of them is locked.
+func (rdb *redisStoreImpl) LockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	pipe := rdb.client.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		ttls[i] = pipe.PTTL(ctx, "throttle:lock:"+key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	var longest time.Duration
+	for _, ttl := range ttls {
+		if ttl.Val() > longest {
+			longest = ttl.Val()
+		}
+	}
+	return longest, nil
+}
+
+// ClearFailures forgets the failures counted under keys. Locks already in
+// place are left to expire.
+func (rdb *redisStoreImpl) ClearFailures(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = "throttle:fail:" + key
+	}
+	return rdb.client.Del(ctx, full...).Err()
+}
+
+// Unlock removes every failure counter and lock whose key matches pattern.
+func (rdb *redisStoreImpl) Unlock(ctx context.Context, pattern string) (int64, error) {
+	var removed int64
+	for _, prefix := range []string{"throttle:fail:", "throttle:lock:"} {
+		iter := rdb.client.Scan(ctx, 0, prefix+pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			n, err := rdb.client.Del(ctx, iter.Val()).Result()
+			if err != nil {
+				return removed, err
+			}
+			removed += n
+		}
+		if err := iter.Err(); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// IncrementCodeAttempts counts the guesses made against the reset code of
+// email. The counter lives as long as the code.
+func (rdb *redisStoreImpl) IncrementCodeAttempts(ctx context.Context, email string) (int64, error) {
+	key := email + ":code:attempts"
+
+	n, err := rdb.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		ttl, err := rdb.client.PTTL(ctx, email+":code").Result()
+		if err != nil || ttl <= 0 {
+			ttl = 5 * time.Minute
+		}
+		rdb.client.PExpire(ctx, key, ttl)
+	}
+	return n, nil
+}
+
+func (rdb *redisStoreImpl) DeleteCode(ctx context.Context, email string) error {
+	if err := rdb.client.Del(ctx, email+":code", email+":code:attempts").Err(); err != nil {
+		return fmt.Errorf("delete code: %w", err)
+	}
+	return nil
+}
@@ -3,60 +3,84 @@ package redis
 import (
 	"auth-service/models"
 	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type RedisStore interface {
-	AddTokenBlacklist(token string, expirationTime time.Duration) (*models.Response, error)
-	IsTokenBlacklisted(token string) (bool, error)
-	StoreCode(email, code string, expirationTime time.Duration) (*models.Response, error)
-	IsCodeValid(email, code string) (bool, error)
+	RevokeToken(ctx context.Context, tokenID string, expirationTime time.Duration) (*models.Response, error)
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	StoreCode(ctx context.Context, email, code string, expirationTime time.Duration) (*models.Response, error)
+	IsCodeValid(ctx context.Context, email, code string) (bool, error)
+	RevokeSession(ctx context.Context, sessionID string, expirationTime time.Duration) (*models.Response, error)
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+	StoreAuthorizationCode(ctx context.Context, code string, data models.AuthorizationCode, expirationTime time.Duration) (*models.Response, error)
+	ConsumeAuthorizationCode(ctx context.Context, code string) (*models.AuthorizationCode, error)
+	MarkTOTPUsed(ctx context.Context, userID string, step int64, expirationTime time.Duration) (bool, error)
+	IncrementMFAAttempts(ctx context.Context, tokenID string, expirationTime time.Duration) (int64, error)
+	StoreWebAuthnSession(ctx context.Context, key string, session []byte, expirationTime time.Duration) (*models.Response, error)
+	ConsumeWebAuthnSession(ctx context.Context, key string) ([]byte, error)
+	StoreEmailVerification(ctx context.Context, tokenHash string, email string, expirationTime time.Duration) (*models.Response, error)
+	ConsumeEmailVerification(ctx context.Context, tokenHash string) (string, error)
+	MarkVerificationSent(ctx context.Context, email string, expirationTime time.Duration) (bool, error)
+	StoreEmailChange(ctx context.Context, tokenHash string, change models.EmailChange, expirationTime time.Duration) (*models.Response, error)
+	ConsumeEmailChange(ctx context.Context, tokenHash string) (*models.EmailChange, error)
+
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, key string, duration time.Duration) error
+	LockedFor(ctx context.Context, keys ...string) (time.Duration, error)
+	ClearFailures(ctx context.Context, keys ...string) error
+	Unlock(ctx context.Context, pattern string) (int64, error)
+	IncrementCodeAttempts(ctx context.Context, email string) (int64, error)
+	DeleteCode(ctx context.Context, email string) error
 }
 
 type redisStoreImpl struct {
 	client *redis.Client
 }
 
-var ctx = context.Background()
-
 func NewRedisStore(client *redis.Client) RedisStore {
 	return &redisStoreImpl{client: client}
 }
 
-func (rdb *redisStoreImpl) AddTokenBlacklist(token string, expirationTime time.Duration) (*models.Response, error) {
-	err := rdb.client.Set(ctx, token, "blacklisted", expirationTime).Err()
+// RevokeToken blacklists the access token with the jti tokenID. The entry
+// only has to outlive the token, so expirationTime is its remaining lifetime.
+func (rdb *redisStoreImpl) RevokeToken(ctx context.Context, tokenID string, expirationTime time.Duration) (*models.Response, error) {
+	err := rdb.client.Set(ctx, "token:"+tokenID+":revoked", "revoked", expirationTime).Err()
 	if err != nil {
 		return &models.Response{
 			Status:  "error",
 			Message: err.Error(),
-		}, nil
+		}, err
 	}
 
 	return &models.Response{
 		Status:  "success",
-		Message: "Token added to blacklist successfully",
+		Message: "Token revoked successfully",
 	}, nil
 }
 
-func (rdb *redisStoreImpl) IsTokenBlacklisted(token string) (bool, error) {
-	val, err := rdb.client.Get(ctx, token).Result()
-	if err == redis.Nil {
-		return false, nil
-	} else if err != nil {
+func (rdb *redisStoreImpl) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	n, err := rdb.client.Exists(ctx, "token:"+tokenID+":revoked").Result()
+	if err != nil {
 		return false, err
 	}
-	return val == "blacklisted", nil
+	return n > 0, nil
 }
 
-func (rdb *redisStoreImpl) StoreCode(email, code string, expirationTime time.Duration) (*models.Response, error) {
-	err := rdb.client.Set(ctx, email+":code", code, expirationTime).Err()
+func (rdb *redisStoreImpl) StoreCode(ctx context.Context, email, code string, expirationTime time.Duration) (*models.Response, error) {
+	pipe := rdb.client.TxPipeline()
+	pipe.Set(ctx, email+":code", code, expirationTime)
+	pipe.Del(ctx, email+":code:attempts")
+	_, err := pipe.Exec(ctx)
 	if err != nil {
 		return &models.Response{
 			Status:  "error",
 			Message: err.Error(),
-		}, nil
+		}, err
 	}
 
 	return &models.Response{
@@ -65,7 +89,7 @@ func (rdb *redisStoreImpl) StoreCode(email, code string, expirationTime time.Dur
 	}, nil
 }
 
-func (rdb *redisStoreImpl) IsCodeValid(email, code string) (bool, error) {
+func (rdb *redisStoreImpl) IsCodeValid(ctx context.Context, email, code string) (bool, error) {
 	val, err := rdb.client.Get(ctx, email+":code").Result()
 	if err == redis.Nil {
 		return false, nil
@@ -74,3 +98,183 @@ func (rdb *redisStoreImpl) IsCodeValid(email, code string) (bool, error) {
 	}
 	return val == code, nil
 }
+
+func (rdb *redisStoreImpl) RevokeSession(ctx context.Context, sessionID string, expirationTime time.Duration) (*models.Response, error) {
+	err := rdb.client.Set(ctx, "session:"+sessionID+":revoked", "revoked", expirationTime).Err()
+	if err != nil {
+		return &models.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Session revoked successfully",
+	}, nil
+}
+
+func (rdb *redisStoreImpl) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := rdb.client.Exists(ctx, "session:"+sessionID+":revoked").Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (rdb *redisStoreImpl) StoreAuthorizationCode(ctx context.Context, code string, data models.AuthorizationCode, expirationTime time.Duration) (*models.Response, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	err = rdb.client.Set(ctx, "oauth_code:"+code, payload, expirationTime).Err()
+	if err != nil {
+		return &models.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Authorization code stored successfully",
+	}, nil
+}
+
+// ConsumeAuthorizationCode returns the data stored for code and deletes it,
+// so that every code can be exchanged at most once.
+func (rdb *redisStoreImpl) ConsumeAuthorizationCode(ctx context.Context, code string) (*models.AuthorizationCode, error) {
+	val, err := rdb.client.GetDel(ctx, "oauth_code:"+code).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("authorization code not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	var data models.AuthorizationCode
+	if err := json.Unmarshal(val, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// MarkTOTPUsed records that the user's code for the given time step has been
+// accepted. It reports false when the step was already used, so the same
+// code cannot be replayed inside its validity window.
+func (rdb *redisStoreImpl) MarkTOTPUsed(ctx context.Context, userID string, step int64, expirationTime time.Duration) (bool, error) {
+	return rdb.client.SetNX(ctx, fmt.Sprintf("totp_used:%s:%d", userID, step), "used", expirationTime).Result()
+}
+
+// IncrementMFAAttempts counts the verification attempts made with one
+// mfa_pending token.
+func (rdb *redisStoreImpl) IncrementMFAAttempts(ctx context.Context, tokenID string, expirationTime time.Duration) (int64, error) {
+	key := "mfa_attempts:" + tokenID
+	n, err := rdb.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		rdb.client.Expire(ctx, key, expirationTime)
+	}
+	return n, nil
+}
+
+// StoreWebAuthnSession keeps the challenge of a WebAuthn ceremony between its
+// begin and finish requests.
+func (rdb *redisStoreImpl) StoreWebAuthnSession(ctx context.Context, key string, session []byte, expirationTime time.Duration) (*models.Response, error) {
+	err := rdb.client.Set(ctx, "webauthn:"+key, session, expirationTime).Err()
+	if err != nil {
+		return &models.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "WebAuthn session stored successfully",
+	}, nil
+}
+
+// ConsumeWebAuthnSession returns and deletes a stored ceremony, so that every
+// challenge can be answered once.
+func (rdb *redisStoreImpl) ConsumeWebAuthnSession(ctx context.Context, key string) ([]byte, error) {
+	val, err := rdb.client.GetDel(ctx, "webauthn:"+key).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("webauthn session not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+func (rdb *redisStoreImpl) StoreEmailVerification(ctx context.Context, tokenHash string, email string, expirationTime time.Duration) (*models.Response, error) {
+	err := rdb.client.Set(ctx, "email_verification:"+tokenHash, email, expirationTime).Err()
+	if err != nil {
+		return &models.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Verification token stored successfully",
+	}, nil
+}
+
+// ConsumeEmailVerification returns the email a verification token was issued
+// for and deletes the token.
+func (rdb *redisStoreImpl) ConsumeEmailVerification(ctx context.Context, tokenHash string) (string, error) {
+	email, err := rdb.client.GetDel(ctx, "email_verification:"+tokenHash).Result()
+	if err == redis.Nil {
+		return "", fmt.Errorf("verification token not found")
+	} else if err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+// MarkVerificationSent reports false when a verification email was already
+// sent to email within expirationTime.
+func (rdb *redisStoreImpl) MarkVerificationSent(ctx context.Context, email string, expirationTime time.Duration) (bool, error) {
+	return rdb.client.SetNX(ctx, "email_verification_sent:"+email, "sent", expirationTime).Result()
+}
+
+func (rdb *redisStoreImpl) StoreEmailChange(ctx context.Context, tokenHash string, change models.EmailChange, expirationTime time.Duration) (*models.Response, error) {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return &models.Response{Status: "error", Message: err.Error()}, err
+	}
+
+	err = rdb.client.Set(ctx, "email_change:"+tokenHash, payload, expirationTime).Err()
+	if err != nil {
+		return &models.Response{
+			Status:  "error",
+			Message: err.Error(),
+		}, err
+	}
+
+	return &models.Response{
+		Status:  "success",
+		Message: "Email change token stored successfully",
+	}, nil
+}
+
+// ConsumeEmailChange returns the change a confirmation token was issued for
+// and deletes the token.
+func (rdb *redisStoreImpl) ConsumeEmailChange(ctx context.Context, tokenHash string) (*models.EmailChange, error) {
+	val, err := rdb.client.GetDel(ctx, "email_change:"+tokenHash).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("email change token not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	var change models.EmailChange
+	if err := json.Unmarshal(val, &change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
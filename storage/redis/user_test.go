@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizationCodeIsSingleUse(t *testing.T) {
+	client, err := RedisConnect(config.Load())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewRedisStore(client)
+
+	code := uuid.NewString()
+	data := models.AuthorizationCode{
+		ClientID:      "web",
+		RedirectURI:   "https://app.example.com/callback",
+		UserID:        "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		Scope:         "openid",
+		CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+	}
+	_, err = store.StoreAuthorizationCode(context.Background(), code, data, time.Minute)
+	assert.NoError(t, err)
+
+	consumed, err := store.ConsumeAuthorizationCode(context.Background(), code)
+	assert.NoError(t, err)
+	assert.Equal(t, &data, consumed)
+
+	_, err = store.ConsumeAuthorizationCode(context.Background(), code)
+	assert.Error(t, err)
+}
+
+func TestEmailChangeIsSingleUse(t *testing.T) {
+	client, err := RedisConnect(config.Load())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewRedisStore(client)
+
+	tokenHash := uuid.NewString()
+	change := models.EmailChange{
+		UserID: "d70789c8-37e0-4de6-8195-d900abc0afb5",
+		Email:  "new_email@test.com",
+	}
+	_, err = store.StoreEmailChange(context.Background(), tokenHash, change, time.Minute)
+	assert.NoError(t, err)
+
+	consumed, err := store.ConsumeEmailChange(context.Background(), tokenHash)
+	assert.NoError(t, err)
+	assert.Equal(t, &change, consumed)
+
+	_, err = store.ConsumeEmailChange(context.Background(), tokenHash)
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"auth-service/storage/postgres"
 	rdb "auth-service/storage/redis"
+	"context"
 	"database/sql"
 
 	"github.com/redis/go-redis/v9"
@@ -11,11 +12,28 @@ import (
 type IStorage interface {
 	AuthRepository() postgres.AuthenticationRepository
 	UserRepository() postgres.UserRepository
+	SessionRepository() postgres.SessionRepository
+	AuditRepository() postgres.AuditRepository
+	OAuthClientRepository() postgres.OAuthClientRepository
+	MFARepository() postgres.MFARepository
+	WebAuthnRepository() postgres.WebAuthnRepository
+	EmailOutboxRepository() postgres.EmailOutboxRepository
+	RoleRepository() postgres.RoleRepository
+	GroupRepository() postgres.GroupRepository
+	PersonalTokenRepository() postgres.PersonalTokenRepository
+	AccountDeletionRepository() postgres.AccountDeletionRepository
+	DataExportRepository() postgres.DataExportRepository
+	EventOutboxRepository() postgres.EventOutboxRepository
 	RedisStore() rdb.RedisStore
+
+	// WithTx runs fn in a Postgres transaction and commits it if fn succeeds.
+	// The repositories of the IStorage passed to fn run in the transaction;
+	// the Redis store does not, so Redis writes belong after WithTx returns.
+	WithTx(ctx context.Context, fn func(tx IStorage) error) error
 }
 
 type storageImpl struct {
-	db  *sql.DB
+	db  postgres.DBTX
 	rdb *redis.Client
 }
 
@@ -31,6 +49,60 @@ func (s *storageImpl) UserRepository() postgres.UserRepository {
 	return postgres.NewUserRepository(s.db)
 }
 
+func (s *storageImpl) SessionRepository() postgres.SessionRepository {
+	return postgres.NewSessionRepository(s.db)
+}
+
+func (s *storageImpl) AuditRepository() postgres.AuditRepository {
+	return postgres.NewAuditRepository(s.db)
+}
+
+func (s *storageImpl) OAuthClientRepository() postgres.OAuthClientRepository {
+	return postgres.NewOAuthClientRepository(s.db)
+}
+
+func (s *storageImpl) MFARepository() postgres.MFARepository {
+	return postgres.NewMFARepository(s.db)
+}
+
+func (s *storageImpl) WebAuthnRepository() postgres.WebAuthnRepository {
+	return postgres.NewWebAuthnRepository(s.db)
+}
+
+func (s *storageImpl) EmailOutboxRepository() postgres.EmailOutboxRepository {
+	return postgres.NewEmailOutboxRepository(s.db)
+}
+
+func (s *storageImpl) RoleRepository() postgres.RoleRepository {
+	return postgres.NewRoleRepository(s.db)
+}
+
+func (s *storageImpl) GroupRepository() postgres.GroupRepository {
+	return postgres.NewGroupRepository(s.db)
+}
+
+func (s *storageImpl) PersonalTokenRepository() postgres.PersonalTokenRepository {
+	return postgres.NewPersonalTokenRepository(s.db)
+}
+
+func (s *storageImpl) AccountDeletionRepository() postgres.AccountDeletionRepository {
+	return postgres.NewAccountDeletionRepository(s.db)
+}
+
+func (s *storageImpl) DataExportRepository() postgres.DataExportRepository {
+	return postgres.NewDataExportRepository(s.db)
+}
+
+func (s *storageImpl) EventOutboxRepository() postgres.EventOutboxRepository {
+	return postgres.NewEventOutboxRepository(s.db)
+}
+
 func (s *storageImpl) RedisStore() rdb.RedisStore {
 	return rdb.NewRedisStore(s.rdb)
 }
+
+func (s *storageImpl) WithTx(ctx context.Context, fn func(tx IStorage) error) error {
+	return postgres.RunInTx(ctx, s.db, func(tx postgres.DBTX) error {
+		return fn(&storageImpl{db: tx, rdb: s.rdb})
+	})
+}